@@ -38,13 +38,29 @@ type HorizontalRunnerAutoscalerSpec struct {
 	// +optional
 	ScaleDownDelaySecondsAfterScaleUp *int `json:"scaleDownDelaySecondsAfterScaleOut,omitempty"`
 
+	// Behavior configures the scaling behavior of the target in both up and down directions, like Kubernetes HPA does.
+	// When Behavior.ScaleDown is specified, it replaces ScaleDownDelaySecondsAfterScaleUp.
+	// +optional
+	Behavior *ScalingBehavior `json:"behavior,omitempty"`
+
 	// Metrics is the collection of various metric targets to calculate desired number of runners
 	// +optional
 	Metrics []MetricSpec `json:"metrics,omitempty"`
 
+	// MetricsAggregationPolicy is the policy to combine the desired replicas suggested by each of Metrics.
+	// FirstNonNil uses the first metric that suggests one or more replicas, without even computing the later metrics.
+	// Max, Min and Sum compute all the metrics and use the maximum, the minimum, or the sum of the suggestions respectively.
+	// Defaults to FirstNonNil.
+	// +optional
+	// +kubebuilder:validation:Enum=FirstNonNil;Max;Min;Sum
+	MetricsAggregationPolicy string `json:"metricsAggregationPolicy,omitempty"`
+
 	// ScaleUpTriggers is an experimental feature to increase the desired replicas by 1
 	// on each webhook requested received by the webhookBasedAutoscaler.
 	//
+	// When two or more triggers are specified, they are evaluated in order and
+	// the first trigger that matches the webhook event determines the amount and the duration of the scale-up.
+	//
 	// This feature requires you to also enable and deploy the webhookBasedAutoscaler onto your cluster.
 	//
 	// Note that the added runners remain until the next sync period at least,
@@ -53,6 +69,22 @@ type HorizontalRunnerAutoscalerSpec struct {
 	// receive a webhook from GitHub, so that you can loosely expect MinReplicas runners to be always available.
 	ScaleUpTriggers []ScaleUpTrigger `json:"scaleUpTriggers,omitempty"`
 
+	// RoutingWeight is the weight of this HRA when the webhook-based autoscaler routes a scale-up
+	// among two or more HRAs that match the same webhook event with the Weighted routing policy.
+	// HRAs with higher weights receive proportionally more capacity reservations.
+	// An HRA with zero weight receives reservations only when every matching HRA has zero weight.
+	// Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	RoutingWeight *int `json:"routingWeight,omitempty"`
+
+	// CapacityReservations is the list of capacity reservations made by older versions of the webhook-based autoscaler.
+	// Reservations are now recorded in HorizontalRunnerAutoscalerStatus.CapacityReservations instead,
+	// so that a burst of webhook events neither bumps the generation of the HRA nor conflicts with tools that manage the spec.
+	// Reservations in the spec are still honored until they expire.
+	//
+	// Deprecated: Use HorizontalRunnerAutoscalerStatus.CapacityReservations instead.
+	// +optional
 	CapacityReservations []CapacityReservation `json:"capacityReservations,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
 
 	// ScheduledOverrides is the list of ScheduledOverride.
@@ -63,8 +95,111 @@ type HorizontalRunnerAutoscalerSpec struct {
 
 	// +optional
 	GitHubAPICredentialsFrom *GitHubAPICredentialsFrom `json:"githubAPICredentialsFrom,omitempty"`
+
+	// DryRun makes the HRA controller compute the desired replicas and record it in the status and events
+	// without updating the scale target.
+	// A dry-run HRA never receives capacity reservations from the webhook-based autoscaler.
+	// Instead, it takes into account the capacity reservations of the other HRAs of the same scale target,
+	// so that you can compare its configuration against the live one before you cut over.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// CapacityLimit limits the desired replicas by the capacity of the cluster,
+	// so that the HRA doesn't create runner pods that can't be scheduled and remain pending.
+	// +optional
+	CapacityLimit *CapacityLimit `json:"capacityLimit,omitempty"`
+}
+
+// CapacityLimit configures the limits on the desired replicas based on the capacity of the cluster to run more runner pods.
+// Each limit never reduces the desired replicas below MinReplicas.
+type CapacityLimit struct {
+	// ResourceQuota limits the desired replicas to the number of runner pods that fit in the headroom of
+	// the ResourceQuotas in the namespace of the HRA. ResourceQuotas with scopes are ignored.
+	// +optional
+	ResourceQuota bool `json:"resourceQuota,omitempty"`
+
+	// NodeAllocatable limits the desired replicas to the number of runner pods that fit in the unrequested
+	// allocatable resources of the ready and schedulable nodes matching the node selector and the tolerations
+	// of the runner pod template.
+	// +optional
+	NodeAllocatable bool `json:"nodeAllocatable,omitempty"`
+}
+
+const (
+	MetricsAggregationPolicyFirstNonNil = "FirstNonNil"
+	MetricsAggregationPolicyMax         = "Max"
+	MetricsAggregationPolicyMin         = "Min"
+	MetricsAggregationPolicySum         = "Sum"
+)
+
+// ScalingBehavior configures the scaling behavior for scaling up and down separately.
+type ScalingBehavior struct {
+	// ScaleUp is the scaling rules for scaling up.
+	// When omitted, the target is scaled up immediately without any rate limit.
+	// +optional
+	ScaleUp *ScalingRules `json:"scaleUp,omitempty"`
+
+	// ScaleDown is the scaling rules for scaling down.
+	// When omitted, scaling down is delayed by ScaleDownDelaySecondsAfterScaleUp instead.
+	// +optional
+	ScaleDown *ScalingRules `json:"scaleDown,omitempty"`
+}
+
+// ScalingRules configures the scaling behavior for one direction.
+// The desired replicas computed by the metrics and the capacity reservations is first stabilized by
+// StabilizationWindowSeconds, and then limited by Policies.
+type ScalingRules struct {
+	// StabilizationWindowSeconds is the number of seconds for which past recommendations are considered
+	// while scaling up or down.
+	// The highest recommendation in the window is used for scaling down, and the lowest is used for scaling up.
+	// Defaults to 0 for scaling up and 300 (five minutes) for scaling down.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=3600
+	StabilizationWindowSeconds *int `json:"stabilizationWindowSeconds,omitempty"`
+
+	// SelectPolicy specifies which policy should be used when two or more policies are specified.
+	// Max selects the policy that allows the largest change, Min selects the one that allows the smallest change,
+	// and Disabled disables scaling in this direction.
+	// Defaults to Max.
+	// +optional
+	// +kubebuilder:validation:Enum=Max;Min;Disabled
+	SelectPolicy string `json:"selectPolicy,omitempty"`
+
+	// Policies is the list of policies that limit the number of runners added or removed in a period.
+	// When omitted, the number of runners is changed without any rate limit.
+	// +optional
+	Policies []ScalingPolicy `json:"policies,omitempty"`
+}
+
+const (
+	ScalingPolicySelectMax      = "Max"
+	ScalingPolicySelectMin      = "Min"
+	ScalingPolicySelectDisabled = "Disabled"
+)
+
+// ScalingPolicy is a single policy that limits the change of the number of runners in the period.
+type ScalingPolicy struct {
+	// Type is either Runners, to limit the change by the absolute number of runners,
+	// or Percent, to limit the change by the percentage of the number of runners at the beginning of the period.
+	// +kubebuilder:validation:Enum=Runners;Percent
+	Type string `json:"type"`
+
+	// Value is the number of runners or the percentage of runners that can be added or removed in the period.
+	// +kubebuilder:validation:Minimum=1
+	Value int `json:"value"`
+
+	// PeriodSeconds is the length of the period in seconds.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1800
+	PeriodSeconds int `json:"periodSeconds"`
 }
 
+const (
+	ScalingPolicyTypeRunners = "Runners"
+	ScalingPolicyTypePercent = "Percent"
+)
+
 type ScaleUpTrigger struct {
 	GitHubEvent *GitHubEventScaleUpTriggerSpec `json:"githubEvent,omitempty"`
 	Amount      int                            `json:"amount,omitempty"`
@@ -97,6 +232,21 @@ type CheckRunSpec struct {
 
 // https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#workflow_job
 type WorkflowJobSpec struct {
+	// Labels is a list of runner labels.
+	// Only workflow_job events whose job requests all the labels in the list can trigger autoscaling.
+	// This is useful to scale by a different amount or duration depending on the kind of the job.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// Repositories is a list of GitHub repositories.
+	// Any workflow_job event whose repository matches one of repositories in the list can trigger autoscaling.
+	// +optional
+	Repositories []string `json:"repositories,omitempty"`
+
+	// WorkflowNames is a list of GitHub Actions glob patterns.
+	// Any workflow_job event whose workflow name matches one of patterns in the list can trigger autoscaling.
+	// +optional
+	WorkflowNames []string `json:"workflowNames,omitempty"`
 }
 
 // https://docs.github.com/en/actions/reference/events-that-trigger-workflows#pull_request
@@ -119,12 +269,39 @@ type CapacityReservation struct {
 
 	// +optional
 	EffectiveTime metav1.Time `json:"effectiveTime,omitempty"`
+
+	// ScaleUpTriggerIndex is the index of the scale-up trigger in HorizontalRunnerAutoscalerSpec.ScaleUpTriggers
+	// that resulted in this reservation.
+	// It's there only for debugging purpose.
+	// +optional
+	ScaleUpTriggerIndex *int `json:"scaleUpTriggerIndex,omitempty"`
+
+	// WorkflowJobID is the ID of the workflow job that resulted in this reservation.
+	// It's set only for the reservations made on workflow_job events,
+	// so that the HRA controller can expire or extend the reservation according to the status of the job on GitHub.
+	// +optional
+	WorkflowJobID int64 `json:"workflowJobID,omitempty"`
+
+	// Repository is the repository of the workflow job that resulted in this reservation, in the form of OWNER/REPO.
+	// +optional
+	Repository string `json:"repository,omitempty"`
+
+	// RunnerName is the name of the runner the workflow job has been assigned to.
+	// It's set once the job is in progress.
+	// +optional
+	RunnerName string `json:"runnerName,omitempty"`
 }
 
 type ScaleTargetRef struct {
-	// Kind is the type of resource being referenced
+	// APIVersion is the API version of the resource being referenced, like `example.com/v1`.
+	// It's required when Kind is neither RunnerDeployment nor RunnerSet.
+	// +optional
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Kind is the type of resource being referenced.
+	// It's either RunnerDeployment, RunnerSet, or the kind of any other resource that exposes the scale subresource.
+	// Defaults to RunnerDeployment.
 	// +optional
-	// +kubebuilder:validation:Enum=RunnerDeployment;RunnerSet
 	Kind string `json:"kind,omitempty"`
 
 	// Name is the name of resource being referenced
@@ -133,14 +310,38 @@ type ScaleTargetRef struct {
 
 type MetricSpec struct {
 	// Type is the type of metric to be used for autoscaling.
-	// It can be TotalNumberOfQueuedAndInProgressWorkflowRuns or PercentageRunnersBusy.
+	// It can be TotalNumberOfQueuedAndInProgressWorkflowRuns, TotalNumberOfQueuedAndInProgressWorkflowJobs,
+	// PercentageRunnersBusy, PrometheusQuery, or IdleRunnerBuffer.
 	Type string `json:"type,omitempty"`
 
 	// RepositoryNames is the list of repository names to be used for calculating the metric.
 	// For example, a repository name is the REPO part of `github.com/USER/REPO`.
+	// An entry can be a GitHub Actions glob pattern like `infra-*` or `**`, which is expanded against the repositories
+	// visible to the runners, and an entry prefixed with `!` excludes the matching repositories.
 	// +optional
 	RepositoryNames []string `json:"repositoryNames,omitempty"`
 
+	// RepositoryDiscovery enables discovering the repositories to be used for calculating the
+	// TotalNumberOfQueuedAndInProgressWorkflowRuns and TotalNumberOfQueuedAndInProgressWorkflowJobs metrics of organizational runners,
+	// so that you don't need to list every repository in RepositoryNames.
+	// The discovered repositories are used in addition to RepositoryNames.
+	// +optional
+	RepositoryDiscovery *RepositoryDiscoverySpec `json:"repositoryDiscovery,omitempty"`
+
+	// ImplicitRunnerLabels is the list of labels that the runners are assumed to have in addition to their labels
+	// and the implicit labels of their labelMatching policy,
+	// when the TotalNumberOfQueuedAndInProgressWorkflowJobs metric matches jobs against the runners.
+	// Specify the labels that GitHub assigns to self-hosted runners automatically, like `linux` and `x64`,
+	// so that jobs with `runs-on: [self-hosted, linux, x64]` are counted without declaring them as runner labels.
+	// +optional
+	ImplicitRunnerLabels []string `json:"implicitRunnerLabels,omitempty"`
+
+	// RunnerPool scopes the PercentageRunnersBusy metric to every runner in a runner group or with a set of labels,
+	// including the runners that are not managed by the scale target, like the runners of other clusters and non-ARC runners.
+	// When omitted, only the runners of the scale target are counted.
+	// +optional
+	RunnerPool *RunnerPoolSpec `json:"runnerPool,omitempty"`
+
 	// ScaleUpThreshold is the percentage of busy runners greater than which will
 	// trigger the hpa to scale runners up.
 	// +optional
@@ -170,16 +371,107 @@ type MetricSpec struct {
 	// You can only specify either ScaleDownFactor or ScaleDownAdjustment.
 	// +optional
 	ScaleDownAdjustment int `json:"scaleDownAdjustment,omitempty"`
+
+	// Prometheus is the query to be run for the PrometheusQuery metric.
+	// Required when Type is PrometheusQuery.
+	// +optional
+	Prometheus *PrometheusMetricSpec `json:"prometheus,omitempty"`
+
+	// IdleRunners is the number of idle runners the IdleRunnerBuffer metric keeps in addition to the busy runners,
+	// so that the desired replicas is the number of busy runners plus IdleRunners.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	IdleRunners int `json:"idleRunners,omitempty"`
+}
+
+// RepositoryDiscoverySpec configures how repositories are discovered for an organizational runner deployment.
+// Archived and disabled repositories are always excluded.
+// When the runner group of the scale target has limited visibility, only the repositories that can access the group are used.
+type RepositoryDiscoverySpec struct {
+	// Source is where the repositories are discovered from.
+	// Organization lists all the repositories in the organization, and Installation lists
+	// the repositories accessible to the GitHub App installation.
+	// Defaults to Organization.
+	// +optional
+	// +kubebuilder:validation:Enum=Organization;Installation
+	Source string `json:"source,omitempty"`
+
+	// RepositoryNamePatterns is the list of GitHub Actions glob patterns of the repository names to be used.
+	// A pattern prefixed with "!" excludes the matching repositories.
+	// If it has no pattern without "!", all the repositories that are not excluded are used.
+	// +optional
+	RepositoryNamePatterns []string `json:"repositoryNamePatterns,omitempty"`
+
+	// RefreshInterval is how long the discovered repositories are cached before being discovered again.
+	// Defaults to 10m.
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+const (
+	RepositoryDiscoverySourceOrganization = "Organization"
+	RepositoryDiscoverySourceInstallation = "Installation"
+)
+
+// RunnerPoolSpec is the set of runners registered to the enterprise, organization, or repository of the scale target
+// whose busy ratio is used by the PercentageRunnersBusy metric.
+// Only online runners are counted.
+type RunnerPoolSpec struct {
+	// Group is the name of the runner group the runners belong to.
+	// Repository runners can't be scoped by group.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Labels is the list of labels the runners must all have, compared case-insensitively.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+}
+
+// PrometheusMetricSpec is the configuration of the PrometheusQuery metric.
+// The desired replicas is computed as ceil(result / targetValuePerRunner).
+type PrometheusMetricSpec struct {
+	// Address is the URL of the Prometheus-compatible HTTP API endpoint, like `http://prometheus.monitoring:9090`.
+	Address string `json:"address"`
+
+	// Query is the PromQL expression to be evaluated.
+	// It must result in either a scalar or a vector of at most one element.
+	// An empty vector results in no suggestion, so that the desired replicas is determined by other metrics and minReplicas.
+	Query string `json:"query"`
+
+	// TargetValuePerRunner is the value of the query result that a single runner is expected to handle.
+	// It must be a positive number, like `1` or `0.5`.
+	TargetValuePerRunner string `json:"targetValuePerRunner"`
 }
 
 // ScheduledOverride can be used to override a few fields of HorizontalRunnerAutoscalerSpec on schedule.
 // A schedule can optionally be recurring, so that the corresponding override happens every day, week, month, or year.
+// Alternatively, a schedule can be written as a cron expression, like "every weekday from 09:00 for 9 hours".
 type ScheduledOverride struct {
 	// StartTime is the time at which the first override starts.
-	StartTime metav1.Time `json:"startTime"`
+	// Required unless Cron is specified.
+	// +optional
+	StartTime metav1.Time `json:"startTime,omitempty"`
 
 	// EndTime is the time at which the first override ends.
-	EndTime metav1.Time `json:"endTime"`
+	// Required unless Cron is specified.
+	// +optional
+	EndTime metav1.Time `json:"endTime,omitempty"`
+
+	// Cron is a standard cron expression with five fields, like "0 9 * * 1-5", or a predefined schedule like "@daily".
+	// Each override starts at the time matching the expression and lasts for Duration.
+	// It cannot be specified together with StartTime, EndTime, and RecurrenceRule.Frequency.
+	// +optional
+	Cron string `json:"cron,omitempty"`
+
+	// Duration is how long each override lasts when Cron is specified.
+	// +optional
+	Duration metav1.Duration `json:"duration,omitempty"`
+
+	// TimeZone is the IANA time zone name, like "Europe/Berlin", in which Cron, StartTime and EndTime are interpreted.
+	// Recurring overrides follow the daylight saving time of the time zone.
+	// Defaults to UTC for Cron, and to the offsets of StartTime and EndTime otherwise.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
 
 	// MinReplicas is the number of runners while overriding.
 	// If omitted, it doesn't override minReplicas.
@@ -188,10 +480,42 @@ type ScheduledOverride struct {
 	// +kubebuilder:validation:Minimum=0
 	MinReplicas *int `json:"minReplicas,omitempty"`
 
+	// MaxReplicas overrides maxReplicas while overriding.
+	// +optional
+	// +nullable
+	// +kubebuilder:validation:Minimum=0
+	MaxReplicas *int `json:"maxReplicas,omitempty"`
+
+	// ScaleDownDelaySecondsAfterScaleUp overrides scaleDownDelaySecondsAfterScaleOut while overriding.
+	// +optional
+	// +nullable
+	ScaleDownDelaySecondsAfterScaleUp *int `json:"scaleDownDelaySecondsAfterScaleOut,omitempty"`
+
+	// Metrics overrides the thresholds of the metrics of the same type in HorizontalRunnerAutoscalerSpec.Metrics while overriding.
+	// +optional
+	Metrics []MetricOverride `json:"metrics,omitempty"`
+
 	// +optional
 	RecurrenceRule RecurrenceRule `json:"recurrenceRule,omitempty"`
 }
 
+// MetricOverride overrides the thresholds of every metric of the Type.
+// Empty fields are not overridden.
+type MetricOverride struct {
+	// Type is the type of the metrics to be overridden.
+	Type string `json:"type"`
+
+	// +optional
+	ScaleUpThreshold string `json:"scaleUpThreshold,omitempty"`
+
+	// +optional
+	ScaleDownThreshold string `json:"scaleDownThreshold,omitempty"`
+
+	// TargetValuePerRunner overrides prometheus.targetValuePerRunner of PrometheusQuery metrics.
+	// +optional
+	TargetValuePerRunner string `json:"targetValuePerRunner,omitempty"`
+}
+
 type RecurrenceRule struct {
 	// Frequency is the name of a predefined interval of each recurrence.
 	// The valid values are "Daily", "Weekly", "Monthly", and "Yearly".
@@ -221,13 +545,106 @@ type HorizontalRunnerAutoscalerStatus struct {
 	// +nullable
 	LastSuccessfulScaleOutTime *metav1.Time `json:"lastSuccessfulScaleOutTime,omitempty"`
 
+	// CacheEntries is no longer populated.
+	//
+	// Deprecated: See CurrentMetrics and Conditions for the observations behind DesiredReplicas instead.
 	// +optional
 	CacheEntries []CacheEntry `json:"cacheEntries,omitempty"`
 
+	// CapacityReservations is the list of capacity reservations made by the webhook-based autoscaler.
+	// Each reservation adds its replicas to the desired replicas until it expires,
+	// and expired reservations are garbage-collected by the HRA controller.
+	// +optional
+	CapacityReservations []CapacityReservation `json:"capacityReservations,omitempty"`
+
+	// AppliedWebhookDeliveries is the IDs of the webhook deliveries applied to CapacityReservations by the latest batch
+	// of the webhook-based autoscaler with the persistent queue journal, so that the deliveries replayed from the journal
+	// after a restart of the webhook server are never applied twice.
+	// +optional
+	AppliedWebhookDeliveries []string `json:"appliedWebhookDeliveries,omitempty"`
+
 	// ScheduledOverridesSummary is the summary of active and upcoming scheduled overrides to be shown in e.g. a column of a `kubectl get hra` output
 	// for observability.
 	// +optional
 	ScheduledOverridesSummary *string `json:"scheduledOverridesSummary,omitempty"`
+
+	// DryRunSummary is the summary of the desired replicas computed in dry-run mode and how it was computed,
+	// like `current=2 desired=5 suggested=3 reserved=2 min=1 max=10`.
+	// It is maintained only when Spec.DryRun is true.
+	// +optional
+	DryRunSummary *string `json:"dryRunSummary,omitempty"`
+
+	// Recommendations is the history of the desired replicas computed before the scaling behavior is applied.
+	// Only the changes within the longest stabilization window are kept.
+	// It is maintained only when Spec.Behavior is specified.
+	// +optional
+	Recommendations []ScaleRecommendation `json:"recommendations,omitempty"`
+
+	// ScaleEvents is the history of the changes of the desired replicas within the longest policy period.
+	// It is maintained only when Spec.Behavior is specified.
+	// +optional
+	ScaleEvents []ScaleEvent `json:"scaleEvents,omitempty"`
+
+	// Conditions is the latest observations of the HRA's state.
+	// AbleToScale tells whether the HRA is able to update the scale target,
+	// ScalingActive tells whether the metrics could be computed,
+	// and ScalingLimited tells whether the desired replicas is limited by e.g. maxReplicas or the scale down delay.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// CurrentMetrics is the latest observation of each entry of HorizontalRunnerAutoscalerSpec.Metrics, in the same order.
+	// +optional
+	CurrentMetrics []MetricStatus `json:"currentMetrics,omitempty"`
+
+	// ActiveCapacityReservations is the number of unexpired capacity reservations that were added to the desired replicas.
+	// +optional
+	ActiveCapacityReservations int `json:"activeCapacityReservations,omitempty"`
+
+	// LastScaleTime is the last time the HRA changed the replicas of the scale target.
+	// +optional
+	// +nullable
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+}
+
+const (
+	// HorizontalRunnerAutoscalerConditionAbleToScale tells whether the HRA is able to update the replicas of the scale target.
+	HorizontalRunnerAutoscalerConditionAbleToScale = "AbleToScale"
+	// HorizontalRunnerAutoscalerConditionScalingActive tells whether the HRA is able to compute the desired replicas from the metrics.
+	HorizontalRunnerAutoscalerConditionScalingActive = "ScalingActive"
+	// HorizontalRunnerAutoscalerConditionScalingLimited tells whether the desired replicas is limited by
+	// minReplicas, maxReplicas, the scale down delay or the scaling behavior.
+	HorizontalRunnerAutoscalerConditionScalingLimited = "ScalingLimited"
+)
+
+// MetricStatus is the latest observation of a metric.
+type MetricStatus struct {
+	// Type is the type of the metric.
+	Type string `json:"type"`
+
+	// Value is the summary of the value observed by the metric, like `queued=3 inProgress=2`.
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// SuggestedReplicas is the desired replicas suggested by the metric.
+	// It's omitted when the metric had no suggestion, or wasn't computed because an earlier metric was used
+	// with the FirstNonNil metrics aggregation policy.
+	// +optional
+	SuggestedReplicas *int `json:"suggestedReplicas,omitempty"`
+}
+
+// ScaleRecommendation is the desired replicas recommended at Timestamp.
+type ScaleRecommendation struct {
+	Timestamp metav1.Time `json:"timestamp"`
+	Replicas  int         `json:"replicas"`
+}
+
+// ScaleEvent is the change of the desired replicas made at Timestamp.
+// ReplicaChange is positive for scaling up and negative for scaling down.
+type ScaleEvent struct {
+	Timestamp     metav1.Time `json:"timestamp"`
+	ReplicaChange int         `json:"replicaChange"`
 }
 
 const CacheEntryKeyDesiredReplicas = "desiredReplicas"
@@ -49,6 +49,12 @@ type RunnerConfig struct {
 	// +optional
 	Labels []string `json:"labels,omitempty"`
 
+	// LabelMatching configures the labels the runners are assumed to have in addition to Labels,
+	// and how they are matched against the labels requested by workflow jobs.
+	// It's used consistently by the webhook-based autoscaler, the pull-based metrics, and the runner registration.
+	// +optional
+	LabelMatching *LabelMatchingPolicy `json:"labelMatching,omitempty"`
+
 	// +optional
 	Group string `json:"group,omitempty"`
 
@@ -80,6 +86,28 @@ type RunnerConfig struct {
 	GitHubAPICredentialsFrom *GitHubAPICredentialsFrom `json:"githubAPICredentialsFrom,omitempty"`
 }
 
+// LabelMatchingPolicy configures how the labels requested by a workflow job are matched against the runner labels.
+// The runners are always assumed to have the `self-hosted` label.
+type LabelMatchingPolicy struct {
+	// ImplicitLabels is the list of labels the runners are assumed to have in addition to Labels.
+	// The ones that GitHub doesn't assign to self-hosted runners automatically are registered along with Labels.
+	// +optional
+	ImplicitLabels []string `json:"implicitLabels,omitempty"`
+
+	// ImplicitLabelsFromNodeSelector derives the OS and the architecture labels that GitHub assigns to self-hosted runners,
+	// like `linux` and `x64`, from the `kubernetes.io/os` and `kubernetes.io/arch` node selectors of the runner pods.
+	// +optional
+	ImplicitLabelsFromNodeSelector bool `json:"implicitLabelsFromNodeSelector,omitempty"`
+
+	// CaseInsensitive makes the labels matched case-insensitively, like GitHub does.
+	// +optional
+	CaseInsensitive bool `json:"caseInsensitive,omitempty"`
+
+	// IgnoredLabels is the list of labels requested by workflow jobs that are ignored on matching.
+	// +optional
+	IgnoredLabels []string `json:"ignoredLabels,omitempty"`
+}
+
 type GitHubAPICredentialsFrom struct {
 	SecretRef SecretReference `json:"secretRef,omitempty"`
 }
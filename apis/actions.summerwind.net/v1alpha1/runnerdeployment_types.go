@@ -22,7 +22,24 @@ import (
 
 const (
 	AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns = "TotalNumberOfQueuedAndInProgressWorkflowRuns"
+	AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowJobs = "TotalNumberOfQueuedAndInProgressWorkflowJobs"
 	AutoscalingMetricTypePercentageRunnersBusy                        = "PercentageRunnersBusy"
+	AutoscalingMetricTypePrometheusQuery                              = "PrometheusQuery"
+	AutoscalingMetricTypeIdleRunnerBuffer                             = "IdleRunnerBuffer"
+)
+
+// Scale down policies that decide which runners are removed first when the desired replicas decreases.
+const (
+	// ScaleDownPolicyOldestFirst removes the runners created earliest first. This is the default.
+	ScaleDownPolicyOldestFirst = "OldestFirst"
+	// ScaleDownPolicyNewestFirst removes the runners created latest first.
+	ScaleDownPolicyNewestFirst = "NewestFirst"
+	// ScaleDownPolicyLongestIdle removes the runners that have been idle for the longest time first,
+	// keeping the runners running workflow jobs until the last.
+	ScaleDownPolicyLongestIdle = "LongestIdle"
+	// ScaleDownPolicyPackNodes removes the runners on the nodes with the fewest runners first,
+	// so that nodes are drained and can be removed by the cluster autoscaler.
+	ScaleDownPolicyPackNodes = "PackNodes"
 )
 
 // RunnerDeploymentSpec defines the desired state of RunnerDeployment
@@ -39,6 +56,13 @@ type RunnerDeploymentSpec struct {
 	// +nullable
 	EffectiveTime *metav1.Time `json:"effectiveTime"`
 
+	// ScaleDownPolicy decides which runners are removed first on scale down.
+	// Defaults to OldestFirst.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=OldestFirst;NewestFirst;LongestIdle;PackNodes
+	ScaleDownPolicy string `json:"scaleDownPolicy,omitempty"`
+
 	// +optional
 	// +nullable
 	Selector *metav1.LabelSelector `json:"selector"`
@@ -72,11 +96,17 @@ type RunnerDeploymentStatus struct {
 	// Replicas is the total number of replicas
 	// +optional
 	Replicas *int `json:"replicas"`
+
+	// Selector is the label selector of the runner pods in the string form,
+	// which is exposed via the scale subresource so that e.g. Kubernetes HPA can find the pods.
+	// +optional
+	Selector string `json:"selector,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:shortName=rdeploy
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
 // +kubebuilder:printcolumn:JSONPath=".spec.replicas",name=Desired,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.replicas",name=Current,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.updatedReplicas",name=Up-To-Date,type=number
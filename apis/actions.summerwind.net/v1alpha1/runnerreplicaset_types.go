@@ -35,6 +35,13 @@ type RunnerReplicaSetSpec struct {
 	// +nullable
 	EffectiveTime *metav1.Time `json:"effectiveTime"`
 
+	// ScaleDownPolicy decides which runners are removed first on scale down.
+	// It is inherited from RunnerDeployment. Defaults to OldestFirst.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=OldestFirst;NewestFirst;LongestIdle;PackNodes
+	ScaleDownPolicy string `json:"scaleDownPolicy,omitempty"`
+
 	// +optional
 	// +nullable
 	Selector *metav1.LabelSelector `json:"selector"`
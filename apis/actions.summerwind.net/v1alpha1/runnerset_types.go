@@ -33,6 +33,13 @@ type RunnerSetSpec struct {
 	// +nullable
 	EffectiveTime *metav1.Time `json:"effectiveTime,omitempty"`
 
+	// ScaleDownPolicy decides which runners are removed first on scale down.
+	// Defaults to OldestFirst.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=OldestFirst;NewestFirst;LongestIdle;PackNodes
+	ScaleDownPolicy string `json:"scaleDownPolicy,omitempty"`
+
 	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 
@@ -69,10 +76,16 @@ type RunnerSetStatus struct {
 	// Replicas is the total number of replicas
 	// +optional
 	Replicas *int `json:"replicas"`
+
+	// Selector is the label selector of the runner pods in the string form,
+	// which is exposed via the scale subresource so that e.g. Kubernetes HPA can find the pods.
+	// +optional
+	Selector string `json:"selector,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
 // +kubebuilder:printcolumn:JSONPath=".spec.replicas",name=Desired,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.replicas",name=Current,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.updatedReplicas",name=Up-To-Date,type=number
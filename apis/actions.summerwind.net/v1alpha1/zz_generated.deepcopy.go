@@ -43,11 +43,31 @@ func (in *CacheEntry) DeepCopy() *CacheEntry {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityLimit) DeepCopyInto(out *CapacityLimit) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityLimit.
+func (in *CapacityLimit) DeepCopy() *CapacityLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CapacityReservation) DeepCopyInto(out *CapacityReservation) {
 	*out = *in
 	in.ExpirationTime.DeepCopyInto(&out.ExpirationTime)
 	in.EffectiveTime.DeepCopyInto(&out.EffectiveTime)
+	if in.ScaleUpTriggerIndex != nil {
+		in, out := &in.ScaleUpTriggerIndex, &out.ScaleUpTriggerIndex
+		*out = new(int)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservation.
@@ -127,7 +147,7 @@ func (in *GitHubEventScaleUpTriggerSpec) DeepCopyInto(out *GitHubEventScaleUpTri
 	if in.WorkflowJob != nil {
 		in, out := &in.WorkflowJob, &out.WorkflowJob
 		*out = new(WorkflowJobSpec)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -233,6 +253,11 @@ func (in *HorizontalRunnerAutoscalerSpec) DeepCopyInto(out *HorizontalRunnerAuto
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RoutingWeight != nil {
+		in, out := &in.RoutingWeight, &out.RoutingWeight
+		*out = new(int)
+		**out = **in
+	}
 	if in.CapacityReservations != nil {
 		in, out := &in.CapacityReservations, &out.CapacityReservations
 		*out = make([]CapacityReservation, len(*in))
@@ -252,6 +277,16 @@ func (in *HorizontalRunnerAutoscalerSpec) DeepCopyInto(out *HorizontalRunnerAuto
 		*out = new(GitHubAPICredentialsFrom)
 		**out = **in
 	}
+	if in.CapacityLimit != nil {
+		in, out := &in.CapacityLimit, &out.CapacityLimit
+		*out = new(CapacityLimit)
+		**out = **in
+	}
+	if in.Behavior != nil {
+		in, out := &in.Behavior, &out.Behavior
+		*out = new(ScalingBehavior)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscalerSpec.
@@ -283,11 +318,60 @@ func (in *HorizontalRunnerAutoscalerStatus) DeepCopyInto(out *HorizontalRunnerAu
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CapacityReservations != nil {
+		in, out := &in.CapacityReservations, &out.CapacityReservations
+		*out = make([]CapacityReservation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AppliedWebhookDeliveries != nil {
+		in, out := &in.AppliedWebhookDeliveries, &out.AppliedWebhookDeliveries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.ScheduledOverridesSummary != nil {
 		in, out := &in.ScheduledOverridesSummary, &out.ScheduledOverridesSummary
 		*out = new(string)
 		**out = **in
 	}
+	if in.DryRunSummary != nil {
+		in, out := &in.DryRunSummary, &out.DryRunSummary
+		*out = new(string)
+		**out = **in
+	}
+	if in.Recommendations != nil {
+		in, out := &in.Recommendations, &out.Recommendations
+		*out = make([]ScaleRecommendation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ScaleEvents != nil {
+		in, out := &in.ScaleEvents, &out.ScaleEvents
+		*out = make([]ScaleEvent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CurrentMetrics != nil {
+		in, out := &in.CurrentMetrics, &out.CurrentMetrics
+		*out = make([]MetricStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastScaleTime != nil {
+		in, out := &in.LastScaleTime, &out.LastScaleTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscalerStatus.
@@ -300,6 +384,46 @@ func (in *HorizontalRunnerAutoscalerStatus) DeepCopy() *HorizontalRunnerAutoscal
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LabelMatchingPolicy) DeepCopyInto(out *LabelMatchingPolicy) {
+	*out = *in
+	if in.ImplicitLabels != nil {
+		in, out := &in.ImplicitLabels, &out.ImplicitLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IgnoredLabels != nil {
+		in, out := &in.IgnoredLabels, &out.IgnoredLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LabelMatchingPolicy.
+func (in *LabelMatchingPolicy) DeepCopy() *LabelMatchingPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(LabelMatchingPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricOverride) DeepCopyInto(out *MetricOverride) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricOverride.
+func (in *MetricOverride) DeepCopy() *MetricOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricSpec) DeepCopyInto(out *MetricSpec) {
 	*out = *in
@@ -308,6 +432,26 @@ func (in *MetricSpec) DeepCopyInto(out *MetricSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.RepositoryDiscovery != nil {
+		in, out := &in.RepositoryDiscovery, &out.RepositoryDiscovery
+		*out = new(RepositoryDiscoverySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImplicitRunnerLabels != nil {
+		in, out := &in.ImplicitRunnerLabels, &out.ImplicitRunnerLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RunnerPool != nil {
+		in, out := &in.RunnerPool, &out.RunnerPool
+		*out = new(RunnerPoolSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Prometheus != nil {
+		in, out := &in.Prometheus, &out.Prometheus
+		*out = new(PrometheusMetricSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricSpec.
@@ -320,6 +464,41 @@ func (in *MetricSpec) DeepCopy() *MetricSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricStatus) DeepCopyInto(out *MetricStatus) {
+	*out = *in
+	if in.SuggestedReplicas != nil {
+		in, out := &in.SuggestedReplicas, &out.SuggestedReplicas
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricStatus.
+func (in *MetricStatus) DeepCopy() *MetricStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusMetricSpec) DeepCopyInto(out *PrometheusMetricSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusMetricSpec.
+func (in *PrometheusMetricSpec) DeepCopy() *PrometheusMetricSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusMetricSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PullRequestSpec) DeepCopyInto(out *PullRequestSpec) {
 	*out = *in
@@ -376,6 +555,31 @@ func (in *RecurrenceRule) DeepCopy() *RecurrenceRule {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryDiscoverySpec) DeepCopyInto(out *RepositoryDiscoverySpec) {
+	*out = *in
+	if in.RepositoryNamePatterns != nil {
+		in, out := &in.RepositoryNamePatterns, &out.RepositoryNamePatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RefreshInterval != nil {
+		in, out := &in.RefreshInterval, &out.RefreshInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryDiscoverySpec.
+func (in *RepositoryDiscoverySpec) DeepCopy() *RepositoryDiscoverySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryDiscoverySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Runner) DeepCopyInto(out *Runner) {
 	*out = *in
@@ -411,6 +615,11 @@ func (in *RunnerConfig) DeepCopyInto(out *RunnerConfig) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.LabelMatching != nil {
+		in, out := &in.LabelMatching, &out.LabelMatching
+		*out = new(LabelMatchingPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Ephemeral != nil {
 		in, out := &in.Ephemeral, &out.Ephemeral
 		*out = new(bool)
@@ -784,6 +993,26 @@ func (in *RunnerPodSpec) DeepCopy() *RunnerPodSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerPoolSpec) DeepCopyInto(out *RunnerPoolSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerPoolSpec.
+func (in *RunnerPoolSpec) DeepCopy() *RunnerPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RunnerReplicaSet) DeepCopyInto(out *RunnerReplicaSet) {
 	*out = *in
@@ -1103,6 +1332,38 @@ func (in *RunnerTemplate) DeepCopy() *RunnerTemplate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleEvent) DeepCopyInto(out *ScaleEvent) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleEvent.
+func (in *ScaleEvent) DeepCopy() *ScaleEvent {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleEvent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleRecommendation) DeepCopyInto(out *ScaleRecommendation) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleRecommendation.
+func (in *ScaleRecommendation) DeepCopy() *ScaleRecommendation {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleRecommendation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScaleTargetRef) DeepCopyInto(out *ScaleTargetRef) {
 	*out = *in
@@ -1139,16 +1400,97 @@ func (in *ScaleUpTrigger) DeepCopy() *ScaleUpTrigger {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScalingBehavior) DeepCopyInto(out *ScalingBehavior) {
+	*out = *in
+	if in.ScaleUp != nil {
+		in, out := &in.ScaleUp, &out.ScaleUp
+		*out = new(ScalingRules)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScaleDown != nil {
+		in, out := &in.ScaleDown, &out.ScaleDown
+		*out = new(ScalingRules)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalingBehavior.
+func (in *ScalingBehavior) DeepCopy() *ScalingBehavior {
+	if in == nil {
+		return nil
+	}
+	out := new(ScalingBehavior)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScalingPolicy) DeepCopyInto(out *ScalingPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalingPolicy.
+func (in *ScalingPolicy) DeepCopy() *ScalingPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ScalingPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScalingRules) DeepCopyInto(out *ScalingRules) {
+	*out = *in
+	if in.StabilizationWindowSeconds != nil {
+		in, out := &in.StabilizationWindowSeconds, &out.StabilizationWindowSeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]ScalingPolicy, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalingRules.
+func (in *ScalingRules) DeepCopy() *ScalingRules {
+	if in == nil {
+		return nil
+	}
+	out := new(ScalingRules)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScheduledOverride) DeepCopyInto(out *ScheduledOverride) {
 	*out = *in
 	in.StartTime.DeepCopyInto(&out.StartTime)
 	in.EndTime.DeepCopyInto(&out.EndTime)
+	out.Duration = in.Duration
 	if in.MinReplicas != nil {
 		in, out := &in.MinReplicas, &out.MinReplicas
 		*out = new(int)
 		**out = **in
 	}
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.ScaleDownDelaySecondsAfterScaleUp != nil {
+		in, out := &in.ScaleDownDelaySecondsAfterScaleUp, &out.ScaleDownDelaySecondsAfterScaleUp
+		*out = new(int)
+		**out = **in
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]MetricOverride, len(*in))
+		copy(*out, *in)
+	}
 	in.RecurrenceRule.DeepCopyInto(&out.RecurrenceRule)
 }
 
@@ -1201,6 +1543,21 @@ func (in *WorkVolumeClaimTemplate) DeepCopy() *WorkVolumeClaimTemplate {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkflowJobSpec) DeepCopyInto(out *WorkflowJobSpec) {
 	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.WorkflowNames != nil {
+		in, out := &in.WorkflowNames, &out.WorkflowNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkflowJobSpec.
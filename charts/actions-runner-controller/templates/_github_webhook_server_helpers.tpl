@@ -55,6 +55,14 @@ Create the name of the service account to use
 {{- include "actions-runner-controller-github-webhook-server.fullname" . }}
 {{- end }}
 
+{{- define "actions-runner-controller-github-webhook-server.queueJournalRoleName" -}}
+{{- include "actions-runner-controller-github-webhook-server.fullname" . | trunc 49 }}-queue-journal
+{{- end }}
+
+{{- define "actions-runner-controller-github-webhook-server.queueJournalConfigMapName" -}}
+{{- default (printf "%s-queue-journal" (include "actions-runner-controller-github-webhook-server.fullname" . | trunc 49)) .Values.githubWebhookServer.queueJournal.configMapName }}
+{{- end }}
+
 {{- define "actions-runner-controller-github-webhook-server.serviceMonitorName" -}}
 {{- include "actions-runner-controller-github-webhook-server.fullname" . | trunc 47 }}-service-monitor
 {{- end }}
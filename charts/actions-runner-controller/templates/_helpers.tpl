@@ -100,6 +100,10 @@ Create the name of the service account to use
 {{- include "actions-runner-controller.fullname" . | trunc 47 }}-metrics-service
 {{- end }}
 
+{{- define "actions-runner-controller.kedaExternalScalerServiceName" -}}
+{{- include "actions-runner-controller.fullname" . | trunc 41 }}-keda-external-scaler
+{{- end }}
+
 {{- define "actions-runner-controller.serviceMonitorName" -}}
 {{- include "actions-runner-controller.fullname" . | trunc 47 }}-service-monitor
 {{- end }}
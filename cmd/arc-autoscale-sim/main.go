@@ -0,0 +1,258 @@
+/*
+Copyright 2021 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// arc-autoscale-sim replays recorded webhook events through the webhook-based autoscaler and the HRA controller
+// on a virtual clock, and prints the timeline of the desired replicas and the workflow job queue.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	actionssummerwindnet "github.com/actions/actions-runner-controller/controllers/actions.summerwind.net"
+	"github.com/actions/actions-runner-controller/logging"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var (
+	scheme = runtime.NewScheme()
+)
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	_ = actionsv1alpha1.AddToScheme(scheme)
+}
+
+func main() {
+	var (
+		manifests  []string
+		eventsFile string
+
+		logLevel  string
+		logFormat string
+
+		sim actionssummerwindnet.AutoscaleSimulator
+	)
+
+	flag.Func("f", "The path to a YAML file of HorizontalRunnerAutoscalers and their RunnerDeployments or RunnerSets to simulate. Can be specified multiple times.", func(v string) error {
+		manifests = append(manifests, v)
+		return nil
+	})
+	flag.StringVar(&eventsFile, "events", "", `The path to the JSON Lines file of the webhook events to replay. Each line is an object like {"time": "2022-01-01T00:00:00Z", "event": "workflow_job", "payload": {...}}, where event is the value of the X-GitHub-Event header. Specify "-" to read from the standard input.`)
+	flag.StringVar(&sim.RoutingPolicy, "scale-target-routing-policy", actionssummerwindnet.DefaultScaleTargetRoutingPolicy, fmt.Sprintf(`The policy to choose the HorizontalRunnerAutoscaler to scale when two or more HorizontalRunnerAutoscalers match a webhook event. Valid values are %s.`, strings.Join(actionssummerwindnet.ScaleTargetRoutingPolicies, ", ")))
+	flag.IntVar(&sim.QueueLimit, "queue-limit", actionssummerwindnet.DefaultQueueLimit, "The maximum length of the scale operation queue of the webhook-based autoscaler.")
+	flag.DurationVar(&sim.SyncPeriod, "sync-period", actionssummerwindnet.DefaultAutoscaleSimulatorSyncPeriod, "The interval between reconciliations of each HorizontalRunnerAutoscaler, like the sync period of the controller.")
+	flag.DurationVar(&sim.DefaultScaleDownDelay, "default-scale-down-delay", actionssummerwindnet.DefaultScaleDownDelay, "The approximate delay for a scale down followed by a scale up, used to prevent flapping (down->up->down->... loop)")
+	flag.DurationVar(&sim.CapacityReservationSyncPeriod, "capacity-reservation-sync-period", actionssummerwindnet.DefaultCapacityReservationSyncPeriod, "The minimum interval between syncs of the capacity reservations made for workflow jobs with the status of the jobs.")
+	flag.DurationVar(&sim.SampleInterval, "interval", time.Minute, "The interval between the rows of the timeline.")
+	flag.DurationVar(&sim.Tail, "tail", 30*time.Minute, "How long the simulation continues after the last event, so that the scale down after the workload is observed.")
+	flag.StringVar(&logLevel, "log-level", logging.LogLevelError, `The verbosity of the logging. Valid values are "debug", "info", "warn", "error". Defaults to "error".`)
+	flag.StringVar(&logFormat, "log-format", "text", `The log format. Valid options are "text" and "json". Defaults to "text"`)
+
+	flag.Parse()
+
+	logger, err := logging.NewLogger(logLevel, logFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: creating logger: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := actionssummerwindnet.ValidateScaleTargetRoutingPolicy(sim.RoutingPolicy); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(manifests) == 0 || eventsFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: -f and -events are required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var objs []client.Object
+
+	for _, f := range manifests {
+		o, err := readManifests(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: reading manifests from %s: %v\n", f, err)
+			os.Exit(1)
+		}
+
+		objs = append(objs, o...)
+	}
+
+	events, err := readEvents(eventsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: reading events from %s: %v\n", eventsFile, err)
+		os.Exit(1)
+	}
+
+	sim.Client = clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	sim.Log = logger
+	sim.Scheme = scheme
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+
+	var start time.Time
+
+	err = sim.Run(context.Background(), events, func(s actionssummerwindnet.AutoscaleSimulationSample) {
+		if start.IsZero() {
+			start = s.Time
+
+			header := []string{"TIME", "ELAPSED", "QUEUED", "IN_PROGRESS", "PENDING_OPS", "FAILED_DELIVERIES"}
+			for _, t := range s.Targets {
+				header = append(header, t.HorizontalRunnerAutoscaler.String())
+			}
+
+			fmt.Fprintln(w, strings.Join(header, "\t"))
+		}
+
+		row := []string{
+			s.Time.UTC().Format(time.RFC3339),
+			s.Time.Sub(start).String(),
+			fmt.Sprint(s.QueuedJobs),
+			fmt.Sprint(s.InProgressJobs),
+			fmt.Sprint(s.PendingOperations),
+			fmt.Sprint(s.FailedDeliveries),
+		}
+
+		// Each HRA is printed as the desired replicas followed by the replicas reserved for the webhook events
+		for _, t := range s.Targets {
+			desired := "-"
+			if t.DesiredReplicas != nil {
+				desired = fmt.Sprint(*t.DesiredReplicas)
+			}
+
+			row = append(row, fmt.Sprintf("%s (%d reserved)", desired, t.CapacityReservations))
+		}
+
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	})
+
+	w.Flush()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// readManifests decodes the Kubernetes objects in the YAML documents in the file.
+// Objects without a namespace are put in the default namespace.
+func readManifests(path string) ([]client.Object, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+	reader := yaml.NewYAMLReader(bufio.NewReader(f))
+
+	var objs []client.Object
+
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if strings.TrimSpace(string(doc)) == "" {
+			continue
+		}
+
+		o, gvk, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		obj, ok := o.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("unsupported object of %s", gvk)
+		}
+
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace("default")
+		}
+
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+// readEvents reads the webhook events from the JSON Lines file, or from the standard input when the path is "-".
+func readEvents(path string) ([]actionssummerwindnet.SimulatedWebhookEvent, error) {
+	var r io.Reader = os.Stdin
+
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	scanner := bufio.NewScanner(r)
+	// Webhook payloads can be larger than the default limit of 64KiB
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+
+	var events []actionssummerwindnet.SimulatedWebhookEvent
+
+	for line := 1; scanner.Scan(); line++ {
+		b := scanner.Bytes()
+		if strings.TrimSpace(string(b)) == "" {
+			continue
+		}
+
+		var ev actionssummerwindnet.SimulatedWebhookEvent
+		if err := json.Unmarshal(b, &ev); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if ev.Time.IsZero() || ev.Event == "" {
+			return nil, fmt.Errorf("line %d: time and event are required", line)
+		}
+
+		events = append(events, ev)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -70,9 +71,16 @@ func main() {
 
 		watchNamespace string
 
-		logLevel   string
-		queueLimit int
-		logFormat  string
+		logLevel      string
+		queueLimit    int
+		routingPolicy string
+		logFormat     string
+
+		enableLeaderElection bool
+		leaderElectionId     string
+
+		queueJournalName      string
+		queueJournalNamespace string
 
 		ghClient *github.Client
 	)
@@ -91,6 +99,7 @@ func main() {
 	flag.StringVar(&watchNamespace, "watch-namespace", "", "The namespace to watch for HorizontalRunnerAutoscaler's to scale on Webhook. Set to empty for letting it watch for all namespaces.")
 	flag.StringVar(&logLevel, "log-level", logging.LogLevelDebug, `The verbosity of the logging. Valid values are "debug", "info", "warn", "error". Defaults to "debug".`)
 	flag.IntVar(&queueLimit, "queue-limit", actionssummerwindnet.DefaultQueueLimit, `The maximum length of the scale operation queue. The scale opration is enqueued per every matching webhook event, and the server returns a 500 HTTP status when the queue was already full on enqueue attempt.`)
+	flag.StringVar(&routingPolicy, "scale-target-routing-policy", actionssummerwindnet.DefaultScaleTargetRoutingPolicy, fmt.Sprintf(`The policy to choose the HorizontalRunnerAutoscaler to scale when two or more HorizontalRunnerAutoscalers match a webhook event. Valid values are %s. "First" scales the first one found, searching repository, organization, and enterprise runners in this order.`, strings.Join(actionssummerwindnet.ScaleTargetRoutingPolicies, ", ")))
 	flag.StringVar(&webhookSecretToken, "github-webhook-secret-token", "", "The personal access token of GitHub.")
 	flag.StringVar(&c.Token, "github-token", c.Token, "The personal access token of GitHub.")
 	flag.Int64Var(&c.AppID, "github-app-id", c.AppID, "The application ID of GitHub App.")
@@ -102,6 +111,11 @@ func main() {
 	flag.StringVar(&c.BasicauthPassword, "github-basicauth-password", c.BasicauthPassword, "Password for GitHub basic auth to use instead of PAT or GitHub APP in case it's running behind a proxy API")
 	flag.StringVar(&c.RunnerGitHubURL, "runner-github-url", c.RunnerGitHubURL, "GitHub URL to be used by runners during registration")
 	flag.StringVar(&logFormat, "log-format", "text", `The log format. Valid options are "text" and "json". Defaults to "text"`)
+	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
+		"Enable leader election for the webhook server. Enabling this will ensure there is only one replica applying the scale operations in the queue journal.")
+	flag.StringVar(&leaderElectionId, "leader-election-id", "actions-runner-controller-github-webhook-server", "The id for leader election of the webhook server.")
+	flag.StringVar(&queueJournalName, "queue-journal-configmap", "", `The name prefix of the ConfigMaps to persist the scale operation queue, so that it survives restarts of the webhook server and is shared among the replicas. The queue is kept in memory when empty. Requires -enable-leader-election when running two or more replicas.`)
+	flag.StringVar(&queueJournalNamespace, "queue-journal-namespace", os.Getenv("POD_NAMESPACE"), `The namespace of the ConfigMaps specified by -queue-journal-configmap. Defaults to the value of the POD_NAMESPACE envvar.`)
 
 	flag.Parse()
 
@@ -112,6 +126,16 @@ func main() {
 	}
 	logger.WithName("setup")
 
+	if err := actionssummerwindnet.ValidateScaleTargetRoutingPolicy(routingPolicy); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if queueJournalName != "" && queueJournalNamespace == "" {
+		fmt.Fprintln(os.Stderr, "Error: -queue-journal-namespace or the POD_NAMESPACE envvar is required when -queue-journal-configmap is set")
+		os.Exit(1)
+	}
+
 	if webhookSecretToken == "" && webhookSecretTokenEnv != "" {
 		logger.Info(fmt.Sprintf("Using the value from %s for -github-webhook-secret-token", webhookSecretTokenEnvName))
 		webhookSecretToken = webhookSecretTokenEnv
@@ -154,6 +178,8 @@ func main() {
 		Namespace:          watchNamespace,
 		MetricsBindAddress: metricsAddr,
 		Port:               9443,
+		LeaderElection:     enableLeaderElection,
+		LeaderElectionID:   leaderElectionId,
 	})
 	if err != nil {
 		logger.Error(err, "unable to start manager")
@@ -170,6 +196,10 @@ func main() {
 		Namespace:      watchNamespace,
 		GitHubClient:   ghClient,
 		QueueLimit:     queueLimit,
+		RoutingPolicy:  routingPolicy,
+
+		QueueJournalNamespace: queueJournalNamespace,
+		QueueJournalName:      queueJournalName,
 	}
 
 	if err = hraGitHubWebhook.SetupWithManager(mgr); err != nil {
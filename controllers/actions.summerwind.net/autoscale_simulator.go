@@ -0,0 +1,518 @@
+package actionssummerwindnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	arcgithub "github.com/actions/actions-runner-controller/github"
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v47/github"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultAutoscaleSimulatorSyncPeriod is the default interval between reconciliations of each HRA in the simulation,
+	// which is the default sync period of the controller manager.
+	DefaultAutoscaleSimulatorSyncPeriod = time.Minute
+)
+
+// SimulatedWebhookEvent is a webhook event delivered at the recorded time.
+type SimulatedWebhookEvent struct {
+	// Time is the time the event was delivered
+	Time time.Time `json:"time"`
+	// Event is the type of the event, i.e. the value of the X-GitHub-Event header
+	Event string `json:"event"`
+	// Payload is the body of the webhook delivery
+	Payload json.RawMessage `json:"payload"`
+}
+
+// AutoscaleSimulationSample is the state of the simulation at a point in the virtual time.
+type AutoscaleSimulationSample struct {
+	Time time.Time
+
+	// QueuedJobs and InProgressJobs are the numbers of the workflow jobs in the respective status, as of the replayed workflow_job events
+	QueuedJobs     int
+	InProgressJobs int
+
+	// PendingOperations is the number of the scale operations waiting for the next batch of the batch scaler
+	PendingOperations int
+
+	// FailedDeliveries is the cumulative number of the webhook events that the webhook-based autoscaler failed to handle,
+	// including the ones rejected due to the full queue
+	FailedDeliveries int
+
+	// Targets is the state of each HRA, in the order of the namespaced names
+	Targets []AutoscaleSimulationTarget
+}
+
+// AutoscaleSimulationTarget is the state of an HRA in the simulation.
+type AutoscaleSimulationTarget struct {
+	HorizontalRunnerAutoscaler types.NamespacedName
+
+	// DesiredReplicas is the desired replicas in the status of the HRA, or nil when the HRA has never been reconciled successfully
+	DesiredReplicas *int
+
+	// CapacityReservations is the sum of the replicas of the unexpired capacity reservations of the HRA
+	CapacityReservations int
+}
+
+// AutoscaleSimulator replays recorded webhook events through the webhook-based autoscaler, the batch scaler,
+// and the HRA controller on a virtual clock, so that changes to HRAs and their scale targets can be evaluated offline
+// against a real workload before being rolled out.
+//
+// The GitHub API is emulated so that only the status of the workflow jobs of the replayed workflow_job events is available,
+// which is used to sync the capacity reservations. Runner pods are not simulated either.
+// Therefore, HRAs are expected to scale on webhook events only, and pull-based metrics fail to be computed.
+type AutoscaleSimulator struct {
+	// Client is the client of the Kubernetes API that has the HRAs and their scale targets to simulate, usually a fake one.
+	// It's modified by the simulation.
+	Client client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// RoutingPolicy and QueueLimit are the settings of the webhook-based autoscaler
+	RoutingPolicy string
+	QueueLimit    int
+
+	// BatchInterval is the interval between batches of the batch scaler. The interval of the batch scaler is used when omitted.
+	BatchInterval time.Duration
+
+	// SyncPeriod is the interval between reconciliations of each HRA in addition to the ones triggered by the HRA controller itself
+	// and the updates of capacity reservations. DefaultAutoscaleSimulatorSyncPeriod is used when omitted.
+	SyncPeriod time.Duration
+
+	// DefaultScaleDownDelay and CapacityReservationSyncPeriod are the settings of the HRA controller.
+	// DefaultScaleDownDelay and DefaultCapacityReservationSyncPeriod are used when omitted.
+	DefaultScaleDownDelay         time.Duration
+	CapacityReservationSyncPeriod time.Duration
+
+	// SampleInterval is the interval between samples. SyncPeriod is used when omitted.
+	SampleInterval time.Duration
+
+	// Tail is the duration the simulation continues after the last event, so that the scale down after the workload is observed.
+	Tail time.Duration
+}
+
+// Run replays the events in the order of their times and calls sample with the state of the simulation
+// at every SampleInterval, starting from the time of the first event.
+func (s *AutoscaleSimulator) Run(ctx context.Context, events []SimulatedWebhookEvent, sample func(AutoscaleSimulationSample)) error {
+	if len(events) == 0 {
+		return errors.New("no webhook events to replay")
+	}
+
+	events = append([]SimulatedWebhookEvent{}, events...)
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+
+	var (
+		now = events[0].Time
+		end = events[len(events)-1].Time.Add(s.Tail)
+
+		clock = func() time.Time { return now }
+		jobs  = simulatedWorkflowJobs{}
+	)
+
+	syncPeriod := s.SyncPeriod
+	if syncPeriod == 0 {
+		syncPeriod = DefaultAutoscaleSimulatorSyncPeriod
+	}
+
+	sampleInterval := s.SampleInterval
+	if sampleInterval == 0 {
+		sampleInterval = syncPeriod
+	}
+
+	scaleDownDelay := s.DefaultScaleDownDelay
+	if scaleDownDelay == 0 {
+		scaleDownDelay = DefaultScaleDownDelay
+	}
+
+	queueLimit := s.QueueLimit
+	if queueLimit == 0 {
+		queueLimit = DefaultQueueLimit
+	}
+
+	c := &scaleTargetIndexClient{Client: s.Client}
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Client:        c,
+		Log:           s.Log.WithName("webhook"),
+		Recorder:      &record.FakeRecorder{},
+		Scheme:        s.Scheme,
+		QueueLimit:    queueLimit,
+		RoutingPolicy: s.RoutingPolicy,
+	}
+
+	c.index = webhook.indexScaleTargetKeys
+
+	// The scale targets enqueued by the webhook-based autoscaler are dequeued by the simulation instead of the worker goroutines,
+	// so that they are batched on the virtual clock
+	webhook.worker = &worker{
+		scaleTargetQueue: make(chan *ScaleTarget, queueLimit),
+		done:             make(chan struct{}),
+	}
+	webhook.workerInit.Do(func() {})
+
+	batchScaler := newBatchScaler(ctx, c, s.Log.WithName("batchscaler"))
+	batchScaler.routingPolicy = s.RoutingPolicy
+	batchScaler.clock = clock
+
+	batchInterval := s.BatchInterval
+	if batchInterval == 0 {
+		batchInterval = batchScaler.interval
+	}
+
+	autoscaler := &HorizontalRunnerAutoscalerReconciler{
+		Client:                        c,
+		GitHubClient:                  NewMultiGitHubClient(c, newSimulatedGitHubClient(jobs)),
+		Log:                           s.Log.WithName("horizontalrunnerautoscaler"),
+		Recorder:                      &record.FakeRecorder{},
+		Scheme:                        s.Scheme,
+		DefaultScaleDownDelay:         scaleDownDelay,
+		CapacityReservationSyncPeriod: s.CapacityReservationSyncPeriod,
+		clock:                         clock,
+	}
+
+	var hraList v1alpha1.HorizontalRunnerAutoscalerList
+	if err := c.List(ctx, &hraList); err != nil {
+		return err
+	}
+
+	if len(hraList.Items) == 0 {
+		return errors.New("no horizontalrunnerautoscalers to simulate")
+	}
+
+	var hras []types.NamespacedName
+
+	// The time of the next reconciliation of each HRA
+	due := map[types.NamespacedName]time.Time{}
+
+	for _, hra := range hraList.Items {
+		nsName := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}
+
+		hras = append(hras, nsName)
+		due[nsName] = now
+	}
+
+	sort.Slice(hras, func(i, j int) bool {
+		return hras[i].String() < hras[j].String()
+	})
+
+	var (
+		next             int
+		failedDeliveries int
+
+		nextBatch  = now.Add(batchInterval)
+		nextSample = now
+	)
+
+	for {
+		t := nextBatch
+		if nextSample.Before(t) {
+			t = nextSample
+		}
+		if next < len(events) && events[next].Time.Before(t) {
+			t = events[next].Time
+		}
+		for _, d := range due {
+			if d.Before(t) {
+				t = d
+			}
+		}
+
+		if t.After(end) {
+			return nil
+		}
+
+		now = t
+
+		for ; next < len(events) && !events[next].Time.After(now); next++ {
+			ev := events[next]
+
+			jobs.observe(ev)
+
+			if err := s.deliver(ctx, webhook, ev); err != nil {
+				s.Log.V(1).Info("Failed to deliver webhook event", "time", ev.Time, "event", ev.Event, "error", err.Error())
+
+				failedDeliveries++
+			}
+		}
+
+		if !nextBatch.After(now) {
+			for _, nsName := range s.batchScale(ctx, batchScaler, webhook.worker.scaleTargetQueue) {
+				// The HRA controller reconciles the HRA as soon as the capacity reservations are updated, as it watches HRAs
+				due[nsName] = now
+			}
+
+			nextBatch = now.Add(batchInterval)
+		}
+
+		for _, nsName := range hras {
+			if due[nsName].After(now) {
+				continue
+			}
+
+			due[nsName] = now.Add(syncPeriod)
+
+			res, err := autoscaler.Reconcile(ctx, ctrl.Request{NamespacedName: nsName})
+			if err != nil {
+				s.Log.Error(err, "Failed to reconcile horizontalrunnerautoscaler", "horizontalrunnerautoscaler", nsName, "time", now)
+
+				continue
+			}
+
+			if res.Requeue {
+				due[nsName] = now.Add(batchInterval)
+			} else if res.RequeueAfter > 0 && res.RequeueAfter < syncPeriod {
+				due[nsName] = now.Add(res.RequeueAfter)
+			}
+		}
+
+		if !nextSample.After(now) {
+			smpl := AutoscaleSimulationSample{
+				Time:              now,
+				PendingOperations: len(webhook.worker.scaleTargetQueue),
+				FailedDeliveries:  failedDeliveries,
+			}
+
+			smpl.QueuedJobs, smpl.InProgressJobs = jobs.count()
+
+			for _, nsName := range hras {
+				var hra v1alpha1.HorizontalRunnerAutoscaler
+				if err := c.Get(ctx, nsName, &hra); err != nil {
+					return err
+				}
+
+				target := AutoscaleSimulationTarget{
+					HorizontalRunnerAutoscaler: nsName,
+					DesiredReplicas:            hra.Status.DesiredReplicas,
+				}
+
+				for _, r := range getValidCapacityReservationsAt(&hra, now) {
+					target.CapacityReservations += r.Replicas
+				}
+
+				smpl.Targets = append(smpl.Targets, target)
+			}
+
+			sample(smpl)
+
+			nextSample = nextSample.Add(sampleInterval)
+		}
+	}
+}
+
+// deliver sends the webhook event to the webhook-based autoscaler as GitHub does, without the signature.
+func (s *AutoscaleSimulator) deliver(ctx context.Context, webhook *HorizontalRunnerAutoscalerGitHubWebhook, ev SimulatedWebhookEvent) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader(ev.Payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", ev.Event)
+
+	w := &simulatedResponseWriter{header: http.Header{}}
+
+	webhook.Handle(w, req)
+
+	if w.code != 0 && w.code != http.StatusOK {
+		return fmt.Errorf("webhook server responded with status %d: %s", w.code, w.body.String())
+	}
+
+	return nil
+}
+
+// batchScale dequeues all the scale targets and applies them in a batch per HRA, as the batch worker does every interval.
+// It returns the HRAs that have been updated.
+func (s *AutoscaleSimulator) batchScale(ctx context.Context, batchScaler *batchScaler, queue chan *ScaleTarget) []types.NamespacedName {
+	batches := map[types.NamespacedName]batchScaleOperation{}
+	routed := map[types.NamespacedName]*routedReservations{}
+
+dequeue:
+	for {
+		select {
+		case st := <-queue:
+			batchScaler.addToBatches(st, batches, routed)
+		default:
+			break dequeue
+		}
+	}
+
+	var updated []types.NamespacedName
+
+	for nsName := range batches {
+		updated = append(updated, nsName)
+	}
+
+	sort.Slice(updated, func(i, j int) bool {
+		return updated[i].String() < updated[j].String()
+	})
+
+	for _, nsName := range updated {
+		if err := batchScaler.batchScale(ctx, batches[nsName]); err != nil {
+			s.Log.Error(err, "Failed to scale", "horizontalrunnerautoscaler", nsName)
+		}
+	}
+
+	return updated
+}
+
+// scaleTargetIndexClient filters the HRAs listed with the scaleTargetKey field selector by the index function of the webhook-based autoscaler,
+// as clients without the cache of the manager, like the fake client, ignore field selectors.
+type scaleTargetIndexClient struct {
+	client.Client
+
+	index func(client.Object) []string
+}
+
+func (c *scaleTargetIndexClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+
+	hraList, ok := list.(*v1alpha1.HorizontalRunnerAutoscalerList)
+	if !ok {
+		return nil
+	}
+
+	var listOpts client.ListOptions
+
+	listOpts.ApplyOptions(opts)
+
+	if listOpts.FieldSelector == nil {
+		return nil
+	}
+
+	value, found := listOpts.FieldSelector.RequiresExactMatch(scaleTargetKey)
+	if !found {
+		return nil
+	}
+
+	var items []v1alpha1.HorizontalRunnerAutoscaler
+
+	for i := range hraList.Items {
+		for _, key := range c.index(&hraList.Items[i]) {
+			if key == value {
+				items = append(items, hraList.Items[i])
+				break
+			}
+		}
+	}
+
+	hraList.Items = items
+
+	return nil
+}
+
+// simulatedWorkflowJobs is the status of each workflow job keyed by the repository and the job ID, as of the replayed workflow_job events.
+type simulatedWorkflowJobs map[string]string
+
+func simulatedWorkflowJobKey(repository string, id int64) string {
+	return repository + "/" + strconv.FormatInt(id, 10)
+}
+
+func (j simulatedWorkflowJobs) observe(ev SimulatedWebhookEvent) {
+	if ev.Event != "workflow_job" {
+		return
+	}
+
+	var e gogithub.WorkflowJobEvent
+	if err := json.Unmarshal(ev.Payload, &e); err != nil || e.WorkflowJob == nil {
+		return
+	}
+
+	status := e.WorkflowJob.GetStatus()
+	if status == "" {
+		status = e.GetAction()
+	}
+
+	j[simulatedWorkflowJobKey(e.Repo.GetFullName(), e.WorkflowJob.GetID())] = status
+}
+
+func (j simulatedWorkflowJobs) count() (queued, inProgress int) {
+	for _, status := range j {
+		switch status {
+		case "queued":
+			queued++
+		case "in_progress":
+			inProgress++
+		}
+	}
+
+	return queued, inProgress
+}
+
+// RoundTrip serves the status of the workflow job for GET /repos/{owner}/{repo}/actions/jobs/{job_id},
+// and responds with 404 to any other request.
+func (j simulatedWorkflowJobs) RoundTrip(req *http.Request) (*http.Response, error) {
+	code := http.StatusNotFound
+	body := `{"message":"Not Found"}`
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+
+	if req.Method == http.MethodGet && len(parts) == 6 && parts[0] == "repos" && parts[3] == "actions" && parts[4] == "jobs" {
+		id, err := strconv.ParseInt(parts[5], 10, 64)
+		if err == nil {
+			if status, ok := j[simulatedWorkflowJobKey(parts[1]+"/"+parts[2], id)]; ok {
+				code = http.StatusOK
+				body = fmt.Sprintf(`{"id":%d,"status":%q}`, id, status)
+			}
+		}
+	}
+
+	return &http.Response{
+		StatusCode: code,
+		Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func newSimulatedGitHubClient(jobs simulatedWorkflowJobs) *arcgithub.Client {
+	return &arcgithub.Client{
+		Client: gogithub.NewClient(&http.Client{Transport: jobs}),
+	}
+}
+
+// simulatedResponseWriter records the status code and the body of the response of the webhook-based autoscaler.
+type simulatedResponseWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *simulatedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *simulatedResponseWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+
+	return w.body.Write(b)
+}
+
+func (w *simulatedResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
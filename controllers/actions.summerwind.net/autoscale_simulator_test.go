@@ -0,0 +1,170 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v47/github"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestAutoscaleSimulator(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	f, err := os.Open("testdata/org_webhook_workflow_job_payload.json")
+	if err != nil {
+		t.Fatalf("could not open the fixture: %s", err)
+	}
+	defer f.Close()
+
+	var fixture github.WorkflowJobEvent
+	if err := json.NewDecoder(f).Decode(&fixture); err != nil {
+		t.Fatalf("invalid json: %s", err)
+	}
+
+	newEvent := func(at time.Duration, id int64, status, runnerName string) SimulatedWebhookEvent {
+		e := fixture
+		job := *fixture.WorkflowJob
+
+		e.Action = github.String(status)
+		e.WorkflowJob = &job
+		job.ID = github.Int64(id)
+		job.Status = github.String(status)
+
+		if runnerName != "" {
+			job.RunnerName = github.String(runnerName)
+		}
+
+		payload, err := json.Marshal(e)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return SimulatedWebhookEvent{Time: start.Add(at), Event: "workflow_job", Payload: payload}
+	}
+
+	events := []SimulatedWebhookEvent{
+		newEvent(5*time.Minute, 1, "completed", "rd-abcde"),
+		newEvent(5*time.Minute, 2, "completed", ""),
+		newEvent(0, 1, "queued", ""),
+		newEvent(0, 2, "queued", ""),
+		newEvent(2*time.Minute, 1, "in_progress", "rd-abcde"),
+	}
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "hra",
+		},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: v1alpha1.ScaleTargetRef{
+				Name: "rd",
+			},
+			MinReplicas:                       intPtr(1),
+			MaxReplicas:                       intPtr(10),
+			ScaleDownDelaySecondsAfterScaleUp: intPtr(300),
+			ScaleUpTriggers: []v1alpha1.ScaleUpTrigger{
+				{
+					GitHubEvent: &v1alpha1.GitHubEventScaleUpTriggerSpec{
+						WorkflowJob: &v1alpha1.WorkflowJobSpec{},
+					},
+					Duration: metav1.Duration{Duration: 30 * time.Minute},
+				},
+			},
+		},
+	}
+
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "rd",
+		},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			Template: v1alpha1.RunnerTemplate{
+				Spec: v1alpha1.RunnerSpec{
+					RunnerConfig: v1alpha1.RunnerConfig{
+						Organization: "MYORG",
+						Labels:       []string{"label1"},
+					},
+				},
+			},
+		},
+	}
+
+	sim := &AutoscaleSimulator{
+		Client: clientfake.NewClientBuilder().WithScheme(sc).WithObjects(hra, rd).Build(),
+		Log: zap.New(func(o *zap.Options) {
+			o.Development = true
+		}),
+		Scheme: sc,
+		Tail:   15 * time.Minute,
+	}
+
+	var samples []AutoscaleSimulationSample
+
+	if err := sim.Run(context.Background(), events, func(s AutoscaleSimulationSample) {
+		samples = append(samples, s)
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A sample per minute from the first event until the tail after the last event
+	if len(samples) != 21 {
+		t.Fatalf("unexpected number of samples: want 21, got %d", len(samples))
+	}
+
+	nsName := types.NamespacedName{Namespace: "default", Name: "hra"}
+
+	want := []AutoscaleSimulationSample{
+		{
+			// The jobs are yet to be batched
+			Time:              start,
+			QueuedJobs:        2,
+			PendingOperations: 2,
+			Targets:           []AutoscaleSimulationTarget{{HorizontalRunnerAutoscaler: nsName, DesiredReplicas: intPtr(1)}},
+		},
+		{
+			Time:       start.Add(time.Minute),
+			QueuedJobs: 2,
+			Targets:    []AutoscaleSimulationTarget{{HorizontalRunnerAutoscaler: nsName, DesiredReplicas: intPtr(3), CapacityReservations: 2}},
+		},
+		{
+			Time:           start.Add(3 * time.Minute),
+			QueuedJobs:     1,
+			InProgressJobs: 1,
+			Targets:        []AutoscaleSimulationTarget{{HorizontalRunnerAutoscaler: nsName, DesiredReplicas: intPtr(3), CapacityReservations: 2}},
+		},
+		{
+			// Scaled down once the scale down delay passed after the completions
+			Time:    start.Add(6 * time.Minute),
+			Targets: []AutoscaleSimulationTarget{{HorizontalRunnerAutoscaler: nsName, DesiredReplicas: intPtr(1)}},
+		},
+	}
+
+	got := []AutoscaleSimulationSample{samples[0], samples[1], samples[3], samples[6]}
+
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("unexpected samples: (-want, +got)\n%s", d)
+	}
+
+	var updated v1alpha1.RunnerDeployment
+	if err := sim.Client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "rd"}, &updated); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if updated.Spec.Replicas == nil || *updated.Spec.Replicas != 1 {
+		t.Errorf("unexpected replicas of the runnerdeployment: %v", updated.Spec.Replicas)
+	}
+}
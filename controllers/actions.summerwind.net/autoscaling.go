@@ -7,6 +7,7 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	prometheus_metrics "github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
@@ -23,72 +24,184 @@ const (
 	defaultScaleDownFactor    = 0.7
 )
 
-func (r *HorizontalRunnerAutoscalerReconciler) suggestDesiredReplicas(ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, error) {
+// metricProvider computes the desired replicas suggested by a single entry of HRA's spec.metrics.
+type metricProvider interface {
+	// suggestReplicas returns nil when the metric has no opinion on the desired replicas,
+	// so that the desired replicas are determined by other metrics and minReplicas.
+	suggestReplicas() (*int, error)
+}
+
+type metricProviderFunc func() (*int, error)
+
+func (f metricProviderFunc) suggestReplicas() (*int, error) {
+	return f()
+}
+
+// metricObservation records the value observed by a metric, which is shown in HRA's status.currentMetrics.
+// A nil metricObservation discards the value.
+type metricObservation struct {
+	value string
+}
+
+func (o *metricObservation) observe(format string, args ...interface{}) {
+	if o == nil {
+		return
+	}
+
+	o.value = fmt.Sprintf(format, args...)
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) newMetricProvider(ctx context.Context, ghc *arcgithub.Client, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metric v1alpha1.MetricSpec, obs *metricObservation) (metricProvider, error) {
+	switch metric.Type {
+	case v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns:
+		return metricProviderFunc(func() (*int, error) {
+			return r.suggestReplicasByQueuedAndInProgressWorkflowRuns(ctx, ghc, now, st, hra, &metric, obs)
+		}), nil
+	case v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowJobs:
+		return metricProviderFunc(func() (*int, error) {
+			return r.suggestReplicasByQueuedAndInProgressWorkflowJobs(ctx, ghc, now, st, hra, metric, obs)
+		}), nil
+	case v1alpha1.AutoscalingMetricTypePercentageRunnersBusy:
+		return metricProviderFunc(func() (*int, error) {
+			return r.suggestReplicasByPercentageRunnersBusy(ctx, ghc, st, hra, metric, obs)
+		}), nil
+	case v1alpha1.AutoscalingMetricTypePrometheusQuery:
+		return metricProviderFunc(func() (*int, error) {
+			return r.suggestReplicasByPrometheusQuery(st, hra, metric, obs)
+		}), nil
+	case v1alpha1.AutoscalingMetricTypeIdleRunnerBuffer:
+		return metricProviderFunc(func() (*int, error) {
+			return r.suggestReplicasByIdleRunnerBuffer(ghc, st, hra, metric, obs)
+		}), nil
+	default:
+		return nil, fmt.Errorf("validating autoscaling metrics: unsupported metric type %q", metric.Type)
+	}
+}
+
+// suggestDesiredReplicas returns the desired replicas suggested by the metrics,
+// along with the observation of each metric in the same order as HRA's spec.metrics.
+func (r *HorizontalRunnerAutoscalerReconciler) suggestDesiredReplicas(ctx context.Context, ghc *arcgithub.Client, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, []v1alpha1.MetricStatus, error) {
 	if hra.Spec.MinReplicas == nil {
-		return nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing minReplicas", hra.Namespace, hra.Name)
+		return nil, nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing minReplicas", hra.Namespace, hra.Name)
 	} else if hra.Spec.MaxReplicas == nil {
-		return nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing maxReplicas", hra.Namespace, hra.Name)
+		return nil, nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing maxReplicas", hra.Namespace, hra.Name)
 	}
 
 	metrics := hra.Spec.Metrics
-	numMetrics := len(metrics)
-	if numMetrics == 0 {
+	if len(metrics) == 0 {
 		// We don't default to anything since ARC 0.23.0
 		// See https://github.com/actions/actions-runner-controller/issues/728
-		return nil, nil
-	} else if numMetrics > 2 {
-		return nil, fmt.Errorf("too many autoscaling metrics configured: It must be 0 to 2, but got %d", numMetrics)
+		return nil, nil, nil
 	}
 
-	primaryMetric := metrics[0]
-	primaryMetricType := primaryMetric.Type
+	statuses := make([]v1alpha1.MetricStatus, len(metrics))
+	providers := make([]metricProvider, 0, len(metrics))
 
-	var (
-		suggested *int
-		err       error
-	)
+	for i, m := range metrics {
+		obs := &metricObservation{}
 
-	switch primaryMetricType {
-	case v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns:
-		suggested, err = r.suggestReplicasByQueuedAndInProgressWorkflowRuns(ghc, st, hra, &primaryMetric)
-	case v1alpha1.AutoscalingMetricTypePercentageRunnersBusy:
-		suggested, err = r.suggestReplicasByPercentageRunnersBusy(ghc, st, hra, primaryMetric)
-	default:
-		return nil, fmt.Errorf("validating autoscaling metrics: unsupported metric type %q", primaryMetric)
+		p, err := r.newMetricProvider(ctx, ghc, now, st, hra, m, obs)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		status := &statuses[i]
+		status.Type = m.Type
+
+		providers = append(providers, metricProviderFunc(func() (*int, error) {
+			suggested, err := p.suggestReplicas()
+			if err != nil {
+				return nil, err
+			}
+
+			status.Value = obs.value
+
+			if suggested != nil {
+				v := *suggested
+				status.SuggestedReplicas = &v
+			}
+
+			return suggested, nil
+		}))
 	}
 
+	suggested, err := aggregateSuggestedReplicas(hra.Spec.MetricsAggregationPolicy, providers)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if suggested != nil && *suggested > 0 {
-		return suggested, nil
-	}
+	return suggested, statuses, nil
+}
+
+// aggregateSuggestedReplicas calls each metric provider in order and aggregates their suggestions according to the policy.
+//
+// FirstNonNil, which is the default, stops at the first metric that suggests one or more replicas,
+// so that the later metrics don't consume the API rate limit unless necessary.
+// A suggestion of zero replicas is skipped as well, so that e.g. PercentageRunnersBusy can be followed by
+// TotalNumberOfQueuedAndInProgressWorkflowRuns to scale from zero.
+//
+// The other policies call every metric provider, ignore nil suggestions, and aggregate the rest like
+// Kubernetes HPA does across multiple metrics.
+func aggregateSuggestedReplicas(policy string, providers []metricProvider) (*int, error) {
+	switch policy {
+	case "", v1alpha1.MetricsAggregationPolicyFirstNonNil:
+		for _, p := range providers {
+			suggested, err := p.suggestReplicas()
+			if err != nil {
+				return nil, err
+			}
+
+			if suggested != nil && *suggested > 0 {
+				return suggested, nil
+			}
+		}
 
-	if len(metrics) == 1 {
-		// This is never supposed to happen but anyway-
 		// Fall-back to `minReplicas + capacityReservedThroughWebhook`.
 		return nil, nil
+	case v1alpha1.MetricsAggregationPolicyMax, v1alpha1.MetricsAggregationPolicyMin, v1alpha1.MetricsAggregationPolicySum:
+	default:
+		return nil, fmt.Errorf("validating autoscaling metrics: unsupported metrics aggregation policy %q", policy)
 	}
 
-	// At this point, we are sure that there are exactly 2 Metrics entries.
+	var aggregated *int
+
+	for _, p := range providers {
+		suggested, err := p.suggestReplicas()
+		if err != nil {
+			return nil, err
+		}
 
-	fallbackMetric := metrics[1]
-	fallbackMetricType := fallbackMetric.Type
+		if suggested == nil {
+			continue
+		}
 
-	if primaryMetricType != v1alpha1.AutoscalingMetricTypePercentageRunnersBusy ||
-		fallbackMetricType != v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns {
+		v := *suggested
 
-		return nil, fmt.Errorf(
-			"invalid HRA Spec: Metrics[0] of %s cannot be combined with Metrics[1] of %s: The only allowed combination is 0=PercentageRunnersBusy and 1=TotalNumberOfQueuedAndInProgressWorkflowRuns",
-			primaryMetricType, fallbackMetricType,
-		)
+		if aggregated != nil {
+			switch policy {
+			case v1alpha1.MetricsAggregationPolicyMax:
+				if *aggregated > v {
+					v = *aggregated
+				}
+			case v1alpha1.MetricsAggregationPolicyMin:
+				if *aggregated < v {
+					v = *aggregated
+				}
+			case v1alpha1.MetricsAggregationPolicySum:
+				v += *aggregated
+			}
+		}
+
+		aggregated = &v
 	}
 
-	return r.suggestReplicasByQueuedAndInProgressWorkflowRuns(ghc, st, hra, &fallbackMetric)
+	return aggregated, nil
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgressWorkflowRuns(ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics *v1alpha1.MetricSpec) (*int, error) {
+// workflowRunRepositories returns the pairs of the owner and the name of the repositories
+// whose workflow runs are used to calculate the metric.
+// It returns nil for an organizational scale target without metrics.
+func (r *HorizontalRunnerAutoscalerReconciler) workflowRunRepositories(ctx context.Context, ghc *arcgithub.Client, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics *v1alpha1.MetricSpec) ([][]string, error) {
 	var repos [][]string
 	repoID := st.repo
 	if repoID == "" {
@@ -104,11 +217,16 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 			return nil, nil
 		}
 
-		if len(metrics.RepositoryNames) == 0 {
-			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames is required and must have one more more entries for organizational runner deployment")
+		if len(metrics.RepositoryNames) == 0 && metrics.RepositoryDiscovery == nil {
+			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames or repositoryDiscovery is required for organizational runner deployment")
+		}
+
+		repoNames, err := r.expandRepositoryNames(ctx, ghc, now, st, hra, metrics)
+		if err != nil {
+			return nil, err
 		}
 
-		for _, repoName := range metrics.RepositoryNames {
+		for _, repoName := range repoNames {
 			repos = append(repos, []string{orgName, repoName})
 		}
 	} else {
@@ -117,51 +235,49 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 		repos = append(repos, repo)
 	}
 
+	return repos, nil
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgressWorkflowRuns(ctx context.Context, ghc *arcgithub.Client, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics *v1alpha1.MetricSpec, obs *metricObservation) (*int, error) {
+	repos, err := r.workflowRunRepositories(ctx, ghc, now, st, hra, metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	if repos == nil {
+		return nil, nil
+	}
+
+	snapshots, err := r.workflowRunPoller().Snapshots(ctx, ghc, repos)
+	if err != nil {
+		return nil, err
+	}
+
 	var total, inProgress, queued, completed, unknown int
 	type callback func()
-	listWorkflowJobs := func(user string, repoName string, runID int64, fallback_cb callback) {
+	countWorkflowJobs := func(snapshot *workflowRunsSnapshot, runID int64, fallback_cb callback) {
 		if runID == 0 {
 			fallback_cb()
 			return
 		}
-		opt := github.ListWorkflowJobsOptions{ListOptions: github.ListOptions{PerPage: 50}}
-		var allJobs []*github.WorkflowJob
-		for {
-			jobs, resp, err := ghc.Actions.ListWorkflowJobs(context.TODO(), user, repoName, runID, &opt)
-			if err != nil {
-				r.Log.Error(err, "Error listing workflow jobs")
-				return //err
-			}
-			allJobs = append(allJobs, jobs.Jobs...)
-			if resp.NextPage == 0 {
-				break
-			}
-			opt.Page = resp.NextPage
+		allJobs, ok := snapshot.jobs[runID]
+		if !ok {
+			// The poller failed to list the jobs, which has already been logged
+			return
 		}
 		if len(allJobs) == 0 {
 			fallback_cb()
 		} else {
 		JOB:
 			for _, job := range allJobs {
-				runnerLabels := make(map[string]struct{}, len(st.labels))
-				for _, l := range st.labels {
-					runnerLabels[l] = struct{}{}
-				}
-
 				if len(job.Labels) == 0 {
 					// This shouldn't usually happen
 					r.Log.Info("Detected job with no labels, which is not supported by ARC. Skipping anyway.", "labels", job.Labels, "run_id", job.GetRunID(), "job_id", job.GetID())
 					continue JOB
 				}
 
-				for _, l := range job.Labels {
-					if l == "self-hosted" {
-						continue
-					}
-
-					if _, ok := runnerLabels[l]; !ok {
-						continue JOB
-					}
+				if !st.labels.matches(job.Labels) {
+					continue JOB
 				}
 
 				switch job.GetStatus() {
@@ -183,14 +299,8 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 		}
 	}
 
-	for _, repo := range repos {
-		user, repoName := repo[0], repo[1]
-		workflowRuns, err := ghc.ListRepositoryWorkflowRuns(context.TODO(), user, repoName)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, run := range workflowRuns {
+	for _, snapshot := range snapshots {
+		for _, run := range snapshot.runs {
 			total++
 
 			// In May 2020, there are only 3 statuses.
@@ -201,9 +311,9 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 			case "completed":
 				completed++
 			case "in_progress":
-				listWorkflowJobs(user, repoName, run.GetID(), func() { inProgress++ })
+				countWorkflowJobs(snapshot, run.GetID(), func() { inProgress++ })
 			case "queued":
-				listWorkflowJobs(user, repoName, run.GetID(), func() { queued++ })
+				countWorkflowJobs(snapshot, run.GetID(), func() { queued++ })
 			default:
 				unknown++
 			}
@@ -212,6 +322,8 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 
 	necessaryReplicas := queued + inProgress
 
+	obs.observe("queued=%d inProgress=%d", queued, inProgress)
+
 	prometheus_metrics.SetHorizontalRunnerAutoscalerQueuedAndInProgressWorkflowRuns(
 		hra.ObjectMeta,
 		st.enterprise,
@@ -241,8 +353,79 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 	return &necessaryReplicas, nil
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunnersBusy(ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec) (*int, error) {
-	ctx := context.Background()
+// suggestReplicasByQueuedAndInProgressWorkflowJobs suggests the number of the queued and in-progress jobs
+// that can run on the scale target's runners.
+// Unlike TotalNumberOfQueuedAndInProgressWorkflowRuns, it never counts a workflow run without jobs as a replica,
+// and the jobs are matched against the runner labels plus the implicit runner labels of the metric.
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgressWorkflowJobs(ctx context.Context, ghc *arcgithub.Client, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec, obs *metricObservation) (*int, error) {
+	repos, err := r.workflowRunRepositories(ctx, ghc, now, st, hra, &metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := r.workflowRunPoller().Snapshots(ctx, ghc, repos)
+	if err != nil {
+		return nil, err
+	}
+
+	implicitLabels := metrics.ImplicitRunnerLabels
+
+	var inProgress, queued, unmatched, unknown int
+
+	for _, snapshot := range snapshots {
+		for _, run := range snapshot.runs {
+			for _, job := range snapshot.jobs[run.GetID()] {
+				if len(job.Labels) == 0 || !st.labels.matches(job.Labels, implicitLabels...) {
+					unmatched++
+					continue
+				}
+
+				switch job.GetStatus() {
+				case "completed":
+				case "in_progress":
+					inProgress++
+				case "queued":
+					queued++
+				default:
+					unknown++
+				}
+			}
+		}
+	}
+
+	necessaryReplicas := queued + inProgress
+
+	obs.observe("queued=%d inProgress=%d", queued, inProgress)
+
+	prometheus_metrics.SetHorizontalRunnerAutoscalerQueuedAndInProgressWorkflowJobs(
+		hra.ObjectMeta,
+		st.enterprise,
+		st.org,
+		st.repo,
+		st.kind,
+		st.st,
+		necessaryReplicas,
+		inProgress,
+		queued,
+	)
+
+	r.Log.V(1).Info(
+		fmt.Sprintf("Suggested desired replicas of %d by TotalNumberOfQueuedAndInProgressWorkflowJobs", necessaryReplicas),
+		"workflow_jobs_in_progress", inProgress,
+		"workflow_jobs_queued", queued,
+		"workflow_jobs_unmatched", unmatched,
+		"workflow_jobs_unknown", unknown,
+		"implicit_runner_labels", implicitLabels,
+		"namespace", hra.Namespace,
+		"kind", st.kind,
+		"name", st.st,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	return &necessaryReplicas, nil
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunnersBusy(ctx context.Context, ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec, obs *metricObservation) (*int, error) {
 	scaleUpThreshold := defaultScaleUpThreshold
 	scaleDownThreshold := defaultScaleDownThreshold
 	scaleUpFactor := defaultScaleUpFactor
@@ -298,27 +481,26 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunner
 		scaleDownFactor = sdf
 	}
 
-	runnerMap, err := st.getRunnerMap()
+	counts, err := r.countRunners(ctx, ghc, st, hra)
 	if err != nil {
 		return nil, err
 	}
 
+	var pool *runnerPoolCounts
+
+	if metrics.RunnerPool != nil {
+		pool, err = r.countRunnerPool(ctx, ghc, st, *metrics.RunnerPool)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var (
 		enterprise   = st.enterprise
 		organization = st.org
 		repository   = st.repo
 	)
 
-	// ListRunners will return all runners managed by GitHub - not restricted to ns
-	runners, err := ghc.ListRunners(
-		ctx,
-		enterprise,
-		organization,
-		repository)
-	if err != nil {
-		return nil, err
-	}
-
 	var desiredReplicasBefore int
 
 	if v := st.replicas; v == nil {
@@ -328,55 +510,20 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunner
 	}
 
 	var (
-		numRunners           int
-		numRunnersRegistered int
-		numRunnersBusy       int
-		numTerminatingBusy   int
+		numRunners           = counts.runners
+		numRunnersRegistered = counts.registered
+		numRunnersBusy       = counts.busy
+		numTerminatingBusy   = counts.terminatingBusy
 	)
 
-	numRunners = len(runnerMap)
-
-	busyTerminatingRunnerPods := map[string]struct{}{}
-
-	kindLabel := LabelKeyRunnerDeploymentName
-	if hra.Spec.ScaleTargetRef.Kind == "RunnerSet" {
-		kindLabel = LabelKeyRunnerSetName
-	}
-
-	var runnerPodList corev1.PodList
-	if err := r.Client.List(ctx, &runnerPodList, client.InNamespace(hra.Namespace), client.MatchingLabels(map[string]string{
-		kindLabel: hra.Spec.ScaleTargetRef.Name,
-	})); err != nil {
-		return nil, err
-	}
-
-	for _, p := range runnerPodList.Items {
-		if p.Annotations[AnnotationKeyUnregistrationFailureMessage] != "" {
-			busyTerminatingRunnerPods[p.Name] = struct{}{}
-		}
-	}
-
-	for _, runner := range runners {
-		if _, ok := runnerMap[*runner.Name]; ok {
-			numRunnersRegistered++
-
-			if runner.GetBusy() {
-				numRunnersBusy++
-			} else if _, ok := busyTerminatingRunnerPods[*runner.Name]; ok {
-				numTerminatingBusy++
-			}
-
-			delete(busyTerminatingRunnerPods, *runner.Name)
-		}
-	}
-
-	// Remaining busyTerminatingRunnerPods are runners that were not on the ListRunners API response yet
-	for range busyTerminatingRunnerPods {
-		numTerminatingBusy++
-	}
-
 	var desiredReplicas int
-	fractionBusy := float64(numRunnersBusy+numTerminatingBusy) / float64(desiredReplicasBefore)
+	var fractionBusy float64
+	if pool == nil {
+		fractionBusy = float64(numRunnersBusy+numTerminatingBusy) / float64(desiredReplicasBefore)
+	} else if pool.runners > 0 {
+		// The busy ratio of the whole pool drives the scale of our own runners
+		fractionBusy = float64(pool.busy) / float64(pool.runners)
+	}
 	if fractionBusy >= scaleUpThreshold {
 		if scaleUpAdjustment > 0 {
 			desiredReplicas = desiredReplicasBefore + scaleUpAdjustment
@@ -393,6 +540,12 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunner
 		desiredReplicas = *st.replicas
 	}
 
+	if pool == nil {
+		obs.observe("busy=%d replicas=%d", numRunnersBusy+numTerminatingBusy, desiredReplicasBefore)
+	} else {
+		obs.observe("busy=%d pool=%d replicas=%d", pool.busy, pool.runners, desiredReplicasBefore)
+	}
+
 	// NOTES for operators:
 	//
 	// - num_runners can be as twice as large as replicas_desired_before while
@@ -428,5 +581,178 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunner
 		"repository", repository,
 	)
 
+	if pool != nil {
+		r.Log.V(1).Info(
+			"Used the runner pool for PercentageRunnersBusy",
+			"pool_group", metrics.RunnerPool.Group,
+			"pool_labels", metrics.RunnerPool.Labels,
+			"pool_runners", pool.runners,
+			"pool_busy", pool.busy,
+			"horizontal_runner_autoscaler", hra.Name,
+		)
+	}
+
 	return &desiredReplicas, nil
 }
+
+// runnerCounts is the numbers of runners of a scale target, as observed via the GitHub API and the runner pods.
+type runnerCounts struct {
+	// runners is the number of runners managed by the scale target.
+	runners int
+	// registered is the number of runners managed by the scale target that are registered to GitHub.
+	registered int
+	// busy is the number of registered runners that are running jobs.
+	busy int
+	// terminatingBusy is the number of runners that failed to be unregistered because they were running jobs.
+	terminatingBusy int
+}
+
+// countRunners counts the runners of the scale target, busy or not, using ListRunners.
+func (r *HorizontalRunnerAutoscalerReconciler) countRunners(ctx context.Context, ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler) (*runnerCounts, error) {
+	runnerMap, err := st.getRunnerMap()
+	if err != nil {
+		return nil, err
+	}
+
+	// ListRunners will return all runners managed by GitHub - not restricted to ns
+	runners, err := ghc.ListRunners(
+		ctx,
+		st.enterprise,
+		st.org,
+		st.repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts runnerCounts
+
+	counts.runners = len(runnerMap)
+
+	busyTerminatingRunnerPods := map[string]struct{}{}
+
+	runnerPodList, err := r.listRunnerPods(ctx, hra, st)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range runnerPodList.Items {
+		if p.Annotations[AnnotationKeyUnregistrationFailureMessage] != "" {
+			busyTerminatingRunnerPods[p.Name] = struct{}{}
+		}
+	}
+
+	for _, runner := range runners {
+		if _, ok := runnerMap[*runner.Name]; ok {
+			counts.registered++
+
+			if runner.GetBusy() {
+				counts.busy++
+			} else if _, ok := busyTerminatingRunnerPods[*runner.Name]; ok {
+				counts.terminatingBusy++
+			}
+
+			delete(busyTerminatingRunnerPods, *runner.Name)
+		}
+	}
+
+	// Remaining busyTerminatingRunnerPods are runners that were not on the ListRunners API response yet
+	for range busyTerminatingRunnerPods {
+		counts.terminatingBusy++
+	}
+
+	return &counts, nil
+}
+
+// runnerPoolCounts is the numbers of online runners in the runner pool of the PercentageRunnersBusy metric.
+type runnerPoolCounts struct {
+	// runners is the number of online runners in the pool, regardless of who manages them.
+	runners int
+	// busy is the number of online runners in the pool that are running jobs.
+	busy int
+}
+
+// countRunnerPool counts the online runners in the runner group or with the labels of the pool,
+// which are registered to the enterprise, organization, or repository of the scale target.
+func (r *HorizontalRunnerAutoscalerReconciler) countRunnerPool(ctx context.Context, ghc *arcgithub.Client, st scaleTarget, pool v1alpha1.RunnerPoolSpec) (*runnerPoolCounts, error) {
+	var (
+		runners []*github.Runner
+		err     error
+	)
+
+	if pool.Group != "" {
+		if st.repo != "" {
+			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].runnerPool.group cannot be specified for repository runners")
+		}
+
+		runners, err = ghc.ListRunnerGroupRunners(ctx, st.enterprise, st.org, pool.Group)
+	} else {
+		runners, err = ghc.ListRunners(ctx, st.enterprise, st.org, st.repo)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var counts runnerPoolCounts
+
+	for _, runner := range runners {
+		if runner.GetStatus() != "online" || !runnerHasLabels(runner, pool.Labels) {
+			continue
+		}
+
+		counts.runners++
+
+		if runner.GetBusy() {
+			counts.busy++
+		}
+	}
+
+	return &counts, nil
+}
+
+// runnerHasLabels returns true when the runner has all the labels, compared case-insensitively as GitHub does.
+func runnerHasLabels(runner *github.Runner, labels []string) bool {
+	for _, l := range labels {
+		var found bool
+
+		for _, rl := range runner.Labels {
+			if strings.EqualFold(rl.GetName(), l) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// listRunnerPods lists the runner pods of the scale target.
+func (r *HorizontalRunnerAutoscalerReconciler) listRunnerPods(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget) (*corev1.PodList, error) {
+	opts := []client.ListOption{client.InNamespace(hra.Namespace)}
+
+	if st.selector != nil {
+		opts = append(opts, client.MatchingLabelsSelector{Selector: st.selector})
+	} else if !isRunnerScaleTargetRef(hra.Spec.ScaleTargetRef) {
+		// Without the selector, we can't tell which pods are runners of the scale target
+		return &corev1.PodList{}, nil
+	} else {
+		kindLabel := LabelKeyRunnerDeploymentName
+		if hra.Spec.ScaleTargetRef.Kind == "RunnerSet" {
+			kindLabel = LabelKeyRunnerSetName
+		}
+
+		opts = append(opts, client.MatchingLabels(map[string]string{
+			kindLabel: hra.Spec.ScaleTargetRef.Name,
+		}))
+	}
+
+	var runnerPodList corev1.PodList
+	if err := r.Client.List(ctx, &runnerPodList, opts...); err != nil {
+		return nil, err
+	}
+
+	return &runnerPodList, nil
+}
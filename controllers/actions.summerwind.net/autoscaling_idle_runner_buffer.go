@@ -0,0 +1,66 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	prometheus_metrics "github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	arcgithub "github.com/actions/actions-runner-controller/github"
+)
+
+// suggestReplicasByIdleRunnerBuffer suggests the number of busy runners plus the configured number of idle runners,
+// so that the specified number of runners are always available for new jobs no matter how many runners are busy.
+//
+// Unlike PercentageRunnersBusy, the suggestion doesn't depend on the current number of replicas,
+// which gives a stable buffer even for small scale targets without oversizing large ones.
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByIdleRunnerBuffer(ghc *arcgithub.Client, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec, obs *metricObservation) (*int, error) {
+	if metrics.IdleRunners < 0 {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].idleRunners cannot be lower than 0")
+	}
+
+	counts, err := r.countRunners(context.Background(), ghc, st, hra)
+	if err != nil {
+		return nil, err
+	}
+
+	// Busy runners that are being terminated still occupy their replicas until they complete their jobs
+	desiredReplicas := counts.busy + counts.terminatingBusy + metrics.IdleRunners
+
+	obs.observe("busy=%d idleRunners=%d", counts.busy+counts.terminatingBusy, metrics.IdleRunners)
+
+	prometheus_metrics.SetHorizontalRunnerAutoscalerIdleRunnerBuffer(
+		hra.ObjectMeta,
+		st.enterprise,
+		st.org,
+		st.repo,
+		st.kind,
+		st.st,
+		desiredReplicas,
+		counts.runners,
+		counts.registered,
+		counts.busy,
+		counts.terminatingBusy,
+		metrics.IdleRunners,
+	)
+
+	r.Log.V(1).Info(
+		fmt.Sprintf("Suggested desired replicas of %d by IdleRunnerBuffer", desiredReplicas),
+		"replicas_desired", desiredReplicas,
+		"num_runners", counts.runners,
+		"num_runners_registered", counts.registered,
+		"num_runners_busy", counts.busy,
+		"num_terminating_busy", counts.terminatingBusy,
+		"idle_runners", metrics.IdleRunners,
+		"namespace", hra.Namespace,
+		"kind", st.kind,
+		"name", st.st,
+		"horizontal_runner_autoscaler", hra.Name,
+		"enterprise", st.enterprise,
+		"organization", st.org,
+		"repository", st.repo,
+	)
+
+	return &desiredReplicas, nil
+}
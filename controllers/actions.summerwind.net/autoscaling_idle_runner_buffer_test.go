@@ -0,0 +1,142 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestSuggestReplicasByIdleRunnerBuffer(t *testing.T) {
+	runners := `{"total_count": 4, "runners": [
+		{"id": 1, "name": "busy1", "os": "linux", "status": "online", "busy": true},
+		{"id": 2, "name": "busy2", "os": "linux", "status": "online", "busy": true},
+		{"id": 3, "name": "idle1", "os": "linux", "status": "online", "busy": false},
+		{"id": 4, "name": "other", "os": "linux", "status": "online", "busy": true}
+	]}`
+
+	testcases := []struct {
+		description string
+		idleRunners int
+		runnerMap   map[string]struct{}
+		pods        []string
+		want        int
+		err         string
+	}{
+		{
+			description: "busy runners plus idle runners",
+			idleRunners: 3,
+			runnerMap:   map[string]struct{}{"busy1": {}, "busy2": {}, "idle1": {}},
+			want:        5,
+		},
+		{
+			description: "no busy runners",
+			idleRunners: 2,
+			runnerMap:   map[string]struct{}{"idle1": {}},
+			want:        2,
+		},
+		{
+			description: "busy runners being terminated are counted as busy",
+			idleRunners: 1,
+			runnerMap:   map[string]struct{}{"busy1": {}, "idle1": {}, "terminating1": {}},
+			pods:        []string{"terminating1"},
+			want:        3,
+		},
+		{
+			description: "negative idle runners",
+			idleRunners: -1,
+			err:         "validating autoscaling metrics: spec.autoscaling.metrics[].idleRunners cannot be lower than 0",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.description, func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRunnersResponse(200, runners),
+			)
+			defer server.Close()
+
+			ghc := newGithubClient(server)
+
+			var objs []runtime.Object
+
+			for _, name := range tc.pods {
+				objs = append(objs, &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "default",
+						Name:      name,
+						Labels: map[string]string{
+							LabelKeyRunnerDeploymentName: "testrd",
+						},
+						Annotations: map[string]string{
+							AnnotationKeyUnregistrationFailureMessage: "runner is busy",
+						},
+					},
+				})
+			}
+
+			log := zap.New(func(o *zap.Options) {
+				o.Development = true
+			})
+
+			r := &HorizontalRunnerAutoscalerReconciler{
+				Client: clientfake.NewClientBuilder().WithScheme(sc).WithRuntimeObjects(objs...).Build(),
+				Log:    log,
+			}
+
+			st := scaleTarget{
+				st:   "testrd",
+				kind: "runnerdeployment",
+				repo: "test/valid",
+				getRunnerMap: func() (map[string]struct{}, error) {
+					return tc.runnerMap, nil
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "testhra",
+				},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: v1alpha1.ScaleTargetRef{
+						Name: "testrd",
+					},
+				},
+			}
+
+			metric := v1alpha1.MetricSpec{
+				Type:        v1alpha1.AutoscalingMetricTypeIdleRunnerBuffer,
+				IdleRunners: tc.idleRunners,
+			}
+
+			got, err := r.suggestReplicasByIdleRunnerBuffer(ghc, st, hra, metric, nil)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("unexpected error: want %q, got %v", tc.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got == nil {
+				t.Fatal("want a suggestion, got nil")
+			}
+
+			if *got != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, *got)
+			}
+		})
+	}
+}
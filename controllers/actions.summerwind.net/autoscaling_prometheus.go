@@ -0,0 +1,181 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+)
+
+const defaultPrometheusQueryTimeout = 30 * time.Second
+
+// prometheusQueryResponse is the subset of the response body of Prometheus' instant query API
+// that we need to read the query result.
+// See https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
+type prometheusQueryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// queryPrometheus evaluates the PromQL query against the Prometheus-compatible HTTP API at address.
+// It returns nil when the query resulted in an empty vector.
+func queryPrometheus(ctx context.Context, httpClient *http.Client, address, query string) (*float64, error) {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultPrometheusQueryTimeout}
+	}
+
+	endpoint := strings.TrimSuffix(address, "/") + "/api/v1/query"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(url.Values{"query": []string{query}}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("querying prometheus at %s: %w", endpoint, err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading prometheus response: %w", err)
+	}
+
+	var r prometheusQueryResponse
+
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("parsing prometheus response with status code %d: %w", res.StatusCode, err)
+	}
+
+	if r.Status != "success" {
+		return nil, fmt.Errorf("querying prometheus at %s: %s: %s", endpoint, r.ErrorType, r.Error)
+	}
+
+	var sample []interface{}
+
+	switch r.Data.ResultType {
+	case "scalar":
+		if err := json.Unmarshal(r.Data.Result, &sample); err != nil {
+			return nil, fmt.Errorf("parsing prometheus scalar result: %w", err)
+		}
+	case "vector":
+		var vector []struct {
+			Value []interface{} `json:"value"`
+		}
+
+		if err := json.Unmarshal(r.Data.Result, &vector); err != nil {
+			return nil, fmt.Errorf("parsing prometheus vector result: %w", err)
+		}
+
+		if len(vector) == 0 {
+			return nil, nil
+		} else if len(vector) > 1 {
+			return nil, fmt.Errorf("prometheus query %q resulted in %d elements, but it must result in at most one element. Consider aggregating it with e.g. sum()", query, len(vector))
+		}
+
+		sample = vector[0].Value
+	default:
+		return nil, fmt.Errorf("prometheus query %q resulted in unsupported result type %q", query, r.Data.ResultType)
+	}
+
+	// A sample is a pair of the timestamp and the value that is encoded as a string.
+	if len(sample) != 2 {
+		return nil, fmt.Errorf("parsing prometheus result: unexpected sample %v", sample)
+	}
+
+	s, ok := sample[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("parsing prometheus result: unexpected sample value %v", sample[1])
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing prometheus result: %w", err)
+	}
+
+	return &v, nil
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPrometheusQuery(st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec, obs *metricObservation) (*int, error) {
+	spec := metrics.Prometheus
+	if spec == nil {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].prometheus is required for PrometheusQuery")
+	}
+
+	if spec.Address == "" {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].prometheus.address is required")
+	}
+
+	if spec.Query == "" {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].prometheus.query is required")
+	}
+
+	targetValuePerRunner, err := strconv.ParseFloat(spec.TargetValuePerRunner, 64)
+	if err != nil {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].prometheus.targetValuePerRunner cannot be parsed into a float64")
+	}
+
+	if targetValuePerRunner <= 0 {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].prometheus.targetValuePerRunner must be greater than 0")
+	}
+
+	value, err := queryPrometheus(context.TODO(), r.PrometheusHTTPClient, spec.Address, spec.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		obs.observe("result=empty")
+
+		r.Log.V(1).Info(
+			"Prometheus query resulted in an empty vector. Skipping PrometheusQuery",
+			"query", spec.Query,
+			"namespace", hra.Namespace,
+			"kind", st.kind,
+			"name", st.st,
+			"horizontal_runner_autoscaler", hra.Name,
+		)
+
+		return nil, nil
+	}
+
+	if math.IsNaN(*value) || math.IsInf(*value, 0) {
+		return nil, fmt.Errorf("prometheus query %q resulted in %v, which cannot be converted into desired replicas", spec.Query, *value)
+	}
+
+	obs.observe("result=%s", strconv.FormatFloat(*value, 'g', -1, 64))
+
+	desiredReplicas := int(math.Ceil(*value / targetValuePerRunner))
+	if desiredReplicas < 0 {
+		desiredReplicas = 0
+	}
+
+	r.Log.V(1).Info(
+		fmt.Sprintf("Suggested desired replicas of %d by PrometheusQuery", desiredReplicas),
+		"query", spec.Query,
+		"query_result", *value,
+		"target_value_per_runner", targetValuePerRunner,
+		"namespace", hra.Namespace,
+		"kind", st.kind,
+		"name", st.st,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	return &desiredReplicas, nil
+}
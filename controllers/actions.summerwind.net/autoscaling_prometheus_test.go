@@ -0,0 +1,180 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestSuggestReplicasByPrometheusQuery(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	const query = `sum(github_workflow_jobs_queued_total{runs_on="my-label"})`
+
+	testcases := []struct {
+		name   string
+		target string
+		status int
+		body   string
+		want   *int
+		value  string
+		err    string
+	}{
+		{
+			name:   "scalar",
+			target: "1",
+			status: http.StatusOK,
+			body:   `{"status":"success","data":{"resultType":"scalar","result":[1435781451.781,"3"]}}`,
+			want:   intPtr(3),
+			value:  "result=3",
+		},
+		{
+			name:   "single element vector rounded up",
+			target: "2",
+			status: http.StatusOK,
+			body:   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1435781451.781,"5"]}]}}`,
+			want:   intPtr(3),
+			value:  "result=5",
+		},
+		{
+			name:   "fractional target",
+			target: "0.5",
+			status: http.StatusOK,
+			body:   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1435781451.781,"1.2"]}]}}`,
+			want:   intPtr(3),
+			value:  "result=1.2",
+		},
+		{
+			name:   "negative value",
+			target: "1",
+			status: http.StatusOK,
+			body:   `{"status":"success","data":{"resultType":"scalar","result":[1435781451.781,"-2"]}}`,
+			want:   intPtr(0),
+			value:  "result=-2",
+		},
+		{
+			name:   "empty vector",
+			target: "1",
+			status: http.StatusOK,
+			body:   `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			want:   nil,
+			value:  "result=empty",
+		},
+		{
+			name:   "multiple elements",
+			target: "1",
+			status: http.StatusOK,
+			body:   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"a":"1"},"value":[1435781451.781,"1"]},{"metric":{"a":"2"},"value":[1435781451.781,"2"]}]}}`,
+			err:    fmt.Sprintf("prometheus query %q resulted in 2 elements, but it must result in at most one element. Consider aggregating it with e.g. sum()", query),
+		},
+		{
+			name:   "NaN",
+			target: "1",
+			status: http.StatusOK,
+			body:   `{"status":"success","data":{"resultType":"scalar","result":[1435781451.781,"NaN"]}}`,
+			err:    fmt.Sprintf("prometheus query %q resulted in NaN, which cannot be converted into desired replicas", query),
+		},
+		{
+			name:   "query error",
+			target: "1",
+			status: http.StatusBadRequest,
+			body:   `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			err:    "querying prometheus at SERVER/api/v1/query: bad_data: parse error",
+		},
+		{
+			name:   "invalid target",
+			target: "0",
+			err:    "validating autoscaling metrics: spec.autoscaling.metrics[].prometheus.targetValuePerRunner must be greater than 0",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path != "/api/v1/query" {
+					t.Errorf("unexpected path: %s", req.URL.Path)
+				}
+
+				if got := req.FormValue("query"); got != query {
+					t.Errorf("unexpected query: want %q, got %q", query, got)
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer server.Close()
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log: zap.New(func(o *zap.Options) {
+					o.Development = true
+				}),
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "testhra",
+				},
+			}
+
+			metric := v1alpha1.MetricSpec{
+				Type: v1alpha1.AutoscalingMetricTypePrometheusQuery,
+				Prometheus: &v1alpha1.PrometheusMetricSpec{
+					Address:              server.URL,
+					Query:                query,
+					TargetValuePerRunner: tc.target,
+				},
+			}
+
+			var obs metricObservation
+
+			got, err := h.suggestReplicasByPrometheusQuery(scaleTarget{kind: "RunnerDeployment", st: "testrd"}, hra, metric, &obs)
+			if tc.err != "" {
+				if err == nil {
+					t.Fatalf("expected error %q, got none", tc.err)
+				}
+
+				want := strings.ReplaceAll(tc.err, "SERVER", server.URL)
+				if err.Error() != want {
+					t.Fatalf("unexpected error: want %q, got %q", want, err.Error())
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if obs.value != tc.value {
+				t.Errorf("unexpected observed value: want %q, got %q", tc.value, obs.value)
+			}
+
+			if tc.want == nil {
+				if got != nil {
+					t.Fatalf("unexpected desired replicas: want nil, got %d", *got)
+				}
+
+				return
+			}
+
+			if got == nil {
+				t.Fatalf("unexpected desired replicas: want %d, got nil", *tc.want)
+			}
+
+			if *got != *tc.want {
+				t.Errorf("unexpected desired replicas: want %d, got %d", *tc.want, *got)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	arcgithub "github.com/actions/actions-runner-controller/github"
+	"github.com/actions/actions-runner-controller/pkg/actionsglob"
+	"github.com/google/go-github/v47/github"
+)
+
+const defaultRepositoryDiscoveryRefreshInterval = 10 * time.Minute
+
+// repositoryDiscoveryCache caches the discovered repositories per HRA for the refresh interval,
+// so that the repositories are not listed on every reconciliation.
+// The zero value is ready to use.
+type repositoryDiscoveryCache struct {
+	mu      sync.Mutex
+	entries map[string]repositoryDiscoveryCacheEntry
+}
+
+type repositoryDiscoveryCacheEntry struct {
+	repositories   []string
+	expirationTime time.Time
+}
+
+func (c *repositoryDiscoveryCache) get(key string, now time.Time) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || !now.Before(e.expirationTime) {
+		return nil, false
+	}
+
+	return e.repositories, true
+}
+
+func (c *repositoryDiscoveryCache) set(key string, repositories []string, now, expirationTime time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]repositoryDiscoveryCacheEntry{}
+	}
+
+	// Drop entries of deleted HRAs and of the HRAs whose scale targets have changed
+	for k, e := range c.entries {
+		if !now.Before(e.expirationTime) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = repositoryDiscoveryCacheEntry{repositories: repositories, expirationTime: expirationTime}
+}
+
+// discoverRepositories returns the names of the repositories in the organization that match the spec,
+// without the owner part, like `myrepo`.
+// The result is cached for the refresh interval.
+func (r *HorizontalRunnerAutoscalerReconciler) discoverRepositories(ctx context.Context, ghc *arcgithub.Client, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, spec v1alpha1.RepositoryDiscoverySpec) ([]string, error) {
+	source := spec.Source
+	if source == "" {
+		source = v1alpha1.RepositoryDiscoverySourceOrganization
+	}
+
+	key := strings.Join([]string{hra.Namespace, hra.Name, source, st.org, st.group}, "/")
+
+	if repos, ok := r.repositoryDiscovery.get(key, now); ok {
+		return filterRepositoryNames(repos, spec.RepositoryNamePatterns), nil
+	}
+
+	var (
+		repos []*github.Repository
+		err   error
+	)
+
+	switch source {
+	case v1alpha1.RepositoryDiscoverySourceOrganization:
+		repos, err = ghc.ListOrganizationRepositories(ctx, st.org)
+	case v1alpha1.RepositoryDiscoverySourceInstallation:
+		repos, err = ghc.ListInstallationRepositories(ctx)
+	default:
+		return nil, fmt.Errorf("validating autoscaling metrics: unsupported repositoryDiscovery.source %q", source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	visible, err := listRepositoriesVisibleToRunnerGroup(ctx, ghc, st.org, st.group)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for _, repo := range repos {
+		// The installation can have access to repositories in other organizations
+		if owner := repo.GetOwner().GetLogin(); owner != "" && !strings.EqualFold(owner, st.org) {
+			continue
+		}
+
+		if repo.GetArchived() || repo.GetDisabled() {
+			continue
+		}
+
+		if visible != nil && !visible(repo) {
+			continue
+		}
+
+		names = append(names, repo.GetName())
+	}
+
+	sort.Strings(names)
+
+	refreshInterval := defaultRepositoryDiscoveryRefreshInterval
+	if spec.RefreshInterval != nil && spec.RefreshInterval.Duration > 0 {
+		refreshInterval = spec.RefreshInterval.Duration
+	}
+
+	r.repositoryDiscovery.set(key, names, now, now.Add(refreshInterval))
+
+	r.Log.V(1).Info(
+		fmt.Sprintf("Discovered %d repositories", len(names)),
+		"source", source,
+		"organization", st.org,
+		"runner_group", st.group,
+		"refresh_interval", refreshInterval,
+		"namespace", hra.Namespace,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	return filterRepositoryNames(names, spec.RepositoryNamePatterns), nil
+}
+
+// isRepositoryNamePattern returns true when the entry of RepositoryNames is a glob pattern rather than a repository name.
+func isRepositoryNamePattern(name string) bool {
+	return strings.HasPrefix(name, "!") || strings.Contains(name, "*")
+}
+
+// expandRepositoryNames returns the names of the repositories to be used for calculating the metric.
+// The glob patterns in RepositoryNames, like `infra-*` and `**`, are expanded against the repositories visible
+// to the runners in the same way as the repository discovery, sharing its cache.
+// The discovered repositories are added, and the repositories that match any of the patterns prefixed with "!" are excluded.
+func (r *HorizontalRunnerAutoscalerReconciler) expandRepositoryNames(ctx context.Context, ghc *arcgithub.Client, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics *v1alpha1.MetricSpec) ([]string, error) {
+	var names, includes, excludes []string
+
+	for _, n := range metrics.RepositoryNames {
+		switch {
+		case n == "" || n == "!":
+			continue
+		case n[0] == '!':
+			excludes = append(excludes, n)
+		case isRepositoryNamePattern(n):
+			includes = append(includes, n)
+		default:
+			names = append(names, n)
+		}
+	}
+
+	if len(includes) > 0 {
+		// The patterns are evaluated against the same repositories as the repository discovery, if any
+		var spec v1alpha1.RepositoryDiscoverySpec
+		if d := metrics.RepositoryDiscovery; d != nil {
+			spec.Source = d.Source
+			spec.RefreshInterval = d.RefreshInterval
+		}
+
+		spec.RepositoryNamePatterns = includes
+
+		matched, err := r.discoverRepositories(ctx, ghc, now, st, hra, spec)
+		if err != nil {
+			return nil, fmt.Errorf("expanding repository name patterns: %w", err)
+		}
+
+		names = append(names, matched...)
+	}
+
+	if metrics.RepositoryDiscovery != nil {
+		discovered, err := r.discoverRepositories(ctx, ghc, now, st, hra, *metrics.RepositoryDiscovery)
+		if err != nil {
+			return nil, fmt.Errorf("discovering repositories: %w", err)
+		}
+
+		names = append(names, discovered...)
+	}
+
+	if len(excludes) > 0 {
+		names = filterRepositoryNames(names, excludes)
+	}
+
+	var expanded []string
+
+	seen := make(map[string]struct{}, len(names))
+
+	for _, n := range names {
+		if _, ok := seen[n]; ok {
+			continue
+		}
+
+		seen[n] = struct{}{}
+
+		expanded = append(expanded, n)
+	}
+
+	return expanded, nil
+}
+
+// listRepositoriesVisibleToRunnerGroup returns a function that tells if the repository can use the runner group.
+// It returns nil when every repository in the organization can use the runner group.
+func listRepositoriesVisibleToRunnerGroup(ctx context.Context, ghc *arcgithub.Client, org, group string) (func(*github.Repository) bool, error) {
+	if group == "" {
+		return nil, nil
+	}
+
+	groups, err := ghc.ListOrganizationRunnerGroups(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+
+	var runnerGroup *github.RunnerGroup
+
+	for _, g := range groups {
+		if g.GetName() == group {
+			runnerGroup = g
+			break
+		}
+	}
+
+	if runnerGroup == nil {
+		return nil, fmt.Errorf("runner group %q not found in organization %s", group, org)
+	}
+
+	switch runnerGroup.GetVisibility() {
+	case "private":
+		return func(repo *github.Repository) bool {
+			return repo.GetPrivate()
+		}, nil
+	case "selected":
+		accesses, err := ghc.ListRunnerGroupRepositoryAccesses(ctx, org, runnerGroup.GetID())
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make(map[int64]struct{}, len(accesses))
+		for _, a := range accesses {
+			ids[a.GetID()] = struct{}{}
+		}
+
+		return func(repo *github.Repository) bool {
+			_, ok := ids[repo.GetID()]
+			return ok
+		}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// filterRepositoryNames returns the repository names that match the GitHub Actions glob patterns.
+// A name is included when it matches any of the patterns without "!", or when there is no such pattern,
+// and it doesn't match any of the patterns with "!".
+func filterRepositoryNames(names []string, patterns []string) []string {
+	var includes, excludes []string
+
+	for _, p := range patterns {
+		if p == "" || p == "!" {
+			continue
+		}
+
+		if p[0] == '!' {
+			excludes = append(excludes, p)
+		} else {
+			includes = append(includes, p)
+		}
+	}
+
+	var filtered []string
+
+NAME:
+	for _, name := range names {
+		for _, p := range excludes {
+			// actionsglob.Match returns false when the name matches the pattern prefixed with "!"
+			if !actionsglob.Match(p, name) {
+				continue NAME
+			}
+		}
+
+		if len(includes) == 0 {
+			filtered = append(filtered, name)
+			continue
+		}
+
+		for _, p := range includes {
+			if actionsglob.Match(p, name) {
+				filtered = append(filtered, name)
+				continue NAME
+			}
+		}
+	}
+
+	return filtered
+}
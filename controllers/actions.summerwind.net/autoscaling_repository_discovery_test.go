@@ -0,0 +1,237 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestDiscoverRepositories(t *testing.T) {
+	const repos = `[
+{"id": 1, "name": "app", "owner": {"login": "test"}, "private": true},
+{"id": 2, "name": "app-legacy", "owner": {"login": "test"}, "private": false, "archived": true},
+{"id": 3, "name": "lib", "owner": {"login": "test"}, "private": false},
+{"id": 4, "name": "lib-disabled", "owner": {"login": "test"}, "private": true, "disabled": true},
+{"id": 5, "name": "sandbox", "owner": {"login": "test"}, "private": true}
+]`
+
+	testcases := []struct {
+		name     string
+		group    string
+		patterns []string
+		want     []string
+	}{
+		{
+			name: "all",
+			want: []string{"app", "lib", "sandbox"},
+		},
+		{
+			name:     "patterns",
+			patterns: []string{"*", "!sand*"},
+			want:     []string{"app", "lib"},
+		},
+		{
+			name:  "group visible to all repositories",
+			group: "all",
+			want:  []string{"app", "lib", "sandbox"},
+		},
+		{
+			name:  "group visible to private repositories",
+			group: "private",
+			want:  []string{"app", "sandbox"},
+		},
+		{
+			name:     "group visible to selected repositories",
+			group:    "selected",
+			patterns: []string{"app", "lib", ""},
+			want:     []string{"lib"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			var listed int32
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/orgs/test/repos", func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&listed, 1)
+				fmt.Fprint(w, repos)
+			})
+			mux.HandleFunc("/orgs/test/actions/runner-groups", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"total_count": 3, "runner_groups": [
+{"id": 1, "name": "all", "visibility": "all"},
+{"id": 2, "name": "private", "visibility": "private"},
+{"id": 3, "name": "selected", "visibility": "selected"}
+]}`)
+			})
+			mux.HandleFunc("/orgs/test/actions/runner-groups/3/repositories", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"total_count": 2, "repositories": [{"id": 3, "name": "lib"}, {"id": 5, "name": "sandbox"}]}`)
+			})
+
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			ghc := newGithubClient(server)
+
+			r := &HorizontalRunnerAutoscalerReconciler{
+				Log: zap.New(func(o *zap.Options) {
+					o.Development = true
+				}),
+			}
+
+			st := scaleTarget{org: "test", group: tc.group}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+			}
+
+			spec := v1alpha1.RepositoryDiscoverySpec{
+				RepositoryNamePatterns: tc.patterns,
+				RefreshInterval:        &metav1.Duration{Duration: time.Minute},
+			}
+
+			now := time.Now()
+
+			got, err := r.discoverRepositories(context.Background(), ghc, now, st, hra, spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Errorf("unexpected repositories: (-want, +got)\n%s", d)
+			}
+
+			// The result is cached until the refresh interval passes
+			for _, d := range []time.Duration{30 * time.Second, 2 * time.Minute} {
+				got, err = r.discoverRepositories(context.Background(), ghc, now.Add(d), st, hra, spec)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if d := cmp.Diff(tc.want, got); d != "" {
+					t.Errorf("unexpected repositories: (-want, +got)\n%s", d)
+				}
+			}
+
+			if n := atomic.LoadInt32(&listed); n != 2 {
+				t.Errorf("unexpected number of repository listings: want 2, got %d", n)
+			}
+		})
+	}
+}
+
+func TestExpandRepositoryNames(t *testing.T) {
+	const repos = `[
+{"id": 1, "name": "app", "owner": {"login": "test"}},
+{"id": 2, "name": "app-legacy", "owner": {"login": "test"}, "archived": true},
+{"id": 3, "name": "infra-core", "owner": {"login": "test"}},
+{"id": 4, "name": "infra-legacy", "owner": {"login": "test"}},
+{"id": 5, "name": "lib", "owner": {"login": "test"}}
+]`
+
+	testcases := []struct {
+		name      string
+		names     []string
+		discovery *v1alpha1.RepositoryDiscoverySpec
+		want      []string
+		listed    int32
+	}{
+		{
+			name:  "exact names",
+			names: []string{"lib", "app", "lib"},
+			want:  []string{"lib", "app"},
+		},
+		{
+			name:  "exact names and exclusions",
+			names: []string{"app", "infra-legacy", "!infra-legacy"},
+			want:  []string{"app"},
+		},
+		{
+			name:   "patterns",
+			names:  []string{"lib", "infra-*", "!infra-legacy", "app*"},
+			want:   []string{"lib", "app", "infra-core"},
+			listed: 1,
+		},
+		{
+			name:   "all",
+			names:  []string{"**", "!app"},
+			want:   []string{"infra-core", "infra-legacy", "lib"},
+			listed: 1,
+		},
+		{
+			// Leading wildcard patterns don't match the names without their literal parts
+			name:   "leading wildcards",
+			names:  []string{"*-legacy", "*-core", "!*-core"},
+			want:   []string{"infra-legacy"},
+			listed: 1,
+		},
+		{
+			name:      "patterns and repository discovery",
+			names:     []string{"infra-*", "!infra-legacy"},
+			discovery: &v1alpha1.RepositoryDiscoverySpec{RepositoryNamePatterns: []string{"infra-legacy", "lib"}},
+			want:      []string{"infra-core", "lib"},
+			// The repositories listed for the patterns are reused for the repository discovery
+			listed: 1,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			var listed int32
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/orgs/test/repos", func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&listed, 1)
+				fmt.Fprint(w, repos)
+			})
+
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			ghc := newGithubClient(server)
+
+			r := &HorizontalRunnerAutoscalerReconciler{
+				Log: zap.New(func(o *zap.Options) {
+					o.Development = true
+				}),
+			}
+
+			st := scaleTarget{org: "test"}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+			}
+
+			metrics := &v1alpha1.MetricSpec{
+				RepositoryNames:     tc.names,
+				RepositoryDiscovery: tc.discovery,
+			}
+
+			got, err := r.expandRepositoryNames(context.Background(), ghc, time.Now(), st, hra, metrics)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Errorf("unexpected repositories: (-want, +got)\n%s", d)
+			}
+
+			if n := atomic.LoadInt32(&listed); n != tc.listed {
+				t.Errorf("unexpected number of repository listings: want %d, got %d", tc.listed, n)
+			}
+		})
+	}
+}
@@ -3,9 +3,12 @@ package actionssummerwindnet
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/actions/actions-runner-controller/github"
@@ -13,6 +16,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -378,7 +382,7 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 
 			st := h.scaleTargetFromRD(context.Background(), rd)
 
-			got, err := h.computeReplicasWithCache(client, log, metav1Now.Time, st, hra, minReplicas)
+			got, err := h.computeReplicasWithCache(context.Background(), client, log, metav1Now.Time, st, hra, minReplicas)
 			if err != nil {
 				if tc.err == "" {
 					t.Fatalf("unexpected error: expected none, got %v", err)
@@ -388,8 +392,8 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 				return
 			}
 
-			if got != tc.want {
-				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, got)
+			if got.desiredReplicas != tc.want {
+				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, got.desiredReplicas)
 			}
 		})
 	}
@@ -544,7 +548,7 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 			workflowRuns:             `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"completed"}]}"`,
 			workflowRuns_queued:      `{"total_count": 0, "workflow_runs":[]}"`,
 			workflowRuns_in_progress: `{"total_count": 1, "workflow_runs":[{"status":"in_progress"}]}"`,
-			err:                      "validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames is required and must have one more more entries for organizational runner deployment",
+			err:                      "validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames or repositoryDiscovery is required for organizational runner deployment",
 		},
 
 		{
@@ -779,7 +783,7 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 
 			st := h.scaleTargetFromRD(context.Background(), rd)
 
-			got, err := h.computeReplicasWithCache(client, log, metav1Now.Time, st, hra, minReplicas)
+			got, err := h.computeReplicasWithCache(context.Background(), client, log, metav1Now.Time, st, hra, minReplicas)
 			if err != nil {
 				if tc.err == "" {
 					t.Fatalf("unexpected error: expected none, got %v", err)
@@ -789,8 +793,348 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 				return
 			}
 
-			if got != tc.want {
-				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, got)
+			if got.desiredReplicas != tc.want {
+				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, got.desiredReplicas)
+			}
+		})
+	}
+}
+
+func TestAggregateSuggestedReplicas(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	testcases := []struct {
+		policy      string
+		suggestions []*int
+		want        *int
+		wantCalls   int
+		err         string
+	}{
+		{
+			policy:      "",
+			suggestions: []*int{intPtr(0), nil, intPtr(3), intPtr(5)},
+			want:        intPtr(3),
+			wantCalls:   3,
+		},
+		{
+			policy:      v1alpha1.MetricsAggregationPolicyFirstNonNil,
+			suggestions: []*int{intPtr(0), nil},
+			want:        nil,
+			wantCalls:   2,
+		},
+		{
+			policy:      v1alpha1.MetricsAggregationPolicyMax,
+			suggestions: []*int{intPtr(2), nil, intPtr(7), intPtr(5)},
+			want:        intPtr(7),
+			wantCalls:   4,
+		},
+		{
+			policy:      v1alpha1.MetricsAggregationPolicyMin,
+			suggestions: []*int{intPtr(2), nil, intPtr(0), intPtr(5)},
+			want:        intPtr(0),
+			wantCalls:   4,
+		},
+		{
+			policy:      v1alpha1.MetricsAggregationPolicySum,
+			suggestions: []*int{intPtr(2), nil, intPtr(7), intPtr(5)},
+			want:        intPtr(14),
+			wantCalls:   4,
+		},
+		{
+			policy:      v1alpha1.MetricsAggregationPolicySum,
+			suggestions: []*int{nil, nil},
+			want:        nil,
+			wantCalls:   2,
+		},
+		{
+			policy:      "Avg",
+			suggestions: []*int{intPtr(1)},
+			err:         `validating autoscaling metrics: unsupported metrics aggregation policy "Avg"`,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			var calls int
+
+			var providers []metricProvider
+
+			for j := range tc.suggestions {
+				s := tc.suggestions[j]
+
+				providers = append(providers, metricProviderFunc(func() (*int, error) {
+					calls++
+					return s, nil
+				}))
+			}
+
+			got, err := aggregateSuggestedReplicas(tc.policy, providers)
+			if err != nil {
+				if tc.err == "" {
+					t.Fatalf("unexpected error: expected none, got %v", err)
+				} else if err.Error() != tc.err {
+					t.Fatalf("unexpected error: expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if calls != tc.wantCalls {
+				t.Errorf("unexpected number of metric computations: want %d, got %d", tc.wantCalls, calls)
+			}
+
+			if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+				t.Errorf("incorrect suggested replicas: want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSuggestReplicasByQueuedAndInProgressWorkflowJobs(t *testing.T) {
+	workflowRunsQueued := `{"total_count": 1, "workflow_runs":[{"id": 1, "status":"queued"}]}"`
+	workflowRunsInProgress := `{"total_count": 2, "workflow_runs":[{"id": 2, "status":"in_progress"}, {"id": 3, "status":"in_progress"}]}"`
+	workflowJobs := map[int]string{
+		1: `{"jobs": [{"status":"queued", "labels":["self-hosted", "custom"]}, {"status":"queued", "labels":["self-hosted", "custom"]}, {"status":"queued", "labels":["self-hosted", "linux", "x64", "custom"]}]}`,
+		2: `{"jobs": [{"status": "in_progress", "labels":["self-hosted", "custom"]}, {"status":"completed", "labels":["self-hosted", "custom"]}, {"status":"queued", "labels":["self-hosted", "other"]}]}`,
+		// Unlike TotalNumberOfQueuedAndInProgressWorkflowRuns, a workflow run without jobs is not counted
+		3: `{"jobs": []}`,
+	}
+
+	testcases := []struct {
+		description    string
+		implicitLabels []string
+		want           int
+	}{
+		{
+			description: "default implicit labels",
+			want:        3,
+		},
+		{
+			description:    "os and arch labels",
+			implicitLabels: []string{"self-hosted", "linux", "x64"},
+			want:           4,
+		},
+		{
+			description:    "os label without arch label",
+			implicitLabels: []string{"linux"},
+			want:           3,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.description, func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200, "", workflowRunsQueued, workflowRunsInProgress),
+				fake.WithListWorkflowJobsResponse(200, workflowJobs),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log: zap.New(func(o *zap.Options) {
+					o.Development = true
+				}),
+			}
+
+			st := scaleTarget{
+				st:     "testrd",
+				kind:   "runnerdeployment",
+				repo:   "test/valid",
+				labels: newRunnerLabelMatcher(v1alpha1.RunnerConfig{Labels: []string{"custom"}}, nil),
+			}
+
+			metric := v1alpha1.MetricSpec{
+				Type:                 v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowJobs,
+				ImplicitRunnerLabels: tc.implicitLabels,
+			}
+
+			got, err := h.suggestReplicasByQueuedAndInProgressWorkflowJobs(context.Background(), client, time.Now(), st, v1alpha1.HorizontalRunnerAutoscaler{}, metric, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got == nil || *got != tc.want {
+				t.Errorf("unexpected suggested replicas: want %d, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSuggestReplicasByPercentageRunnersBusy_RunnerPool(t *testing.T) {
+	const (
+		ownRunner   = `{"id": %d, "name": "own-%d", "status": "online", "busy": false, "labels": [{"name": "self-hosted"}, {"name": "linux"}, {"name": "custom"}]}`
+		otherRunner = `{"id": %d, "name": "other-%d", "status": "%s", "busy": true, "labels": [{"name": "self-hosted"}, {"name": "Linux"}, {"name": "GPU"}]}`
+	)
+
+	groupRunners := fmt.Sprintf(`{"total_count": 6, "runners": [%s, %s, %s, %s, %s, %s]}`,
+		fmt.Sprintf(ownRunner, 1, 1),
+		fmt.Sprintf(ownRunner, 2, 2),
+		fmt.Sprintf(otherRunner, 3, 1, "online"),
+		fmt.Sprintf(otherRunner, 4, 2, "online"),
+		fmt.Sprintf(otherRunner, 5, 3, "online"),
+		fmt.Sprintf(otherRunner, 6, 4, "offline"),
+	)
+
+	// Every runner of the organization, including cpu-1 that is not in the gpu group
+	allRunners := fmt.Sprintf(`{"total_count": 7, "runners": [%s, %s, %s, %s, %s, %s, %s]}`,
+		fmt.Sprintf(ownRunner, 1, 1),
+		fmt.Sprintf(ownRunner, 2, 2),
+		fmt.Sprintf(otherRunner, 3, 1, "online"),
+		fmt.Sprintf(otherRunner, 4, 2, "online"),
+		fmt.Sprintf(otherRunner, 5, 3, "online"),
+		fmt.Sprintf(otherRunner, 6, 4, "offline"),
+		`{"id": 7, "name": "cpu-1", "status": "online", "busy": false, "labels": [{"name": "self-hosted"}, {"name": "linux"}]}`,
+	)
+
+	const runnerGroups = `{"total_count": 2, "runner_groups": [{"id": 1, "name": "Default"}, {"id": 2, "name": "gpu"}]}`
+
+	testcases := []struct {
+		description string
+		enterprise  string
+		org         string
+		repo        string
+		pool        *v1alpha1.RunnerPoolSpec
+		want        int
+		wantValue   string
+		err         string
+	}{
+		{
+			description: "own runners",
+			org:         "test",
+			want:        1,
+			wantValue:   "busy=0 replicas=2",
+		},
+		{
+			description: "organization runner group",
+			org:         "test",
+			pool:        &v1alpha1.RunnerPoolSpec{Group: "gpu"},
+			want:        2,
+			wantValue:   "busy=3 pool=5 replicas=2",
+		},
+		{
+			description: "enterprise runner group",
+			enterprise:  "ent",
+			pool:        &v1alpha1.RunnerPoolSpec{Group: "gpu"},
+			want:        2,
+			wantValue:   "busy=3 pool=5 replicas=2",
+		},
+		{
+			description: "labels",
+			org:         "test",
+			pool:        &v1alpha1.RunnerPoolSpec{Labels: []string{"linux", "gpu"}},
+			want:        3,
+			wantValue:   "busy=3 pool=3 replicas=2",
+		},
+		{
+			description: "runner group and labels",
+			org:         "test",
+			pool:        &v1alpha1.RunnerPoolSpec{Group: "gpu", Labels: []string{"custom"}},
+			want:        1,
+			wantValue:   "busy=0 pool=2 replicas=2",
+		},
+		{
+			description: "empty pool",
+			org:         "test",
+			pool:        &v1alpha1.RunnerPoolSpec{Labels: []string{"arm64"}},
+			want:        1,
+			wantValue:   "busy=0 pool=0 replicas=2",
+		},
+		{
+			description: "missing runner group",
+			org:         "test",
+			pool:        &v1alpha1.RunnerPoolSpec{Group: "missing"},
+			err:         `runner group "missing" not found`,
+		},
+		{
+			description: "runner group of repository runners",
+			repo:        "test/valid",
+			pool:        &v1alpha1.RunnerPoolSpec{Group: "gpu"},
+			err:         "validating autoscaling metrics: spec.autoscaling.metrics[].runnerPool.group cannot be specified for repository runners",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.description, func(t *testing.T) {
+			mux := http.NewServeMux()
+			for _, prefix := range []string{"/orgs/test", "/enterprises/ent"} {
+				mux.HandleFunc(prefix+"/actions/runners", func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, allRunners)
+				})
+				mux.HandleFunc(prefix+"/actions/runner-groups", func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, runnerGroups)
+				})
+				mux.HandleFunc(prefix+"/actions/runner-groups/2/runners", func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, groupRunners)
+				})
+			}
+			mux.HandleFunc("/repos/test/valid/actions/runners", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, allRunners)
+			})
+
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client: clientfake.NewClientBuilder().WithScheme(sc).Build(),
+				Log: zap.New(func(o *zap.Options) {
+					o.Development = true
+				}),
+			}
+
+			replicas := 2
+
+			st := scaleTarget{
+				st:         "testrd",
+				kind:       "runnerdeployment",
+				enterprise: tc.enterprise,
+				org:        tc.org,
+				repo:       tc.repo,
+				replicas:   &replicas,
+				getRunnerMap: func() (map[string]struct{}, error) {
+					return map[string]struct{}{"own-1": {}, "own-2": {}}, nil
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "testhra"},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "testrd"},
+				},
+			}
+
+			metric := v1alpha1.MetricSpec{
+				Type:       v1alpha1.AutoscalingMetricTypePercentageRunnersBusy,
+				RunnerPool: tc.pool,
+			}
+
+			var obs metricObservation
+
+			got, err := h.suggestReplicasByPercentageRunnersBusy(context.Background(), newGithubClient(server), st, hra, metric, &obs)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("unexpected error: want %q, got %v", tc.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got == nil || *got != tc.want {
+				t.Errorf("unexpected suggested replicas: want %d, got %v", tc.want, got)
+			}
+
+			if obs.value != tc.wantValue {
+				t.Errorf("unexpected observed value: want %q, got %q", tc.wantValue, obs.value)
 			}
 		})
 	}
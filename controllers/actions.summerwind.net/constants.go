@@ -39,6 +39,22 @@ const (
 
 	AnnotationKeyRunnerID = annotationKeyPrefix + "id"
 
+	// AnnotationKeyExternalScaler is the annotation that is added onto the HRA to let the KEDA external scaler serve its desired replicas.
+	// The HRA controller doesn't scale the HRA's scale target when it's set to AnnotationValueExternalScalerKEDA, so that it doesn't fight with KEDA.
+	AnnotationKeyExternalScaler = annotationKeyPrefix + "external-scaler"
+
+	AnnotationValueExternalScalerKEDA = "keda"
+
+	// AnnotationKeyWorkflowJobID and AnnotationKeyWorkflowJobRepository are the annotations that are added onto the runner and its pod
+	// while the runner is running a workflow job, so that you can tell which pod is running which job.
+	// They're added and removed by the webhook-based autoscaler on workflow_job events.
+	AnnotationKeyWorkflowJobID         = annotationKeyPrefix + "workflow-job-id"
+	AnnotationKeyWorkflowJobRepository = annotationKeyPrefix + "workflow-job-repository"
+
+	// AnnotationKeyWorkflowJobCompletionTimestamp is the annotation that is added onto the runner and its pod
+	// when the runner completes a workflow job, which is used by the LongestIdle scale down policy.
+	AnnotationKeyWorkflowJobCompletionTimestamp = annotationKeyPrefix + "workflow-job-completion-timestamp"
+
 	// This can be any value but a larger value can make an unregistration timeout longer than configured in practice.
 	DefaultUnregistrationRetryDelay = time.Minute
 
@@ -2,14 +2,19 @@ package actionssummerwindnet
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -19,6 +24,16 @@ type batchScaler struct {
 	Log      logr.Logger
 	interval time.Duration
 
+	// routingPolicy is the policy to route each scale target to one of its candidates
+	routingPolicy string
+
+	// clock returns the current time. It's replaced by the autoscale simulator to replay webhook events on a virtual clock.
+	clock func() time.Time
+
+	// reader reads the HRA bypassing the cache when the status patch conflicted, as the cached HRA might be stale.
+	// Client is used when omitted.
+	reader client.Reader
+
 	queue       chan *ScaleTarget
 	workerStart sync.Once
 }
@@ -35,11 +50,19 @@ func newBatchScaler(ctx context.Context, client client.Client, log logr.Logger)
 type batchScaleOperation struct {
 	namespacedName types.NamespacedName
 	scaleOps       []scaleOperation
+
+	// appliedDeliveryIDs is the IDs of the webhook deliveries in the scale operation journal that are applied by this batch,
+	// including the ones that have already been applied to the HRA but are yet to be removed from the journal.
+	// They are recorded in the HRA so that the deliveries are never applied twice.
+	appliedDeliveryIDs []string
 }
 
 type scaleOperation struct {
-	trigger v1alpha1.ScaleUpTrigger
-	log     logr.Logger
+	trigger               v1alpha1.ScaleUpTrigger
+	triggerIndex          int
+	workflowJob           workflowJobRef
+	workflowJobInProgress bool
+	log                   logr.Logger
 }
 
 // Add the scale target to the unbounded queue, blocking until the target is successfully added to the queue.
@@ -72,6 +95,7 @@ func (s *batchScaler) Add(st *ScaleTarget) {
 				log.V(2).Info("Batch worker is dequeueing operations")
 
 				batches := map[types.NamespacedName]batchScaleOperation{}
+				routed := map[types.NamespacedName]*routedReservations{}
 				after := time.After(s.interval)
 				var ops uint
 
@@ -81,21 +105,7 @@ func (s *batchScaler) Add(st *ScaleTarget) {
 					case <-after:
 						break batch
 					case st := <-s.queue:
-						nsName := types.NamespacedName{
-							Namespace: st.HorizontalRunnerAutoscaler.Namespace,
-							Name:      st.HorizontalRunnerAutoscaler.Name,
-						}
-						b, ok := batches[nsName]
-						if !ok {
-							b = batchScaleOperation{
-								namespacedName: nsName,
-							}
-						}
-						b.scaleOps = append(b.scaleOps, scaleOperation{
-							log:     *st.log,
-							trigger: st.ScaleUpTrigger,
-						})
-						batches[nsName] = b
+						s.addToBatches(st, batches, routed)
 						ops++
 					}
 				}
@@ -135,59 +145,212 @@ func (s *batchScaler) Add(st *ScaleTarget) {
 	s.queue <- st
 }
 
+func (s *batchScaler) now() time.Time {
+	if s.clock != nil {
+		return s.clock()
+	}
+
+	return time.Now()
+}
+
+// addToBatches routes the scale target to one of its candidates if any, and adds the scale operation to the batch of the HRA.
+// It returns the HRA the scale target is routed to.
+func (s *batchScaler) addToBatches(st *ScaleTarget, batches map[types.NamespacedName]batchScaleOperation, routed map[types.NamespacedName]*routedReservations) types.NamespacedName {
+	if len(st.candidates) > 1 {
+		routedTo := routeScaleTarget(s.routingPolicy, s.now(), st, routed)
+
+		st.log.V(1).Info("Routed scale target", "policy", s.routingPolicy, "candidates", len(st.candidates), "hra", routedTo.HorizontalRunnerAutoscaler.Name, "amount", routedTo.Amount)
+
+		st = routedTo
+	}
+
+	nsName := types.NamespacedName{
+		Namespace: st.HorizontalRunnerAutoscaler.Namespace,
+		Name:      st.HorizontalRunnerAutoscaler.Name,
+	}
+	b, ok := batches[nsName]
+	if !ok {
+		b = batchScaleOperation{
+			namespacedName: nsName,
+		}
+	}
+	b.scaleOps = append(b.scaleOps, scaleOperation{
+		log:                   *st.log,
+		trigger:               st.ScaleUpTrigger,
+		triggerIndex:          st.scaleUpTriggerIndex,
+		workflowJob:           st.workflowJob,
+		workflowJobInProgress: st.workflowJobInProgress,
+	})
+	batches[nsName] = b
+
+	return nsName
+}
+
 func (s *batchScaler) batchScale(ctx context.Context, batch batchScaleOperation) error {
-	var hra v1alpha1.HorizontalRunnerAutoscaler
+	var (
+		hra v1alpha1.HorizontalRunnerAutoscaler
 
-	if err := s.Client.Get(ctx, batch.namespacedName, &hra); err != nil {
+		// The first attempt reads the HRA from the cache, and the retries after conflicts read the latest one
+		reader client.Reader = s.Client
+	)
+
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		var latest v1alpha1.HorizontalRunnerAutoscaler
+
+		if err := reader.Get(ctx, batch.namespacedName, &latest); err != nil {
+			return err
+		}
+
+		reader = s.uncachedReader()
+		hra = latest
+
+		return s.patchCapacityReservations(ctx, hra, batch)
+	})
+	if err != nil {
 		return err
 	}
 
+	if len(hra.Spec.CapacityReservations) > 0 {
+		if err := s.pruneSpecCapacityReservations(ctx, hra); err != nil {
+			s.Log.Error(err, "Failed to remove capacity reservations moved to the status from the spec", "hra", batch.namespacedName)
+		}
+	}
+
+	for _, scale := range batch.scaleOps {
+		job := scale.workflowJob
+
+		if job.runnerName == "" {
+			continue
+		}
+
+		// The job annotations are replaced with the completion timestamp once the job completes
+		var annotations map[string]interface{}
+
+		if scale.workflowJobInProgress {
+			annotations = map[string]interface{}{
+				AnnotationKeyWorkflowJobID:         strconv.FormatInt(job.id, 10),
+				AnnotationKeyWorkflowJobRepository: job.repository,
+			}
+		} else if scale.trigger.Amount < 0 {
+			annotations = map[string]interface{}{
+				AnnotationKeyWorkflowJobID:                  nil,
+				AnnotationKeyWorkflowJobRepository:          nil,
+				AnnotationKeyWorkflowJobCompletionTimestamp: s.now().Format(time.RFC3339),
+			}
+		} else {
+			continue
+		}
+
+		if err := s.annotateRunner(ctx, hra.Namespace, job.runnerName, annotations); err != nil {
+			scale.log.Error(err, "Failed to annotate runner with workflow job", "runnerName", job.runnerName)
+		}
+	}
+
+	return nil
+}
+
+// pruneSpecCapacityReservations removes the capacity reservations left in the spec by older versions of the webhook-based autoscaler,
+// which have already been moved to the status.
+// A failure is harmless, as the reservations in both the spec and the status are counted only once and removed by the next batch.
+func (s *batchScaler) pruneSpecCapacityReservations(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) error {
+	copy := hra.DeepCopy()
+	copy.Spec.CapacityReservations = nil
+
+	return s.Client.Patch(ctx, copy, client.MergeFrom(&hra))
+}
+
+func (s *batchScaler) uncachedReader() client.Reader {
+	if s.reader != nil {
+		return s.reader
+	}
+
+	return s.Client
+}
+
+// patchCapacityReservations applies the scale operations of the batch to the capacity reservations of the HRA.
+// The status is patched with an optimistic lock, so that the reservations synced or garbage-collected by the HRA controller,
+// or added by the previous batch, aren't overwritten by a batch applied to a stale HRA.
+func (s *batchScaler) patchCapacityReservations(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, batch batchScaleOperation) error {
 	copy := hra.DeepCopy()
 
-	copy.Spec.CapacityReservations = getValidCapacityReservations(copy)
+	// The reservations left in the spec by older versions are moved to the status, and removed from the spec once the status is patched
+	copy.Status.CapacityReservations = filterValidCapacityReservations(getCapacityReservations(copy), s.now())
 
-	var added, completed int
+	var added, completed, assigned int
 
 	for _, scale := range batch.scaleOps {
+		if scale.workflowJobInProgress {
+			if i := findCapacityReservationForWorkflowJob(copy.Status.CapacityReservations, scale.workflowJob); i >= 0 {
+				scale.log.V(2).Info("Assigning runner to capacity reservation", "runnerName", scale.workflowJob.runnerName)
+
+				copy.Status.CapacityReservations[i].RunnerName = scale.workflowJob.runnerName
+
+				assigned++
+			}
+
+			continue
+		}
+
 		amount := 1
 
 		if scale.trigger.Amount != 0 {
 			amount = scale.trigger.Amount
 		}
 
-		scale.log.V(2).Info("Adding capacity reservation", "amount", amount)
+		scale.log.V(2).Info("Adding capacity reservation", "amount", amount, "scaleUpTriggerIndex", scale.triggerIndex)
 
 		if amount > 0 {
-			now := time.Now()
-			copy.Spec.CapacityReservations = append(copy.Spec.CapacityReservations, v1alpha1.CapacityReservation{
-				EffectiveTime:  metav1.Time{Time: now},
-				ExpirationTime: metav1.Time{Time: now.Add(scale.trigger.Duration.Duration)},
-				Replicas:       amount,
+			now := s.now()
+			triggerIndex := scale.triggerIndex
+			copy.Status.CapacityReservations = append(copy.Status.CapacityReservations, v1alpha1.CapacityReservation{
+				EffectiveTime:       metav1.Time{Time: now},
+				ExpirationTime:      metav1.Time{Time: now.Add(scale.trigger.Duration.Duration)},
+				Replicas:            amount,
+				ScaleUpTriggerIndex: &triggerIndex,
+				WorkflowJobID:       scale.workflowJob.id,
+				Repository:          scale.workflowJob.repository,
 			})
 
 			added += amount
 		} else if amount < 0 {
-			var reservations []v1alpha1.CapacityReservation
+			i := findCapacityReservationForWorkflowJob(copy.Status.CapacityReservations, scale.workflowJob)
+
+			if i < 0 {
+				for j, r := range copy.Status.CapacityReservations {
+					// A reservation for a workflow job is erased only by the completion of the job,
+					// so that a completion whose reservation has already expired doesn't erase the one for another job.
+					if r.WorkflowJobID != 0 {
+						continue
+					}
 
-			var found bool
+					// Reservations created before ScaleUpTriggerIndex was introduced can be erased by any trigger
+					sameTrigger := r.ScaleUpTriggerIndex == nil || *r.ScaleUpTriggerIndex == scale.triggerIndex
 
-			for _, r := range copy.Spec.CapacityReservations {
-				if !found && r.Replicas+amount == 0 {
-					found = true
-				} else {
-					reservations = append(reservations, r)
+					if r.Replicas+amount == 0 && sameTrigger {
+						i = j
+						break
+					}
 				}
 			}
 
-			copy.Spec.CapacityReservations = reservations
+			if i >= 0 {
+				reservations := append([]v1alpha1.CapacityReservation{}, copy.Status.CapacityReservations[:i]...)
+				reservations = append(reservations, copy.Status.CapacityReservations[i+1:]...)
+
+				copy.Status.CapacityReservations = reservations
+			}
 
 			completed += amount
 		}
 	}
 
-	before := len(hra.Spec.CapacityReservations)
-	expired := before - len(copy.Spec.CapacityReservations)
-	after := len(copy.Spec.CapacityReservations)
+	if len(batch.appliedDeliveryIDs) > 0 {
+		copy.Status.AppliedWebhookDeliveries = batch.appliedDeliveryIDs
+	}
+
+	before := len(hra.Status.CapacityReservations)
+	expired := before - len(copy.Status.CapacityReservations)
+	after := len(copy.Status.CapacityReservations)
 
 	s.Log.V(1).Info(
 		fmt.Sprintf("Patching hra %s for capacityReservations update", hra.Name),
@@ -195,11 +358,57 @@ func (s *batchScaler) batchScale(ctx context.Context, batch batchScaleOperation)
 		"expired", expired,
 		"added", added,
 		"completed", completed,
+		"assigned", assigned,
 		"after", after,
 	)
 
-	if err := s.Client.Patch(ctx, copy, client.MergeFrom(&hra)); err != nil {
-		return fmt.Errorf("patching horizontalrunnerautoscaler to add capacity reservation: %w", err)
+	// The reservations are recorded in the status so that they neither bump the generation of the HRA
+	// nor conflict with tools that manage the spec, like GitOps ones.
+	if err := s.Client.Status().Patch(ctx, copy, client.MergeFromWithOptions(&hra, client.MergeFromWithOptimisticLock{})); err != nil {
+		return fmt.Errorf("patching horizontalrunnerautoscaler status to add capacity reservation: %w", err)
+	}
+
+	return nil
+}
+
+// findCapacityReservationForWorkflowJob returns the index of the reservation made for the workflow job, or -1 if there's none.
+func findCapacityReservationForWorkflowJob(reservations []v1alpha1.CapacityReservation, job workflowJobRef) int {
+	if job.id == 0 {
+		return -1
+	}
+
+	for i, r := range reservations {
+		if r.WorkflowJobID == job.id && r.Repository == job.repository {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// annotateRunner patches the annotations of the runner pod and the Runner of the same name, if any.
+// A nil value removes the annotation.
+// The objects are patched without being read, so that the webhook-based autoscaler doesn't need to watch and cache all the pods.
+func (s *batchScaler) annotateRunner(ctx context.Context, namespace, name string, annotations map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	objs := []client.Object{
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}},
+		// Runners managed by RunnerDeployments have the same names as their pods
+		&v1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}},
+	}
+
+	for _, obj := range objs {
+		if err := s.Client.Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch)); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
 	}
 
 	return nil
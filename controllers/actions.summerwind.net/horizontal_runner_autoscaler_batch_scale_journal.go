@@ -0,0 +1,490 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// errScaleOperationJournalFull is returned when the journal already has as many entries as the queue limit.
+var errScaleOperationJournalFull = errors.New("scale operation journal is full")
+
+// validConfigMapKey matches the keys allowed in the data of a ConfigMap.
+var validConfigMapKey = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// scaleOperationJournalShards is the number of ConfigMaps the journal is sharded into by the delivery IDs,
+// so that the replicas of the webhook server adding entries on a burst of webhook events rarely conflict with each other.
+const scaleOperationJournalShards = 8
+
+// scaleOperationJournalBackoff is the backoff of the retries of the conflicting updates of a shard of the journal.
+// It's wider than retry.DefaultRetry, as every replica updates the shards on every webhook delivery.
+var scaleOperationJournalBackoff = wait.Backoff{
+	Steps:    10,
+	Duration: 10 * time.Millisecond,
+	Factor:   1.5,
+	Jitter:   1.0,
+}
+
+// scaleOperationJournal is the persistent queue of the scale operations of the webhook-based autoscaler, backed by ConfigMaps.
+//
+// Every replica of the webhook server adds an entry per webhook delivery before acknowledging the delivery,
+// and only the leader applies the entries and removes them once applied, so that a scale operation is neither lost
+// on a restart of the webhook server nor applied by two replicas.
+// The entries are keyed by the delivery IDs, so that a redelivery of the same webhook event is added only once.
+type scaleOperationJournal struct {
+	// reader reads the ConfigMaps and the HRAs bypassing the cache, as the ConfigMaps are updated by all the replicas
+	reader client.Reader
+	client client.Client
+
+	namespace string
+	name      string
+
+	// shards is the number of ConfigMaps named after name suffixed by the shard index. A single ConfigMap named name is used when omitted.
+	shards int
+
+	// limit is the maximum number of entries, which bounds the size of the ConfigMaps.
+	// It's divided among the shards, so that the journal can be full a bit before it has as many entries when they're unevenly sharded.
+	limit int
+}
+
+// scaleOperationJournalEntry is the scale target of a webhook delivery persisted in the journal.
+type scaleOperationJournalEntry struct {
+	DeliveryID string    `json:"deliveryID"`
+	ReceivedAt time.Time `json:"receivedAt"`
+
+	// Target is the scale target the operation is applied to, unless it's routed to another candidate
+	Target scaleOperationJournalTarget `json:"target"`
+
+	// Candidates is all the scale targets the operation can be routed to, including Target, when there are two or more
+	Candidates []scaleOperationJournalTarget `json:"candidates,omitempty"`
+
+	WorkflowJobID         int64  `json:"workflowJobID,omitempty"`
+	Repository            string `json:"repository,omitempty"`
+	RunnerName            string `json:"runnerName,omitempty"`
+	WorkflowJobInProgress bool   `json:"workflowJobInProgress,omitempty"`
+}
+
+type scaleOperationJournalTarget struct {
+	Namespace    string                  `json:"namespace"`
+	Name         string                  `json:"name"`
+	Trigger      v1alpha1.ScaleUpTrigger `json:"trigger"`
+	TriggerIndex int                     `json:"triggerIndex"`
+}
+
+func newScaleOperationJournalTarget(st ScaleTarget) scaleOperationJournalTarget {
+	return scaleOperationJournalTarget{
+		Namespace:    st.HorizontalRunnerAutoscaler.Namespace,
+		Name:         st.HorizontalRunnerAutoscaler.Name,
+		Trigger:      st.ScaleUpTrigger,
+		TriggerIndex: st.scaleUpTriggerIndex,
+	}
+}
+
+func newScaleOperationJournalEntry(deliveryID string, receivedAt time.Time, st *ScaleTarget) scaleOperationJournalEntry {
+	e := scaleOperationJournalEntry{
+		DeliveryID:            deliveryID,
+		ReceivedAt:            receivedAt,
+		Target:                newScaleOperationJournalTarget(*st),
+		WorkflowJobID:         st.workflowJob.id,
+		Repository:            st.workflowJob.repository,
+		RunnerName:            st.workflowJob.runnerName,
+		WorkflowJobInProgress: st.workflowJobInProgress,
+	}
+
+	for _, c := range st.candidates {
+		e.Candidates = append(e.Candidates, newScaleOperationJournalTarget(c))
+	}
+
+	return e
+}
+
+// webhookDeliveryID returns the ID of the webhook delivery, or the digest of the payload when the delivery has no ID,
+// so that a redelivery of the same payload is identified as the same delivery.
+func webhookDeliveryID(header string, payload []byte) string {
+	if header != "" && validConfigMapKey.MatchString(header) {
+		return header
+	}
+
+	sum := sha256.Sum256(payload)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (j *scaleOperationJournal) shardCount() int {
+	if j.shards < 1 {
+		return 1
+	}
+
+	return j.shards
+}
+
+func (j *scaleOperationJournal) shardName(i int) string {
+	if j.shardCount() == 1 {
+		return j.name
+	}
+
+	return fmt.Sprintf("%s-%d", j.name, i)
+}
+
+// shardOf returns the name of the ConfigMap the entry of the delivery is stored in.
+func (j *scaleOperationJournal) shardOf(deliveryID string) string {
+	h := fnv.New32a()
+	h.Write([]byte(deliveryID))
+
+	return j.shardName(int(h.Sum32() % uint32(j.shardCount())))
+}
+
+// add adds the entry to the journal, unless the journal already has an entry for the same delivery.
+func (j *scaleOperationJournal) add(ctx context.Context, e scaleOperationJournalEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	name := j.shardOf(e.DeliveryID)
+	limit := (j.limit + j.shardCount() - 1) / j.shardCount()
+
+	return retry.RetryOnConflict(scaleOperationJournalBackoff, func() error {
+		var cm corev1.ConfigMap
+
+		if err := j.reader.Get(ctx, types.NamespacedName{Namespace: j.namespace, Name: name}, &cm); err != nil {
+			if !kerrors.IsNotFound(err) {
+				return err
+			}
+
+			cm = corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: j.namespace,
+					Name:      name,
+				},
+				Data: map[string]string{e.DeliveryID: string(data)},
+			}
+
+			if err := j.client.Create(ctx, &cm); err != nil {
+				if kerrors.IsAlreadyExists(err) {
+					// Created by another replica in the meantime. Retry as a conflict
+					return kerrors.NewConflict(corev1.Resource("configmaps"), name, err)
+				}
+
+				return err
+			}
+
+			return nil
+		}
+
+		if _, ok := cm.Data[e.DeliveryID]; ok {
+			return nil
+		}
+
+		if len(cm.Data) >= limit {
+			return errScaleOperationJournalFull
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+
+		cm.Data[e.DeliveryID] = string(data)
+
+		return j.client.Update(ctx, &cm)
+	})
+}
+
+// list returns the entries in the journal in the order they were received.
+// Entries that can't be decoded are logged and skipped, and removed along with the applied entries.
+func (j *scaleOperationJournal) list(ctx context.Context, log logr.Logger) ([]scaleOperationJournalEntry, []string, error) {
+	var (
+		entries []scaleOperationJournalEntry
+		invalid []string
+	)
+
+	for i := 0; i < j.shardCount(); i++ {
+		var cm corev1.ConfigMap
+
+		if err := j.reader.Get(ctx, types.NamespacedName{Namespace: j.namespace, Name: j.shardName(i)}, &cm); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+
+			return nil, nil, err
+		}
+
+		for k, v := range cm.Data {
+			var e scaleOperationJournalEntry
+
+			if err := json.Unmarshal([]byte(v), &e); err != nil {
+				log.Error(err, "Skipping invalid scale operation journal entry", "configmap", cm.Name, "key", k)
+
+				invalid = append(invalid, k)
+
+				continue
+			}
+
+			e.DeliveryID = k
+
+			entries = append(entries, e)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, k int) bool {
+		if !entries[i].ReceivedAt.Equal(entries[k].ReceivedAt) {
+			return entries[i].ReceivedAt.Before(entries[k].ReceivedAt)
+		}
+
+		return entries[i].DeliveryID < entries[k].DeliveryID
+	})
+
+	return entries, invalid, nil
+}
+
+// remove removes the entries of the deliveries from the journal.
+func (j *scaleOperationJournal) remove(ctx context.Context, deliveryIDs []string) error {
+	shards := map[string][]string{}
+
+	for _, id := range deliveryIDs {
+		name := j.shardOf(id)
+		shards[name] = append(shards[name], id)
+	}
+
+	for name, ids := range shards {
+		if err := j.removeFromShard(ctx, name, ids); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *scaleOperationJournal) removeFromShard(ctx context.Context, name string, deliveryIDs []string) error {
+	return retry.RetryOnConflict(scaleOperationJournalBackoff, func() error {
+		var cm corev1.ConfigMap
+
+		if err := j.reader.Get(ctx, types.NamespacedName{Namespace: j.namespace, Name: name}, &cm); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		var changed bool
+
+		for _, id := range deliveryIDs {
+			if _, ok := cm.Data[id]; ok {
+				delete(cm.Data, id)
+				changed = true
+			}
+		}
+
+		if !changed {
+			return nil
+		}
+
+		return j.client.Update(ctx, &cm)
+	})
+}
+
+// scaleOperationJournalWorker applies the scale operations in the journal every interval, in batches per HRA as the batch scaler does.
+// It runs only on the leader of the webhook servers, so that the operations are applied by one replica at a time.
+//
+// An entry is removed from the journal only after it is applied, so that it is applied at least once even though the leader
+// crashes or loses the leadership in the middle. The IDs of the deliveries applied by the latest batch are recorded in the HRA
+// within the same update, so that the entries replayed by the next leader are skipped instead of being applied twice.
+type scaleOperationJournalWorker struct {
+	journal     *scaleOperationJournal
+	batchScaler *batchScaler
+	log         logr.Logger
+}
+
+// Start applies the scale operations in the journal every interval until the context is canceled. It implements manager.Runnable.
+func (w *scaleOperationJournalWorker) Start(ctx context.Context) error {
+	w.log.Info("Starting scale operation journal worker", "configmap", types.NamespacedName{Namespace: w.journal.namespace, Name: w.journal.name}, "shards", w.journal.shardCount())
+	defer w.log.Info("Stopped scale operation journal worker")
+
+	ticker := time.NewTicker(w.batchScaler.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := w.flush(ctx); err != nil {
+			w.log.Error(err, "Failed to apply scale operations in the journal")
+		}
+	}
+}
+
+// NeedLeaderElection returns true so that only the leader applies the scale operations.
+func (w *scaleOperationJournalWorker) NeedLeaderElection() bool {
+	return true
+}
+
+// flush applies all the entries in the journal and removes the applied ones.
+// The entries for the HRAs that failed to be updated are kept in the journal to be retried in the next interval.
+func (w *scaleOperationJournalWorker) flush(ctx context.Context) error {
+	entries, done, err := w.journal.list(ctx, w.log)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return w.journal.remove(ctx, done)
+	}
+
+	var (
+		hras    = map[types.NamespacedName]*v1alpha1.HorizontalRunnerAutoscaler{}
+		batches = map[types.NamespacedName]batchScaleOperation{}
+		routed  = map[types.NamespacedName]*routedReservations{}
+		// applied is the IDs of the deliveries in each batch, including the ones already applied to the HRA
+		applied = map[types.NamespacedName][]string{}
+	)
+
+	getHRA := func(nsName types.NamespacedName) (*v1alpha1.HorizontalRunnerAutoscaler, error) {
+		if hra, ok := hras[nsName]; ok {
+			return hra, nil
+		}
+
+		var hra v1alpha1.HorizontalRunnerAutoscaler
+		if err := w.journal.reader.Get(ctx, nsName, &hra); err != nil {
+			if !kerrors.IsNotFound(err) {
+				return nil, err
+			}
+
+			hras[nsName] = nil
+
+			return nil, nil
+		}
+
+		hras[nsName] = &hra
+
+		return &hra, nil
+	}
+
+	for _, e := range entries {
+		log := w.log.WithValues("delivery", e.DeliveryID)
+
+		st, err := w.restore(e, getHRA)
+		if err != nil {
+			return err
+		}
+
+		if st == nil {
+			log.Info("Dropping scale operation for the horizontalrunnerautoscaler that no longer exists", "horizontalrunnerautoscaler", types.NamespacedName{Namespace: e.Target.Namespace, Name: e.Target.Name})
+
+			done = append(done, e.DeliveryID)
+
+			continue
+		}
+
+		if nsName, ok := appliedWebhookDelivery(st, e.DeliveryID); ok {
+			log.V(1).Info("Skipping scale operation that has already been applied", "horizontalrunnerautoscaler", nsName)
+
+			// Keep the delivery recorded in the HRA until the entry is removed
+			applied[nsName] = append(applied[nsName], e.DeliveryID)
+			done = append(done, e.DeliveryID)
+
+			continue
+		}
+
+		st.log = &log
+
+		nsName := w.batchScaler.addToBatches(st, batches, routed)
+
+		applied[nsName] = append(applied[nsName], e.DeliveryID)
+	}
+
+	for nsName, b := range batches {
+		b.appliedDeliveryIDs = applied[nsName]
+
+		if err := w.batchScaler.batchScale(ctx, b); err != nil {
+			w.log.V(2).Info("Failed to scale due to error", "error", err)
+
+			continue
+		}
+
+		w.log.V(2).Info("Successfully ran batch scale", "hra", b.namespacedName)
+
+		done = append(done, applied[nsName]...)
+	}
+
+	return w.journal.remove(ctx, done)
+}
+
+// restore returns the scale target of the entry, or nil when the HRA no longer exists.
+func (w *scaleOperationJournalWorker) restore(e scaleOperationJournalEntry, getHRA func(types.NamespacedName) (*v1alpha1.HorizontalRunnerAutoscaler, error)) (*ScaleTarget, error) {
+	newScaleTarget := func(t scaleOperationJournalTarget) (*ScaleTarget, error) {
+		hra, err := getHRA(types.NamespacedName{Namespace: t.Namespace, Name: t.Name})
+		if err != nil || hra == nil {
+			return nil, err
+		}
+
+		return &ScaleTarget{
+			HorizontalRunnerAutoscaler: *hra,
+			ScaleUpTrigger:             t.Trigger,
+			scaleUpTriggerIndex:        t.TriggerIndex,
+		}, nil
+	}
+
+	st, err := newScaleTarget(e.Target)
+	if err != nil || st == nil {
+		return nil, err
+	}
+
+	st.workflowJob = workflowJobRef{
+		id:         e.WorkflowJobID,
+		repository: e.Repository,
+		runnerName: e.RunnerName,
+	}
+	st.workflowJobInProgress = e.WorkflowJobInProgress
+
+	var candidates []ScaleTarget
+
+	for _, c := range e.Candidates {
+		t, err := newScaleTarget(c)
+		if err != nil {
+			return nil, err
+		}
+
+		// The HRAs deleted in the meantime are no longer routed to
+		if t != nil {
+			candidates = append(candidates, *t)
+		}
+	}
+
+	if len(candidates) > 1 {
+		st.candidates = candidates
+	}
+
+	return st, nil
+}
+
+// appliedWebhookDelivery returns the HRA among the scale target and its candidates that has already applied the delivery, if any.
+func appliedWebhookDelivery(st *ScaleTarget, deliveryID string) (types.NamespacedName, bool) {
+	targets := append([]ScaleTarget{*st}, st.candidates...)
+
+	for _, t := range targets {
+		for _, id := range t.HorizontalRunnerAutoscaler.Status.AppliedWebhookDeliveries {
+			if id == deliveryID {
+				return types.NamespacedName{Namespace: t.HorizontalRunnerAutoscaler.Namespace, Name: t.HorizontalRunnerAutoscaler.Name}, true
+			}
+		}
+	}
+
+	return types.NamespacedName{}, false
+}
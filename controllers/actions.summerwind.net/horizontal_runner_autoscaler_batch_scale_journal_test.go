@@ -0,0 +1,259 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestScaleOperationJournal(t *testing.T) {
+	ctx := context.Background()
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleUpTriggers: []v1alpha1.ScaleUpTrigger{
+				{
+					GitHubEvent: &v1alpha1.GitHubEventScaleUpTriggerSpec{
+						WorkflowJob: &v1alpha1.WorkflowJobSpec{},
+					},
+					Amount:   1,
+					Duration: metav1.Duration{Duration: 30 * time.Minute},
+				},
+			},
+		},
+	}
+
+	client := fake.NewClientBuilder().WithScheme(sc).WithObjects(hra).Build()
+
+	log := zap.New(func(o *zap.Options) {
+		o.Development = true
+	})
+
+	journal := &scaleOperationJournal{
+		reader:    client,
+		client:    client,
+		namespace: "default",
+		name:      "queue-journal",
+		limit:     2,
+	}
+
+	w := &scaleOperationJournalWorker{
+		journal:     journal,
+		batchScaler: newBatchScaler(ctx, client, log),
+		log:         log,
+	}
+
+	entry := func(deliveryID string, jobID int64) scaleOperationJournalEntry {
+		st := &ScaleTarget{
+			HorizontalRunnerAutoscaler: *hra,
+			ScaleUpTrigger:             hra.Spec.ScaleUpTriggers[0],
+			workflowJob:                workflowJobRef{id: jobID, repository: "test/valid"},
+		}
+
+		return newScaleOperationJournalEntry(deliveryID, time.Now(), st)
+	}
+
+	journaled := func() []string {
+		t.Helper()
+
+		var cm corev1.ConfigMap
+		if err := client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "queue-journal"}, &cm); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var ids []string
+		for k := range cm.Data {
+			ids = append(ids, k)
+		}
+
+		return ids
+	}
+
+	reservedJobs := func() ([]int64, []string) {
+		t.Helper()
+
+		var updated v1alpha1.HorizontalRunnerAutoscaler
+		if err := client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "test"}, &updated); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var jobs []int64
+		for _, r := range updated.Status.CapacityReservations {
+			jobs = append(jobs, r.WorkflowJobID)
+		}
+
+		return jobs, updated.Status.AppliedWebhookDeliveries
+	}
+
+	if err := journal.add(ctx, entry("delivery-1", 1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A redelivery is journaled only once
+	if err := journal.add(ctx, entry("delivery-1", 1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := journal.add(ctx, entry("delivery-2", 2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := journal.add(ctx, entry("delivery-3", 3)); !errors.Is(err, errScaleOperationJournalFull) {
+		t.Fatalf("unexpected error: want %v, got %v", errScaleOperationJournalFull, err)
+	}
+
+	if d := cmp.Diff(2, len(journaled())); d != "" {
+		t.Errorf("unexpected number of journaled entries: (-want, +got)\n%s", d)
+	}
+
+	if err := w.flush(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	jobs, applied := reservedJobs()
+
+	if d := cmp.Diff([]int64{1, 2}, jobs); d != "" {
+		t.Errorf("unexpected reserved jobs: (-want, +got)\n%s", d)
+	}
+
+	if d := cmp.Diff([]string{"delivery-1", "delivery-2"}, applied); d != "" {
+		t.Errorf("unexpected applied deliveries: (-want, +got)\n%s", d)
+	}
+
+	if d := cmp.Diff([]string(nil), journaled()); d != "" {
+		t.Errorf("unexpected journaled entries: (-want, +got)\n%s", d)
+	}
+
+	// The entry is replayed as if the previous leader crashed before removing it.
+	// It's skipped as it's already applied, while the new one is applied.
+	if err := journal.add(ctx, entry("delivery-2", 2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := journal.add(ctx, entry("delivery-3", 3)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := w.flush(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	jobs, applied = reservedJobs()
+
+	if d := cmp.Diff([]int64{1, 2, 3}, jobs); d != "" {
+		t.Errorf("unexpected reserved jobs: (-want, +got)\n%s", d)
+	}
+
+	if d := cmp.Diff([]string{"delivery-2", "delivery-3"}, applied); d != "" {
+		t.Errorf("unexpected applied deliveries: (-want, +got)\n%s", d)
+	}
+
+	if d := cmp.Diff([]string(nil), journaled()); d != "" {
+		t.Errorf("unexpected journaled entries: (-want, +got)\n%s", d)
+	}
+}
+
+func TestScaleOperationJournalShards(t *testing.T) {
+	ctx := context.Background()
+
+	client := fake.NewClientBuilder().WithScheme(sc).Build()
+
+	log := zap.New(func(o *zap.Options) {
+		o.Development = true
+	})
+
+	journal := &scaleOperationJournal{
+		reader:    client,
+		client:    client,
+		namespace: "default",
+		name:      "queue-journal",
+		shards:    4,
+		limit:     100,
+	}
+
+	var want []string
+
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("delivery-%02d", i)
+
+		e := scaleOperationJournalEntry{
+			DeliveryID: id,
+			ReceivedAt: time.Date(2022, 1, 1, 0, 0, i, 0, time.UTC),
+			Target:     scaleOperationJournalTarget{Namespace: "default", Name: "test"},
+		}
+
+		if err := journal.add(ctx, e); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want = append(want, id)
+	}
+
+	var cms corev1.ConfigMapList
+	if err := client.List(ctx, &cms); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The entries are spread across the shards
+	if len(cms.Items) < 2 || len(cms.Items) > 4 {
+		t.Errorf("unexpected number of shards: %d", len(cms.Items))
+	}
+
+	entries, invalid, err := journal.list(ctx, log)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.DeliveryID)
+	}
+
+	// The entries of all the shards are listed in the order they were received
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("unexpected entries: (-want, +got)\n%s", d)
+	}
+
+	if len(invalid) != 0 {
+		t.Errorf("unexpected invalid entries: %v", invalid)
+	}
+
+	if err := journal.remove(ctx, want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, _, err = journal.list(ctx, log)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("unexpected entries left in the journal: %d", len(entries))
+	}
+}
+
+func TestWebhookDeliveryID(t *testing.T) {
+	if got := webhookDeliveryID("72d3162e-cc78-11e3-81ab-4c9367dc0958", []byte("{}")); got != "72d3162e-cc78-11e3-81ab-4c9367dc0958" {
+		t.Errorf("unexpected delivery id: %s", got)
+	}
+
+	// The payload digest is used when the header is missing or isn't a valid key
+	want := "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+
+	for _, header := range []string{"", "a/b"} {
+		if got := webhookDeliveryID(header, []byte("{}")); got != want {
+			t.Errorf("unexpected delivery id for %q: want %s, got %s", header, want, got)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestBatchScaleWorkflowJobs(t *testing.T) {
+	now := time.Now().Round(time.Second)
+
+	triggerIndex := 0
+
+	reservation := func(jobID int64, runnerName string) v1alpha1.CapacityReservation {
+		r := v1alpha1.CapacityReservation{
+			EffectiveTime:       metav1.Time{Time: now},
+			ExpirationTime:      metav1.Time{Time: now.Add(time.Hour)},
+			Replicas:            1,
+			ScaleUpTriggerIndex: &triggerIndex,
+			RunnerName:          runnerName,
+		}
+
+		if jobID != 0 {
+			r.WorkflowJobID = jobID
+			r.Repository = "test/valid"
+		}
+
+		return r
+	}
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+		Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+			CapacityReservations: []v1alpha1.CapacityReservation{
+				reservation(0, ""),
+				reservation(10, ""),
+				reservation(11, ""),
+				reservation(12, ""),
+			},
+		},
+	}
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "runner-10"}}
+
+	client := fake.NewClientBuilder().WithScheme(sc).WithRuntimeObjects([]runtime.Object{hra, pod}...).Build()
+
+	log := zap.New(func(o *zap.Options) {
+		o.Development = true
+	})
+
+	s := newBatchScaler(context.Background(), client, log)
+
+	job := func(id int64, runnerName string) workflowJobRef {
+		return workflowJobRef{id: id, repository: "test/valid", runnerName: runnerName}
+	}
+
+	err := s.batchScale(context.Background(), batchScaleOperation{
+		namespacedName: types.NamespacedName{Namespace: "default", Name: "test"},
+		scaleOps: []scaleOperation{
+			// The runner is assigned to the job
+			{trigger: v1alpha1.ScaleUpTrigger{Amount: 1}, workflowJob: job(10, "runner-10"), workflowJobInProgress: true, log: log},
+			// The exact reservation for the job is erased
+			{trigger: v1alpha1.ScaleUpTrigger{Amount: -1}, workflowJob: job(12, "runner-12"), log: log},
+			// The reservation for the job has already been erased, so the one made before workflow jobs were recorded is erased instead
+			{trigger: v1alpha1.ScaleUpTrigger{Amount: -1}, workflowJob: job(13, "runner-13"), log: log},
+			// No reservation for another job is erased
+			{trigger: v1alpha1.ScaleUpTrigger{Amount: -1}, workflowJob: job(14, "runner-14"), log: log},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var updated v1alpha1.HorizontalRunnerAutoscaler
+
+	if err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "test"}, &updated); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []v1alpha1.CapacityReservation{
+		reservation(10, "runner-10"),
+		reservation(11, ""),
+	}
+
+	if d := cmp.Diff(want, updated.Status.CapacityReservations); d != "" {
+		t.Errorf("unexpected capacity reservations: (-want, +got)\n%s", d)
+	}
+
+	var updatedPod corev1.Pod
+
+	if err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "runner-10"}, &updatedPod); err != nil {
+		t.Fatal(err)
+	}
+
+	wantAnnotations := map[string]string{
+		AnnotationKeyWorkflowJobID:         "10",
+		AnnotationKeyWorkflowJobRepository: "test/valid",
+	}
+
+	if d := cmp.Diff(wantAnnotations, updatedPod.Annotations); d != "" {
+		t.Errorf("unexpected pod annotations: (-want, +got)\n%s", d)
+	}
+}
+
+// staleCacheClient serves the HRA as it was before the latest update, as a lagging cache does.
+type staleCacheClient struct {
+	client.Client
+
+	stale *v1alpha1.HorizontalRunnerAutoscaler
+}
+
+func (c *staleCacheClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if hra, ok := obj.(*v1alpha1.HorizontalRunnerAutoscaler); ok {
+		c.stale.DeepCopyInto(hra)
+		return nil
+	}
+
+	return c.Client.Get(ctx, key, obj)
+}
+
+func TestBatchScaleWithStaleCache(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Now().Round(time.Second)
+
+	reservation := func(jobID int64) v1alpha1.CapacityReservation {
+		return v1alpha1.CapacityReservation{
+			EffectiveTime:  metav1.Time{Time: now},
+			ExpirationTime: metav1.Time{Time: now.Add(time.Hour)},
+			Replicas:       1,
+			WorkflowJobID:  jobID,
+			Repository:     "test/valid",
+		}
+	}
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(sc).WithObjects(hra).Build()
+
+	var stale v1alpha1.HorizontalRunnerAutoscaler
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "test"}, &stale); err != nil {
+		t.Fatal(err)
+	}
+
+	// The reservation added by the previous batch isn't in the cache yet
+	latest := stale.DeepCopy()
+	latest.Status.CapacityReservations = []v1alpha1.CapacityReservation{reservation(1)}
+	if err := c.Status().Update(ctx, latest); err != nil {
+		t.Fatal(err)
+	}
+
+	log := zap.New(func(o *zap.Options) {
+		o.Development = true
+	})
+
+	s := newBatchScaler(ctx, &staleCacheClient{Client: c, stale: &stale}, log)
+	s.reader = c
+
+	err := s.batchScale(ctx, batchScaleOperation{
+		namespacedName: types.NamespacedName{Namespace: "default", Name: "test"},
+		scaleOps: []scaleOperation{
+			{trigger: v1alpha1.ScaleUpTrigger{Amount: 1, Duration: metav1.Duration{Duration: time.Hour}}, workflowJob: workflowJobRef{id: 2, repository: "test/valid"}, log: log},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var updated v1alpha1.HorizontalRunnerAutoscaler
+
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "test"}, &updated); err != nil {
+		t.Fatal(err)
+	}
+
+	var jobs []int64
+	for _, r := range updated.Status.CapacityReservations {
+		jobs = append(jobs, r.WorkflowJobID)
+	}
+
+	// The conflicting patch is retried with the latest HRA instead of overwriting the reservation
+	if d := cmp.Diff([]int64{1, 2}, jobs); d != "" {
+		t.Errorf("unexpected reserved jobs: (-want, +got)\n%s", d)
+	}
+}
+
+func TestBatchScaleMovesSpecCapacityReservationsToStatus(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Now().Round(time.Second)
+
+	legacy := v1alpha1.CapacityReservation{
+		EffectiveTime:  metav1.Time{Time: now},
+		ExpirationTime: metav1.Time{Time: now.Add(time.Hour)},
+		Replicas:       2,
+	}
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			CapacityReservations: []v1alpha1.CapacityReservation{
+				legacy,
+				// Expired reservations aren't moved
+				{
+					EffectiveTime:  metav1.Time{Time: now.Add(-2 * time.Hour)},
+					ExpirationTime: metav1.Time{Time: now.Add(-time.Hour)},
+					Replicas:       1,
+				},
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(sc).WithObjects(hra).Build()
+
+	log := zap.New(func(o *zap.Options) {
+		o.Development = true
+	})
+
+	s := newBatchScaler(ctx, c, log)
+	s.clock = func() time.Time { return now }
+
+	err := s.batchScale(ctx, batchScaleOperation{
+		namespacedName: types.NamespacedName{Namespace: "default", Name: "test"},
+		scaleOps: []scaleOperation{
+			{trigger: v1alpha1.ScaleUpTrigger{Amount: 1, Duration: metav1.Duration{Duration: time.Hour}}, log: log},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var updated v1alpha1.HorizontalRunnerAutoscaler
+
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "test"}, &updated); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(updated.Spec.CapacityReservations) != 0 {
+		t.Errorf("unexpected capacity reservations left in the spec: %v", updated.Spec.CapacityReservations)
+	}
+
+	triggerIndex := 0
+
+	want := []v1alpha1.CapacityReservation{
+		legacy,
+		{
+			EffectiveTime:       metav1.Time{Time: now},
+			ExpirationTime:      metav1.Time{Time: now.Add(time.Hour)},
+			Replicas:            1,
+			ScaleUpTriggerIndex: &triggerIndex,
+		},
+	}
+
+	if d := cmp.Diff(want, updated.Status.CapacityReservations); d != "" {
+		t.Errorf("unexpected capacity reservations: (-want, +got)\n%s", d)
+	}
+}
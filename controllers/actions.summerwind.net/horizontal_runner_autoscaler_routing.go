@@ -0,0 +1,279 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// ScaleTargetRoutingPolicyFirst scales the first HRA that matches the webhook event,
+	// searching repository runners, organization runner groups, and enterprise runner groups in this order.
+	// Two or more HRAs matching the same event at the same level are considered ambiguous and none of them is scaled,
+	// unless the event is a workflow_job event.
+	ScaleTargetRoutingPolicyFirst = "First"
+
+	// ScaleTargetRoutingPolicyWeighted spreads the capacity reservations across all the matching HRAs
+	// in proportion to their spec.routingWeight.
+	ScaleTargetRoutingPolicyWeighted = "Weighted"
+
+	// ScaleTargetRoutingPolicyLeastReserved scales the matching HRA that has the fewest reserved replicas.
+	ScaleTargetRoutingPolicyLeastReserved = "LeastReserved"
+
+	// ScaleTargetRoutingPolicyFirstWithHeadroom scales the first matching HRA, in the same order as First,
+	// whose minReplicas plus reserved replicas still leave room for the scale-up below its spec.maxReplicas.
+	// When no HRA has headroom, the first one is scaled.
+	ScaleTargetRoutingPolicyFirstWithHeadroom = "FirstWithHeadroom"
+
+	DefaultScaleTargetRoutingPolicy = ScaleTargetRoutingPolicyFirst
+)
+
+var ScaleTargetRoutingPolicies = []string{
+	ScaleTargetRoutingPolicyFirst,
+	ScaleTargetRoutingPolicyWeighted,
+	ScaleTargetRoutingPolicyLeastReserved,
+	ScaleTargetRoutingPolicyFirstWithHeadroom,
+}
+
+// ValidateScaleTargetRoutingPolicy returns an error when the policy is not one of ScaleTargetRoutingPolicies.
+// An empty policy is valid and means DefaultScaleTargetRoutingPolicy.
+func ValidateScaleTargetRoutingPolicy(policy string) error {
+	if policy == "" {
+		return nil
+	}
+
+	for _, p := range ScaleTargetRoutingPolicies {
+		if p == policy {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported scale target routing policy %q: it must be one of %v", policy, ScaleTargetRoutingPolicies)
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) routingEnabled() bool {
+	return autoscaler.RoutingPolicy != "" && autoscaler.RoutingPolicy != ScaleTargetRoutingPolicyFirst
+}
+
+// routedReservations is the capacity reservations routed to an HRA within a batch,
+// which are not yet reflected to the HRA observed by the webhook-based autoscaler.
+type routedReservations struct {
+	// replicas is the net number of replicas added to the HRA
+	replicas int
+	// removals is the number of reservations made before workflow jobs were recorded, removed from the HRA
+	removals int
+	// workflowJobIDs is the IDs of the workflow jobs whose reservations are added to the HRA
+	workflowJobIDs []int64
+}
+
+// routeScaleTarget chooses the scale target among the candidates of st according to the routing policy.
+//
+// A scale-up is routed according to the policy, taking the reservations already routed in the current batch into account,
+// so that a burst of webhook events is spread across the HRAs even before the HRAs are updated.
+// A scale-down, or an assignment of a runner to a workflow job, is routed to the HRA that holds the reservation for the same job
+// regardless of the policy, so that it cancels out or updates the scale-up made for the job.
+// A scale-down for a job without such a reservation is routed to the first HRA that still holds a reservation
+// made before workflow jobs were recorded, which the scale-down can erase.
+func routeScaleTarget(policy string, now time.Time, st *ScaleTarget, routed map[types.NamespacedName]*routedReservations) *ScaleTarget {
+	if len(st.candidates) < 2 {
+		return st
+	}
+
+	candidates := st.candidates
+
+	routedTo := func(i int) *routedReservations {
+		hra := candidates[i].HorizontalRunnerAutoscaler
+
+		key := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}
+
+		r, ok := routed[key]
+		if !ok {
+			r = &routedReservations{}
+			routed[key] = r
+		}
+
+		return r
+	}
+
+	reserved := func(i int) int {
+		var n int
+
+		for _, r := range getValidCapacityReservationsAt(&candidates[i].HorizontalRunnerAutoscaler, now) {
+			n += r.Replicas
+		}
+
+		return n + routedTo(i).replicas
+	}
+
+	amount := func(i int) int {
+		if candidates[i].Amount == 0 {
+			return 1
+		}
+
+		return candidates[i].Amount
+	}
+
+	var (
+		chosen int
+		// noop is true when the scale-down erases no reservation
+		noop bool
+	)
+
+	if i, ok := chooseScaleTargetHoldingWorkflowJob(candidates, now, st.workflowJob, routedTo); ok && (st.Amount < 0 || st.workflowJobInProgress) {
+		chosen = i
+	} else if st.workflowJobInProgress {
+		noop = true
+	} else if st.Amount < 0 {
+		var found bool
+
+		chosen, found = chooseScaleTargetHoldingReservation(candidates, now, amount, routedTo)
+
+		noop = !found
+	} else {
+		switch policy {
+		case ScaleTargetRoutingPolicyWeighted:
+			var (
+				found bool
+				min   float64
+			)
+
+			for i := range candidates {
+				w := 1
+				if rw := candidates[i].Spec.RoutingWeight; rw != nil {
+					w = *rw
+				}
+
+				if w <= 0 {
+					continue
+				}
+
+				load := float64(reserved(i)+amount(i)) / float64(w)
+
+				if !found || load < min {
+					found = true
+					min = load
+					chosen = i
+				}
+			}
+		case ScaleTargetRoutingPolicyLeastReserved:
+			min := reserved(0)
+
+			for i := 1; i < len(candidates); i++ {
+				if n := reserved(i); n < min {
+					min = n
+					chosen = i
+				}
+			}
+		case ScaleTargetRoutingPolicyFirstWithHeadroom:
+			for i := range candidates {
+				spec := candidates[i].Spec
+
+				if spec.MaxReplicas == nil {
+					chosen = i
+					break
+				}
+
+				minReplicas := defaultReplicas
+				if spec.MinReplicas != nil && *spec.MinReplicas >= 0 {
+					minReplicas = *spec.MinReplicas
+				}
+
+				if minReplicas+reserved(i)+amount(i) <= *spec.MaxReplicas {
+					chosen = i
+					break
+				}
+			}
+		}
+	}
+
+	t := candidates[chosen]
+
+	t.log = st.log
+	t.workflowJob = st.workflowJob
+	t.workflowJobInProgress = st.workflowJobInProgress
+	t.Amount = amount(chosen)
+
+	if st.Amount < 0 {
+		t.Amount = -t.Amount
+	}
+
+	if !noop && !t.workflowJobInProgress {
+		r := routedTo(chosen)
+
+		if t.Amount > 0 && t.workflowJob.id != 0 {
+			r.workflowJobIDs = append(r.workflowJobIDs, t.workflowJob.id)
+		} else if t.Amount < 0 && !holdsWorkflowJob(candidates[chosen], now, t.workflowJob, r) {
+			r.removals++
+		}
+
+		r.replicas += t.Amount
+	}
+
+	return &t
+}
+
+// chooseScaleTargetHoldingWorkflowJob returns the index of the candidate that holds the reservation for the workflow job,
+// including the one routed in the current batch.
+func chooseScaleTargetHoldingWorkflowJob(candidates []ScaleTarget, now time.Time, job workflowJobRef, routedTo func(int) *routedReservations) (int, bool) {
+	if job.id == 0 {
+		return 0, false
+	}
+
+	for i := range candidates {
+		if holdsWorkflowJob(candidates[i], now, job, routedTo(i)) {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+func holdsWorkflowJob(st ScaleTarget, now time.Time, job workflowJobRef, routed *routedReservations) bool {
+	if job.id == 0 {
+		return false
+	}
+
+	for _, id := range routed.workflowJobIDs {
+		if id == job.id {
+			return true
+		}
+	}
+
+	for _, r := range getValidCapacityReservationsAt(&st.HorizontalRunnerAutoscaler, now) {
+		if r.WorkflowJobID == job.id && r.Repository == job.repository {
+			return true
+		}
+	}
+
+	return false
+}
+
+// chooseScaleTargetHoldingReservation returns the index of the first candidate that holds a reservation made before
+// workflow jobs were recorded, which the scale-down can erase.
+// It returns the first candidate and false when there's no such candidate.
+func chooseScaleTargetHoldingReservation(candidates []ScaleTarget, now time.Time, amount func(int) int, routedTo func(int) *routedReservations) (int, bool) {
+	for i := range candidates {
+		var held int
+
+		for _, r := range getValidCapacityReservationsAt(&candidates[i].HorizontalRunnerAutoscaler, now) {
+			// A reservation for a workflow job is erased only by the completion of the job
+			if r.WorkflowJobID != 0 {
+				continue
+			}
+
+			// Reservations created before ScaleUpTriggerIndex was introduced can be erased by any trigger
+			sameTrigger := r.ScaleUpTriggerIndex == nil || *r.ScaleUpTriggerIndex == candidates[i].scaleUpTriggerIndex
+
+			if r.Replicas == amount(i) && sameTrigger {
+				held++
+			}
+		}
+
+		if held > routedTo(i).removals {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,210 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRouteScaleTarget(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	now := time.Now()
+
+	reservation := func(replicas int) v1alpha1.CapacityReservation {
+		triggerIndex := 0
+
+		return v1alpha1.CapacityReservation{
+			ExpirationTime:      metav1.Time{Time: now.Add(time.Minute)},
+			Replicas:            replicas,
+			ScaleUpTriggerIndex: &triggerIndex,
+		}
+	}
+
+	target := func(name string, spec v1alpha1.HorizontalRunnerAutoscalerSpec) ScaleTarget {
+		return ScaleTarget{
+			HorizontalRunnerAutoscaler: v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+				Spec:       spec,
+			},
+			ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Amount: 1},
+		}
+	}
+
+	testcases := []struct {
+		name       string
+		policy     string
+		candidates []ScaleTarget
+		// amounts is the amount of each scale operation routed in a single batch, in order.
+		// Zero means an assignment of a runner to the workflow job.
+		amounts     []int
+		workflowJob workflowJobRef
+
+		want []string
+	}{
+		{
+			name:   "single target",
+			policy: ScaleTargetRoutingPolicyLeastReserved,
+			candidates: []ScaleTarget{
+				target("a", v1alpha1.HorizontalRunnerAutoscalerSpec{}),
+			},
+			amounts: []int{1, 1},
+			want:    []string{"a", "a"},
+		},
+		{
+			name:   "least reserved",
+			policy: ScaleTargetRoutingPolicyLeastReserved,
+			candidates: []ScaleTarget{
+				target("a", v1alpha1.HorizontalRunnerAutoscalerSpec{
+					CapacityReservations: []v1alpha1.CapacityReservation{reservation(1), reservation(1)},
+				}),
+				target("b", v1alpha1.HorizontalRunnerAutoscalerSpec{}),
+			},
+			amounts: []int{1, 1, 1},
+			want:    []string{"b", "b", "a"},
+		},
+		{
+			name:   "expired reservations are not counted",
+			policy: ScaleTargetRoutingPolicyLeastReserved,
+			candidates: []ScaleTarget{
+				target("a", v1alpha1.HorizontalRunnerAutoscalerSpec{
+					CapacityReservations: []v1alpha1.CapacityReservation{
+						{ExpirationTime: metav1.Time{Time: now.Add(-time.Minute)}, Replicas: 3},
+					},
+				}),
+				target("b", v1alpha1.HorizontalRunnerAutoscalerSpec{}),
+			},
+			amounts: []int{1, 1},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:   "weighted",
+			policy: ScaleTargetRoutingPolicyWeighted,
+			candidates: []ScaleTarget{
+				target("a", v1alpha1.HorizontalRunnerAutoscalerSpec{RoutingWeight: intPtr(2)}),
+				target("b", v1alpha1.HorizontalRunnerAutoscalerSpec{}),
+			},
+			amounts: []int{1, 1, 1, 1, 1, 1},
+			want:    []string{"a", "a", "b", "a", "a", "b"},
+		},
+		{
+			name:   "zero weight",
+			policy: ScaleTargetRoutingPolicyWeighted,
+			candidates: []ScaleTarget{
+				target("a", v1alpha1.HorizontalRunnerAutoscalerSpec{RoutingWeight: intPtr(0)}),
+				target("b", v1alpha1.HorizontalRunnerAutoscalerSpec{}),
+			},
+			amounts: []int{1, 1},
+			want:    []string{"b", "b"},
+		},
+		{
+			name:   "first with headroom",
+			policy: ScaleTargetRoutingPolicyFirstWithHeadroom,
+			candidates: []ScaleTarget{
+				target("a", v1alpha1.HorizontalRunnerAutoscalerSpec{MinReplicas: intPtr(1), MaxReplicas: intPtr(3)}),
+				target("b", v1alpha1.HorizontalRunnerAutoscalerSpec{MinReplicas: intPtr(0), MaxReplicas: intPtr(1)}),
+			},
+			amounts: []int{1, 1, 1, 1},
+			// Falls back to the first target when no target has headroom
+			want: []string{"a", "a", "b", "a"},
+		},
+		{
+			name:   "scale-down is routed to the target holding the reservation",
+			policy: ScaleTargetRoutingPolicyLeastReserved,
+			candidates: []ScaleTarget{
+				target("a", v1alpha1.HorizontalRunnerAutoscalerSpec{}),
+				target("b", v1alpha1.HorizontalRunnerAutoscalerSpec{
+					CapacityReservations: []v1alpha1.CapacityReservation{reservation(1)},
+				}),
+			},
+			// The second scale-down erases nothing, and the last scale-up sees no reservation in either target
+			amounts: []int{-1, -1, 1},
+			want:    []string{"b", "a", "a"},
+		},
+		{
+			name:   "runner assignment and scale-down are routed to the target holding the workflow job",
+			policy: ScaleTargetRoutingPolicyLeastReserved,
+			candidates: []ScaleTarget{
+				target("a", v1alpha1.HorizontalRunnerAutoscalerSpec{}),
+				target("b", v1alpha1.HorizontalRunnerAutoscalerSpec{
+					CapacityReservations: []v1alpha1.CapacityReservation{
+						{ExpirationTime: metav1.Time{Time: now.Add(time.Minute)}, Replicas: 1, WorkflowJobID: 10, Repository: "test/valid"},
+					},
+				}),
+			},
+			workflowJob: workflowJobRef{id: 10, repository: "test/valid", runnerName: "runner"},
+			amounts:     []int{0, -1},
+			want:        []string{"b", "b"},
+		},
+		{
+			name:   "scale-down follows the scale-up for the same workflow job in the batch",
+			policy: ScaleTargetRoutingPolicyLeastReserved,
+			candidates: []ScaleTarget{
+				target("a", v1alpha1.HorizontalRunnerAutoscalerSpec{
+					CapacityReservations: []v1alpha1.CapacityReservation{reservation(1)},
+				}),
+				target("b", v1alpha1.HorizontalRunnerAutoscalerSpec{}),
+			},
+			workflowJob: workflowJobRef{id: 11, repository: "test/valid"},
+			amounts:     []int{1, -1},
+			want:        []string{"b", "b"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			routed := map[types.NamespacedName]*routedReservations{}
+
+			var got []string
+
+			for _, amount := range tc.amounts {
+				st := tc.candidates[0]
+				st.Amount = amount
+				st.workflowJob = tc.workflowJob
+				if amount == 0 {
+					st.Amount = 1
+					st.workflowJobInProgress = true
+				}
+				if len(tc.candidates) > 1 {
+					st.candidates = tc.candidates
+				}
+
+				r := routeScaleTarget(tc.policy, now, &st, routed)
+
+				if r.workflowJob != tc.workflowJob || r.workflowJobInProgress != st.workflowJobInProgress {
+					t.Errorf("unexpected workflow job routed to %s: %+v", r.Name, r.workflowJob)
+				}
+
+				if amount != 0 && r.Amount != amount {
+					t.Errorf("unexpected amount routed to %s: want %d, got %d", r.Name, amount, r.Amount)
+				}
+
+				got = append(got, r.Name)
+			}
+
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Errorf("unexpected routes: (-want, +got)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestValidateScaleTargetRoutingPolicy(t *testing.T) {
+	for _, p := range append([]string{""}, ScaleTargetRoutingPolicies...) {
+		if err := ValidateScaleTargetRoutingPolicy(p); err != nil {
+			t.Errorf("unexpected error for %q: %v", p, err)
+		}
+	}
+
+	if err := ValidateScaleTargetRoutingPolicy("RoundRobin"); err == nil {
+		t.Error("expected an error for an unsupported policy")
+	}
+}
@@ -22,6 +22,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,6 +33,7 @@ import (
 
 	"github.com/go-logr/logr"
 	gogithub "github.com/google/go-github/v47/github"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -74,8 +78,24 @@ type HorizontalRunnerAutoscalerGitHubWebhook struct {
 	// A scale target is enqueued on each retrieval of each eligible webhook event, so that it is processed asynchronously.
 	QueueLimit int
 
+	// RoutingPolicy is the policy to choose the HRA to scale when two or more HRAs match a webhook event.
+	// See ScaleTargetRoutingPolicies for the available policies. Defaults to DefaultScaleTargetRoutingPolicy.
+	RoutingPolicy string
+
+	// QueueJournalNamespace and QueueJournalName are the namespace and the name prefix of the ConfigMaps to persist the queue of scale operations.
+	// When set, every webhook event is journaled in the ConfigMap before it's acknowledged, and the journaled scale operations are
+	// applied only by the leader, so that they survive restarts of the webhook server and it can be run with two or more replicas.
+	// The queue is kept in memory when omitted.
+	QueueJournalNamespace string
+	QueueJournalName      string
+
 	worker     *worker
 	workerInit sync.Once
+
+	journal *scaleOperationJournal
+
+	// apiReader reads the HRAs bypassing the cache, which is used by the batch scaler to retry conflicting updates
+	apiReader client.Reader
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Reconcile(_ context.Context, request reconcile.Request) (reconcile.Result, error) {
@@ -174,6 +194,57 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 	enterpriseSlug := enterpriseEvent.Enterprise.Slug
 
 	switch e := event.(type) {
+	case *gogithub.PushEvent:
+		target, err = autoscaler.getScaleUpTarget(
+			context.TODO(),
+			log,
+			e.Repo.GetName(),
+			e.Repo.Owner.GetLogin(),
+			e.Repo.Owner.GetType(),
+			// Most go-github Event types don't seem to contain Enteprirse(.Slug) fields
+			// we need, so we parse it by ourselves.
+			enterpriseSlug,
+			autoscaler.MatchPushEvent(e),
+		)
+	case *gogithub.PullRequestEvent:
+		if pullRequest := e.PullRequest; pullRequest != nil {
+			log = log.WithValues(
+				"pullRequest.base.ref", pullRequest.Base.GetRef(),
+				"action", e.GetAction(),
+			)
+		}
+
+		target, err = autoscaler.getScaleUpTarget(
+			context.TODO(),
+			log,
+			e.Repo.GetName(),
+			e.Repo.Owner.GetLogin(),
+			e.Repo.Owner.GetType(),
+			// Most go-github Event types don't seem to contain Enteprirse(.Slug) fields
+			// we need, so we parse it by ourselves.
+			enterpriseSlug,
+			autoscaler.MatchPullRequestEvent(e),
+		)
+	case *gogithub.CheckRunEvent:
+		if checkRun := e.GetCheckRun(); checkRun != nil {
+			log = log.WithValues(
+				"checkRun.status", checkRun.GetStatus(),
+				"checkRun.name", checkRun.GetName(),
+				"action", e.GetAction(),
+			)
+		}
+
+		target, err = autoscaler.getScaleUpTarget(
+			context.TODO(),
+			log,
+			e.Repo.GetName(),
+			e.Repo.Owner.GetLogin(),
+			e.Repo.Owner.GetType(),
+			// Most go-github Event types don't seem to contain Enteprirse(.Slug) fields
+			// we need, so we parse it by ourselves.
+			enterpriseSlug,
+			autoscaler.MatchCheckRunEvent(e),
+		)
 	case *gogithub.WorkflowJobEvent:
 		if workflowJob := e.GetWorkflowJob(); workflowJob != nil {
 			log = log.WithValues(
@@ -191,8 +262,20 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 
 		labels := e.WorkflowJob.Labels
 
+		// go-github doesn't expose the workflow name of the workflow job,
+		// so we parse it by ourselves.
+		var workflowJobEvent struct {
+			WorkflowJob struct {
+				WorkflowName string `json:"workflow_name,omitempty"`
+			} `json:"workflow_job,omitempty"`
+		}
+		if err := json.Unmarshal(payload, &workflowJobEvent); err != nil {
+			log.Error(err, "could not parse webhook payload for extracting workflow name")
+		}
+		workflowName := workflowJobEvent.WorkflowJob.WorkflowName
+
 		switch action := e.GetAction(); action {
-		case "queued", "completed":
+		case "queued", "in_progress", "completed":
 			target, err = autoscaler.getJobScaleUpTargetForRepoOrOrg(
 				context.TODO(),
 				log,
@@ -201,23 +284,36 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 				e.Repo.Owner.GetType(),
 				enterpriseSlug,
 				labels,
+				autoscaler.MatchWorkflowJobEvent(e, workflowName),
 			)
 			if target == nil {
 				break
 			}
 
+			target.workflowJob = workflowJobRef{
+				id:         e.GetWorkflowJob().GetID(),
+				repository: e.Repo.GetFullName(),
+				runnerName: e.GetWorkflowJob().GetRunnerName(),
+			}
+
 			if e.GetAction() == "queued" {
-				target.Amount = 1
+				break
+			} else if e.GetAction() == "in_progress" && target.workflowJob.runnerName != "" {
+				// An in_progress event doesn't change the capacity.
+				// It attaches the runner to the reservation made for the job, and annotates the runner with the job.
+				target.workflowJobInProgress = true
 				break
 			} else if e.GetAction() == "completed" && e.GetWorkflowJob().GetConclusion() != "skipped" {
 				// A nagative amount is processed in the tryScale func as a scale-down request,
-				// that erasese the oldest CapacityReservation with the same amount.
-				// If the first CapacityReservation was with Replicas=1, this negative scale target erases that,
+				// that erases the CapacityReservation made for the same workflow job.
+				// For a CapacityReservation made before workflow jobs were recorded, it erases
+				// the oldest one with the same amount and the same scale-up trigger instead.
+				// If the CapacityReservation was with Replicas=1, this negative scale target erases that,
 				// so that the resulting desired replicas decreases by 1.
-				target.Amount = -1
+				target.Amount = -target.Amount
 				break
 			}
-			// If the conclusion is "skipped", we will ignore it and fallthrough to the default case.
+			// If the conclusion is "skipped", or the in_progress job has no runner name, we will ignore it and fallthrough to the default case.
 			fallthrough
 		default:
 			ok = true
@@ -249,7 +345,7 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 	}
 
 	if err != nil {
-		log.Error(err, "handling check_run event")
+		log.Error(err, "handling github event")
 
 		return
 	}
@@ -272,20 +368,29 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		return
 	}
 
-	autoscaler.workerInit.Do(func() {
-		batchScaler := newBatchScaler(context.Background(), autoscaler.Client, autoscaler.Log)
+	target.log = &log
+
+	if autoscaler.journal != nil {
+		e := newScaleOperationJournalEntry(webhookDeliveryID(r.Header.Get("X-GitHub-Delivery"), payload), time.Now(), target)
 
-		queueLimit := autoscaler.QueueLimit
-		if queueLimit == 0 {
-			queueLimit = DefaultQueueLimit
+		// The delivery is acknowledged only after it's journaled, so that GitHub can redeliver it otherwise
+		if err = autoscaler.journal.add(r.Context(), e); err != nil {
+			log.Error(err, "Could not add scale operation to the journal")
+			return
 		}
-		autoscaler.worker = newWorker(context.Background(), queueLimit, batchScaler.Add)
-	})
+	} else {
+		autoscaler.workerInit.Do(func() {
+			batchScaler := newBatchScaler(context.Background(), autoscaler.Client, autoscaler.Log)
+			batchScaler.routingPolicy = autoscaler.RoutingPolicy
+			batchScaler.reader = autoscaler.apiReader
 
-	target.log = &log
-	if ok := autoscaler.worker.Add(target); !ok {
-		log.Error(err, "Could not scale up due to queue full")
-		return
+			autoscaler.worker = newWorker(context.Background(), autoscaler.queueLimit(), batchScaler.Add)
+		})
+
+		if ok := autoscaler.worker.Add(target); !ok {
+			log.Error(err, "Could not scale up due to queue full")
+			return
+		}
 	}
 
 	ok = true
@@ -294,6 +399,10 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 
 	msg := fmt.Sprintf("scaled %s by %d", target.Name, target.Amount)
 
+	if target.workflowJobInProgress {
+		msg = fmt.Sprintf("assigned runner %s to workflow job %d on %s", target.workflowJob.runnerName, target.workflowJob.id, target.Name)
+	}
+
 	log.Info(msg)
 
 	if written, err := w.Write([]byte(msg)); err != nil {
@@ -301,6 +410,14 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 	}
 }
 
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) queueLimit() int {
+	if autoscaler.QueueLimit == 0 {
+		return DefaultQueueLimit
+	}
+
+	return autoscaler.QueueLimit
+}
+
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) findHRAsByKey(ctx context.Context, value string) ([]v1alpha1.HorizontalRunnerAutoscaler, error) {
 	ns := autoscaler.Namespace
 
@@ -326,9 +443,27 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) findHRAsByKey(ctx con
 			return nil, err
 		}
 
-		hras = append(hras, hraList.Items...)
+		for _, hra := range hraList.Items {
+			// HRAs in dry-run mode never receive capacity reservations,
+			// so that they don't take scale-ups away from the live HRAs they are compared against.
+			if hra.Spec.DryRun {
+				continue
+			}
+
+			hras = append(hras, hra)
+		}
 	}
 
+	// Sort the HRAs so that the scale targets are found in a stable order,
+	// which matters to the routing policies that prefer earlier targets.
+	sort.SliceStable(hras, func(i, j int) bool {
+		if hras[i].Namespace != hras[j].Namespace {
+			return hras[i].Namespace < hras[j].Namespace
+		}
+
+		return hras[i].Name < hras[j].Name
+	})
+
 	return hras, nil
 }
 
@@ -336,36 +471,176 @@ type ScaleTarget struct {
 	v1alpha1.HorizontalRunnerAutoscaler
 	v1alpha1.ScaleUpTrigger
 
+	// scaleUpTriggerIndex is the index of ScaleUpTrigger within the HRA's spec.scaleUpTriggers
+	scaleUpTriggerIndex int
+
+	// candidates is all the scale targets that matched the webhook event including this one, in the order of preference.
+	// It's set only when a routing policy other than First is enabled and two or more HRAs matched,
+	// so that the batch scaler can route the scale operation to one of them.
+	candidates []ScaleTarget
+
+	// workflowJob is the workflow job that triggered the scale operation.
+	// It's set only for workflow_job events.
+	workflowJob workflowJobRef
+
+	// workflowJobInProgress is true when the operation only assigns the runner to the reservation made for the workflow job,
+	// without changing the capacity.
+	workflowJobInProgress bool
+
 	log *logr.Logger
 }
 
+// workflowJobRef identifies a workflow job along with the runner the job is assigned to.
+type workflowJobRef struct {
+	id         int64
+	repository string
+	// runnerName is known only once the job is in progress
+	runnerName string
+}
+
+func matchTriggerConditionAgainstEvent(types []string, eventAction *string) bool {
+	if len(types) == 0 {
+		return true
+	}
+
+	if eventAction == nil {
+		return false
+	}
+
+	for _, tpe := range types {
+		if tpe == *eventAction {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleTargets(ctx context.Context, name string, f func(v1alpha1.ScaleUpTrigger) bool) ([]ScaleTarget, error) {
+	hras, err := autoscaler.findHRAsByKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	autoscaler.Log.V(1).Info(fmt.Sprintf("Found %d HRAs by key", len(hras)), "key", name)
+
+	targets := autoscaler.searchScaleTargets(hras, f)
+
+	n := len(targets)
+
+	if n == 0 {
+		return nil, nil
+	}
+
+	if n > 1 && !autoscaler.routingEnabled() {
+		var scaleTargetIDs []string
+
+		for _, t := range targets {
+			scaleTargetIDs = append(scaleTargetIDs, t.HorizontalRunnerAutoscaler.Name)
+		}
+
+		autoscaler.Log.Info(
+			"Found too many scale targets: "+
+				"It must be exactly one to avoid ambiguity. "+
+				"Either set Namespace for the webhook-based autoscaler to let it only find HRAs in the namespace, "+
+				"or update Repository, Organization, or Enterprise fields in your RunnerDeployment resources to fix the ambiguity.",
+			"scaleTargets", strings.Join(scaleTargetIDs, ","))
+
+		return nil, nil
+	}
+
+	return targets, nil
+}
+
+// searchScaleTargets returns a scale target for each HRA that has a scale-up trigger matching the event.
+// Only the first matching trigger of each HRA is used so that a single event never scales an HRA twice.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) searchScaleTargets(hras []v1alpha1.HorizontalRunnerAutoscaler, f func(v1alpha1.ScaleUpTrigger) bool) []ScaleTarget {
+	var matched []ScaleTarget
+
+	for _, hra := range hras {
+		if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		for i, scaleUpTrigger := range hra.Spec.ScaleUpTriggers {
+			if !f(scaleUpTrigger) {
+				continue
+			}
+
+			if scaleUpTrigger.Amount == 0 {
+				scaleUpTrigger.Amount = 1
+			}
+			scaleUpTrigger.Duration = getScaleUpTriggerDuration(scaleUpTrigger)
+
+			matched = append(matched, ScaleTarget{
+				HorizontalRunnerAutoscaler: hra,
+				ScaleUpTrigger:             scaleUpTrigger,
+				scaleUpTriggerIndex:        i,
+			})
+
+			break
+		}
+	}
+
+	return matched
+}
+
+func getScaleUpTriggerDuration(scaleUpTrigger v1alpha1.ScaleUpTrigger) metav1.Duration {
+	duration := scaleUpTrigger.Duration
+	if duration.Duration <= 0 {
+		// Try to release the reserved capacity after at least 10 minutes by default,
+		// we won't end up in the reserved capacity remained forever in case GitHub somehow stopped sending us "completed" workflow_job events.
+		// GitHub usually send us those but nothing is 100% guaranteed, e.g. in case of something went wrong on GitHub :)
+		// Probably we'd better make this configurable via custom resources in the future?
+		duration.Duration = 10 * time.Minute
+	}
+
+	return duration
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTarget(ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, f func(v1alpha1.ScaleUpTrigger) bool) (*ScaleTarget, error) {
+	scaleTargets := func(value string) ([]ScaleTarget, error) {
+		return autoscaler.getScaleTargets(ctx, value, f)
+	}
+	return autoscaler.getScaleUpTargetWithFunction(ctx, log, repo, owner, ownerType, enterprise, scaleTargets)
+}
+
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleUpTargetForRepoOrOrg(
-	ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, labels []string,
+	ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, labels []string, f func(v1alpha1.ScaleUpTrigger) bool,
 ) (*ScaleTarget, error) {
 
-	scaleTarget := func(value string) (*ScaleTarget, error) {
-		return autoscaler.getJobScaleTarget(ctx, value, labels)
+	scaleTargets := func(value string) ([]ScaleTarget, error) {
+		return autoscaler.getJobScaleTargets(ctx, value, labels, f)
 	}
-	return autoscaler.getScaleUpTargetWithFunction(ctx, log, repo, owner, ownerType, enterprise, scaleTarget)
+	return autoscaler.getScaleUpTargetWithFunction(ctx, log, repo, owner, ownerType, enterprise, scaleTargets)
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTargetWithFunction(
-	ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, scaleTarget func(value string) (*ScaleTarget, error)) (*ScaleTarget, error) {
+	ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, scaleTargets func(value string) ([]ScaleTarget, error)) (*ScaleTarget, error) {
 
 	repositoryRunnerKey := owner + "/" + repo
 
+	// candidates accumulates the scale targets found at all the levels when a routing policy is enabled.
+	// Otherwise, the search stops at the first level that has a scale target.
+	var candidates []ScaleTarget
+
 	// Search for repository HRAs
-	if target, err := scaleTarget(repositoryRunnerKey); err != nil {
+	if targets, err := scaleTargets(repositoryRunnerKey); err != nil {
 		log.Error(err, "finding repository-wide runner", "repository", repositoryRunnerKey)
 		return nil, err
-	} else if target != nil {
+	} else if len(targets) > 0 {
 		log.Info("job scale up target is repository-wide runners", "repository", repo)
-		return target, nil
+
+		if !autoscaler.routingEnabled() {
+			return &targets[0], nil
+		}
+
+		candidates = appendScaleTargetCandidates(candidates, targets)
 	}
 
 	if ownerType == "User" {
 		log.V(1).Info("user repositories not supported", "owner", owner)
-		return nil, nil
+		return routableScaleTarget(candidates), nil
 	}
 
 	// Find the potential runner groups first to avoid spending API queries needless. Once/if GitHub improves an
@@ -431,30 +706,31 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTargetWithF
 
 	log.V(1).Info("groups", "groups", visibleGroups)
 
-	var t *ScaleTarget
-
 	traverseErr := visibleGroups.Traverse(func(rg simulator.RunnerGroup) (bool, error) {
 		key := scaleTargetKey(rg)
 
-		target, err := scaleTarget(key)
+		targets, err := scaleTargets(key)
 
 		if err != nil {
 			log.Error(err, "finding runner group", "enterprise", enterprise, "organization", owner, "repository", repo, "key", key)
 			return false, err
-		} else if target == nil {
+		} else if len(targets) == 0 {
 			return false, nil
 		}
 
-		t = target
+		candidates = appendScaleTargetCandidates(candidates, targets)
 		log.V(1).Info("job scale up target found", "enterprise", enterprise, "organization", owner, "repository", repo, "key", key)
 
-		return true, nil
+		// Keep searching for the other candidates in the remaining runner groups
+		return !autoscaler.routingEnabled(), nil
 	})
 
 	if traverseErr != nil {
 		return nil, err
 	}
 
+	t := routableScaleTarget(candidates)
+
 	if t == nil {
 		log.V(1).Info("no repository/organizational/enterprise runner found",
 			"repository", repositoryRunnerKey,
@@ -466,6 +742,38 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTargetWithF
 	return t, nil
 }
 
+// appendScaleTargetCandidates appends the targets to the candidates, skipping the HRAs that are already in the candidates.
+// The same HRA can be found twice, e.g. when a RunnerSet is indexed by both its organization and its runner group.
+func appendScaleTargetCandidates(candidates []ScaleTarget, targets []ScaleTarget) []ScaleTarget {
+TARGETS:
+	for _, t := range targets {
+		for _, c := range candidates {
+			if c.HorizontalRunnerAutoscaler.Namespace == t.HorizontalRunnerAutoscaler.Namespace && c.HorizontalRunnerAutoscaler.Name == t.HorizontalRunnerAutoscaler.Name {
+				continue TARGETS
+			}
+		}
+
+		candidates = append(candidates, t)
+	}
+
+	return candidates
+}
+
+// routableScaleTarget returns the first candidate, along with all the candidates the batch scaler can route the scale operation to.
+func routableScaleTarget(candidates []ScaleTarget) *ScaleTarget {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	t := candidates[0]
+
+	if len(candidates) > 1 {
+		t.candidates = candidates
+	}
+
+	return &t
+}
+
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getManagedRunnerGroupsFromHRAs(ctx context.Context, enterprise, org string) (*simulator.VisibleRunnerGroups, error) {
 	groups := simulator.NewVisibleRunnerGroups()
 	ns := autoscaler.Namespace
@@ -488,6 +796,11 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getManagedRunnerGroup
 	for _, hra := range hraList.Items {
 		var o, e, g string
 
+		// The webhook-based autoscaling is not supported for scale targets other than RunnerDeployment and RunnerSet
+		if !isRunnerScaleTargetRef(hra.Spec.ScaleTargetRef) {
+			continue
+		}
+
 		kind := hra.Spec.ScaleTargetRef.Kind
 		switch kind {
 		case "RunnerSet":
@@ -541,7 +854,9 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getManagedRunnerGroup
 	return groups, nil
 }
 
-func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleTarget(ctx context.Context, name string, labels []string) (*ScaleTarget, error) {
+// getJobScaleTargets returns the scale targets whose runners can run the workflow_job with the labels.
+// Only the first one is returned unless a routing policy is enabled.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleTargets(ctx context.Context, name string, labels []string, f func(v1alpha1.ScaleUpTrigger) bool) ([]ScaleTarget, error) {
 	hras, err := autoscaler.findHRAsByKey(ctx, name)
 	if err != nil {
 		return nil, err
@@ -549,44 +864,25 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleTarget(ctx
 
 	autoscaler.Log.V(1).Info(fmt.Sprintf("Found %d HRAs by key", len(hras)), "key", name)
 
+	var targets []ScaleTarget
+
 HRA:
 	for _, hra := range hras {
 		if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
 			continue
 		}
 
-		if len(hra.Spec.ScaleUpTriggers) > 1 {
-			autoscaler.Log.V(1).Info("Skipping this HRA as it has too many ScaleUpTriggers to be used in workflow_job based scaling", "hra", hra.Name)
-			continue
-		}
-
 		if len(hra.Spec.ScaleUpTriggers) == 0 {
 			autoscaler.Log.V(1).Info("Skipping this HRA as it has no ScaleUpTriggers configured", "hra", hra.Name)
 			continue
 		}
 
-		scaleUpTrigger := hra.Spec.ScaleUpTriggers[0]
-
-		if scaleUpTrigger.GitHubEvent == nil {
-			autoscaler.Log.V(1).Info("Skipping this HRA as it has no `githubEvent` scale trigger configured", "hra", hra.Name)
-
-			continue
-		}
-
-		if scaleUpTrigger.GitHubEvent.WorkflowJob == nil {
-			autoscaler.Log.V(1).Info("Skipping this HRA as it has no `githubEvent.workflowJob` scale trigger configured", "hra", hra.Name)
-
+		if !isRunnerScaleTargetRef(hra.Spec.ScaleTargetRef) {
+			autoscaler.Log.V(1).Info("Skipping this HRA as its scale target is neither RunnerDeployment nor RunnerSet", "hra", hra.Name)
 			continue
 		}
 
-		duration := scaleUpTrigger.Duration
-		if duration.Duration <= 0 {
-			// Try to release the reserved capacity after at least 10 minutes by default,
-			// we won't end up in the reserved capacity remained forever in case GitHub somehow stopped sending us "completed" workflow_job events.
-			// GitHub usually send us those but nothing is 100% guaranteed, e.g. in case of something went wrong on GitHub :)
-			// Probably we'd better make this configurable via custom resources in the future?
-			duration.Duration = 10 * time.Minute
-		}
+		var runnerLabels *runnerLabelMatcher
 
 		switch hra.Spec.ScaleTargetRef.Kind {
 		case "RunnerSet":
@@ -596,30 +892,7 @@ HRA:
 				return nil, err
 			}
 
-			// Ensure that the RunnerSet-managed runners have all the labels requested by the workflow_job.
-			for _, l := range labels {
-				var matched bool
-
-				// ignore "self-hosted" label as all instance here are self-hosted
-				if l == "self-hosted" {
-					continue
-				}
-
-				// TODO labels related to OS and architecture needs to be explicitly declared or the current implementation will not be able to find them.
-
-				for _, l2 := range rs.Spec.Labels {
-					if l == l2 {
-						matched = true
-						break
-					}
-				}
-
-				if !matched {
-					continue HRA
-				}
-			}
-
-			return &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Duration: duration}}, nil
+			runnerLabels = newRunnerLabelMatcher(rs.Spec.RunnerConfig, rs.Spec.Template.Spec.NodeSelector)
 		case "RunnerDeployment", "":
 			var rd v1alpha1.RunnerDeployment
 
@@ -627,44 +900,89 @@ HRA:
 				return nil, err
 			}
 
-			// Ensure that the RunnerDeployment-managed runners have all the labels requested by the workflow_job.
-			for _, l := range labels {
-				var matched bool
+			runnerLabels = newRunnerLabelMatcher(rd.Spec.Template.Spec.RunnerConfig, rd.Spec.Template.Spec.NodeSelector)
+		default:
+			return nil, fmt.Errorf("unsupported scaleTargetRef.kind: %v", hra.Spec.ScaleTargetRef.Kind)
+		}
 
-				// ignore "self-hosted" label as all instance here are self-hosted
-				if l == "self-hosted" {
-					continue
-				}
+		// Ensure that the scale target's runners have all the labels requested by the workflow_job.
+		// Labels related to OS and architecture need to be either declared explicitly or derived via spec.labelMatching.
+		if !runnerLabels.matches(labels) {
+			continue HRA
+		}
 
-				// TODO labels related to OS and architecture needs to be explicitly declared or the current implementation will not be able to find them.
+		// Use the first scale-up trigger that matches the workflow_job, so that
+		// the user can configure a different amount and duration for each kind of jobs.
+		for i, scaleUpTrigger := range hra.Spec.ScaleUpTriggers {
+			if !f(scaleUpTrigger) {
+				continue
+			}
 
-				for _, l2 := range rd.Spec.Template.Spec.Labels {
-					if l == l2 {
-						matched = true
-						break
-					}
-				}
+			amount := scaleUpTrigger.Amount
+			if amount == 0 {
+				amount = 1
+			}
 
-				if !matched {
-					continue HRA
-				}
+			targets = append(targets, ScaleTarget{
+				HorizontalRunnerAutoscaler: hra,
+				ScaleUpTrigger: v1alpha1.ScaleUpTrigger{
+					Amount:   amount,
+					Duration: getScaleUpTriggerDuration(scaleUpTrigger),
+				},
+				scaleUpTriggerIndex: i,
+			})
+
+			if !autoscaler.routingEnabled() {
+				return targets, nil
 			}
 
-			return &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Duration: duration}}, nil
-		default:
-			return nil, fmt.Errorf("unsupported scaleTargetRef.kind: %v", hra.Spec.ScaleTargetRef.Kind)
+			continue HRA
 		}
+
+		autoscaler.Log.V(1).Info("Skipping this HRA as it has no `githubEvent.workflowJob` scale trigger that matches the workflow_job", "hra", hra.Name)
 	}
 
-	return nil, nil
+	return targets, nil
 }
 
-func getValidCapacityReservations(autoscaler *v1alpha1.HorizontalRunnerAutoscaler) []v1alpha1.CapacityReservation {
+func getValidCapacityReservationsAt(autoscaler *v1alpha1.HorizontalRunnerAutoscaler, now time.Time) []v1alpha1.CapacityReservation {
+	return filterValidCapacityReservations(getCapacityReservations(autoscaler), now)
+}
+
+// getCapacityReservations returns all the capacity reservations of the HRA,
+// including the ones left in the spec by older versions of the webhook-based autoscaler.
+// The ones in the spec are moved to the status by the next batch of the webhook-based autoscaler,
+// and a reservation already moved to the status is counted only once until it's removed from the spec.
+func getCapacityReservations(autoscaler *v1alpha1.HorizontalRunnerAutoscaler) []v1alpha1.CapacityReservation {
+	if len(autoscaler.Spec.CapacityReservations) == 0 {
+		return autoscaler.Status.CapacityReservations
+	}
+
 	var capacityReservations []v1alpha1.CapacityReservation
 
-	now := time.Now()
+	for _, r := range autoscaler.Spec.CapacityReservations {
+		if !containsCapacityReservation(autoscaler.Status.CapacityReservations, r) {
+			capacityReservations = append(capacityReservations, r)
+		}
+	}
 
-	for _, reservation := range autoscaler.Spec.CapacityReservations {
+	return append(capacityReservations, autoscaler.Status.CapacityReservations...)
+}
+
+func containsCapacityReservation(reservations []v1alpha1.CapacityReservation, r v1alpha1.CapacityReservation) bool {
+	for _, c := range reservations {
+		if reflect.DeepEqual(c, r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func filterValidCapacityReservations(reservations []v1alpha1.CapacityReservation, now time.Time) []v1alpha1.CapacityReservation {
+	var capacityReservations []v1alpha1.CapacityReservation
+
+	for _, reservation := range reservations {
 		if reservation.ExpirationTime.Time.After(now) {
 			capacityReservations = append(capacityReservations, reservation)
 		}
@@ -680,79 +998,111 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) SetupWithManager(mgr
 	}
 
 	autoscaler.Recorder = mgr.GetEventRecorderFor(name)
+	autoscaler.apiReader = mgr.GetAPIReader()
 
-	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &v1alpha1.HorizontalRunnerAutoscaler{}, scaleTargetKey, func(rawObj client.Object) []string {
-		hra := rawObj.(*v1alpha1.HorizontalRunnerAutoscaler)
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &v1alpha1.HorizontalRunnerAutoscaler{}, scaleTargetKey, autoscaler.indexScaleTargetKeys); err != nil {
+		return err
+	}
 
-		if hra.Spec.ScaleTargetRef.Name == "" {
-			autoscaler.Log.V(1).Info(fmt.Sprintf("scale target ref name not set for hra %s", hra.Name))
-			return nil
+	if autoscaler.QueueJournalName != "" {
+		autoscaler.journal = &scaleOperationJournal{
+			reader:    autoscaler.apiReader,
+			client:    mgr.GetClient(),
+			namespace: autoscaler.QueueJournalNamespace,
+			name:      autoscaler.QueueJournalName,
+			shards:    scaleOperationJournalShards,
+			limit:     autoscaler.queueLimit(),
 		}
 
-		switch hra.Spec.ScaleTargetRef.Kind {
-		case "", "RunnerDeployment":
-			var rd v1alpha1.RunnerDeployment
-			if err := autoscaler.Client.Get(context.Background(), types.NamespacedName{Namespace: hra.Namespace, Name: hra.Spec.ScaleTargetRef.Name}, &rd); err != nil {
-				autoscaler.Log.V(1).Info(fmt.Sprintf("RunnerDeployment not found with scale target ref name %s for hra %s", hra.Spec.ScaleTargetRef.Name, hra.Name))
-				return nil
-			}
+		batchScaler := newBatchScaler(context.Background(), autoscaler.Client, autoscaler.Log)
+		batchScaler.routingPolicy = autoscaler.RoutingPolicy
+		batchScaler.reader = autoscaler.apiReader
+
+		if err := mgr.Add(&scaleOperationJournalWorker{
+			journal:     autoscaler.journal,
+			batchScaler: batchScaler,
+			log:         autoscaler.Log.WithName("journal"),
+		}); err != nil {
+			return err
+		}
+	}
 
-			keys := []string{}
-			if rd.Spec.Template.Spec.Repository != "" {
-				keys = append(keys, rd.Spec.Template.Spec.Repository) // Repository runners
-			}
-			if rd.Spec.Template.Spec.Organization != "" {
-				if group := rd.Spec.Template.Spec.Group; group != "" {
-					keys = append(keys, organizationalRunnerGroupKey(rd.Spec.Template.Spec.Organization, rd.Spec.Template.Spec.Group)) // Organization runner groups
-				} else {
-					keys = append(keys, rd.Spec.Template.Spec.Organization) // Organization runners
-				}
-			}
-			if enterprise := rd.Spec.Template.Spec.Enterprise; enterprise != "" {
-				if group := rd.Spec.Template.Spec.Group; group != "" {
-					keys = append(keys, enterpriseRunnerGroupKey(enterprise, rd.Spec.Template.Spec.Group)) // Enterprise runner groups
-				} else {
-					keys = append(keys, enterpriseKey(enterprise)) // Enterprise runners
-				}
-			}
-			autoscaler.Log.V(2).Info(fmt.Sprintf("HRA keys indexed for HRA %s: %v", hra.Name, keys))
-			return keys
-		case "RunnerSet":
-			var rs v1alpha1.RunnerSet
-			if err := autoscaler.Client.Get(context.Background(), types.NamespacedName{Namespace: hra.Namespace, Name: hra.Spec.ScaleTargetRef.Name}, &rs); err != nil {
-				autoscaler.Log.V(1).Info(fmt.Sprintf("RunnerSet not found with scale target ref name %s for hra %s", hra.Spec.ScaleTargetRef.Name, hra.Name))
-				return nil
-			}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.HorizontalRunnerAutoscaler{}).
+		Named(name).
+		Complete(autoscaler)
+}
 
-			keys := []string{}
-			if rs.Spec.Repository != "" {
-				keys = append(keys, rs.Spec.Repository) // Repository runners
-			}
-			if rs.Spec.Organization != "" {
-				keys = append(keys, rs.Spec.Organization) // Organization runners
-				if group := rs.Spec.Group; group != "" {
-					keys = append(keys, organizationalRunnerGroupKey(rs.Spec.Organization, rs.Spec.Group)) // Organization runner groups
-				}
+// indexScaleTargetKeys returns the keys of the scaleTargetKey index of the HRA, which are the repository, organization, runner group,
+// and enterprise of the runners of its scale target, so that the HRAs to scale can be found by a webhook event.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) indexScaleTargetKeys(rawObj client.Object) []string {
+	hra := rawObj.(*v1alpha1.HorizontalRunnerAutoscaler)
+
+	if hra.Spec.ScaleTargetRef.Name == "" {
+		autoscaler.Log.V(1).Info(fmt.Sprintf("scale target ref name not set for hra %s", hra.Name))
+		return nil
+	}
+
+	if !isRunnerScaleTargetRef(hra.Spec.ScaleTargetRef) {
+		return nil
+	}
+
+	switch hra.Spec.ScaleTargetRef.Kind {
+	case "", "RunnerDeployment":
+		var rd v1alpha1.RunnerDeployment
+		if err := autoscaler.Client.Get(context.Background(), types.NamespacedName{Namespace: hra.Namespace, Name: hra.Spec.ScaleTargetRef.Name}, &rd); err != nil {
+			autoscaler.Log.V(1).Info(fmt.Sprintf("RunnerDeployment not found with scale target ref name %s for hra %s", hra.Spec.ScaleTargetRef.Name, hra.Name))
+			return nil
+		}
+
+		keys := []string{}
+		if rd.Spec.Template.Spec.Repository != "" {
+			keys = append(keys, rd.Spec.Template.Spec.Repository) // Repository runners
+		}
+		if rd.Spec.Template.Spec.Organization != "" {
+			if group := rd.Spec.Template.Spec.Group; group != "" {
+				keys = append(keys, organizationalRunnerGroupKey(rd.Spec.Template.Spec.Organization, rd.Spec.Template.Spec.Group)) // Organization runner groups
+			} else {
+				keys = append(keys, rd.Spec.Template.Spec.Organization) // Organization runners
 			}
-			if enterprise := rs.Spec.Enterprise; enterprise != "" {
+		}
+		if enterprise := rd.Spec.Template.Spec.Enterprise; enterprise != "" {
+			if group := rd.Spec.Template.Spec.Group; group != "" {
+				keys = append(keys, enterpriseRunnerGroupKey(enterprise, rd.Spec.Template.Spec.Group)) // Enterprise runner groups
+			} else {
 				keys = append(keys, enterpriseKey(enterprise)) // Enterprise runners
-				if group := rs.Spec.Group; group != "" {
-					keys = append(keys, enterpriseRunnerGroupKey(enterprise, rs.Spec.Group)) // Enterprise runner groups
-				}
 			}
-			autoscaler.Log.V(2).Info(fmt.Sprintf("HRA keys indexed for HRA %s: %v", hra.Name, keys))
-			return keys
+		}
+		autoscaler.Log.V(2).Info(fmt.Sprintf("HRA keys indexed for HRA %s: %v", hra.Name, keys))
+		return keys
+	case "RunnerSet":
+		var rs v1alpha1.RunnerSet
+		if err := autoscaler.Client.Get(context.Background(), types.NamespacedName{Namespace: hra.Namespace, Name: hra.Spec.ScaleTargetRef.Name}, &rs); err != nil {
+			autoscaler.Log.V(1).Info(fmt.Sprintf("RunnerSet not found with scale target ref name %s for hra %s", hra.Spec.ScaleTargetRef.Name, hra.Name))
+			return nil
 		}
 
-		return nil
-	}); err != nil {
-		return err
+		keys := []string{}
+		if rs.Spec.Repository != "" {
+			keys = append(keys, rs.Spec.Repository) // Repository runners
+		}
+		if rs.Spec.Organization != "" {
+			keys = append(keys, rs.Spec.Organization) // Organization runners
+			if group := rs.Spec.Group; group != "" {
+				keys = append(keys, organizationalRunnerGroupKey(rs.Spec.Organization, rs.Spec.Group)) // Organization runner groups
+			}
+		}
+		if enterprise := rs.Spec.Enterprise; enterprise != "" {
+			keys = append(keys, enterpriseKey(enterprise)) // Enterprise runners
+			if group := rs.Spec.Group; group != "" {
+				keys = append(keys, enterpriseRunnerGroupKey(enterprise, rs.Spec.Group)) // Enterprise runner groups
+			}
+		}
+		autoscaler.Log.V(2).Info(fmt.Sprintf("HRA keys indexed for HRA %s: %v", hra.Name, keys))
+		return keys
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.HorizontalRunnerAutoscaler{}).
-		Named(name).
-		Complete(autoscaler)
+	return nil
 }
 
 func enterpriseKey(name string) string {
@@ -0,0 +1,70 @@
+package actionssummerwindnet
+
+import (
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/actionsglob"
+	"github.com/google/go-github/v47/github"
+)
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchCheckRunEvent(event *github.CheckRunEvent) func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+	return func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+		g := scaleUpTrigger.GitHubEvent
+
+		if g == nil {
+			return false
+		}
+
+		cr := g.CheckRun
+
+		if cr == nil {
+			return false
+		}
+
+		if !matchTriggerConditionAgainstEvent(cr.Types, event.Action) {
+			return false
+		}
+
+		if cr.Status != "" && (event.CheckRun == nil || event.CheckRun.Status == nil || *event.CheckRun.Status != cr.Status) {
+			return false
+		}
+
+		if len(cr.Names) > 0 {
+			if event.CheckRun == nil || !matchGlobPatterns(cr.Names, event.CheckRun.GetName()) {
+				return false
+			}
+		}
+
+		if len(cr.Repositories) > 0 {
+			var matched bool
+
+			for _, repository := range cr.Repositories {
+				if repository == event.Repo.GetName() {
+					matched = true
+					break
+				}
+			}
+
+			if !matched {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// matchGlobPatterns returns true when s matches any of the GitHub Actions glob patterns.
+// Empty patterns are ignored as actionsglob.Match doesn't accept them.
+func matchGlobPatterns(patterns []string, s string) bool {
+	for _, pat := range patterns {
+		if pat == "" {
+			continue
+		}
+
+		if actionsglob.Match(pat, s) {
+			return true
+		}
+	}
+
+	return false
+}
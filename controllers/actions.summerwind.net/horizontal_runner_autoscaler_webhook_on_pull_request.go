@@ -0,0 +1,34 @@
+package actionssummerwindnet
+
+import (
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-github/v47/github"
+)
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchPullRequestEvent(event *github.PullRequestEvent) func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+	return func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+		g := scaleUpTrigger.GitHubEvent
+
+		if g == nil {
+			return false
+		}
+
+		pr := g.PullRequest
+
+		if pr == nil {
+			return false
+		}
+
+		if !matchTriggerConditionAgainstEvent(pr.Types, event.Action) {
+			return false
+		}
+
+		if len(pr.Branches) > 0 {
+			if event.PullRequest == nil || event.PullRequest.Base == nil || !matchGlobPatterns(pr.Branches, event.PullRequest.Base.GetRef()) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
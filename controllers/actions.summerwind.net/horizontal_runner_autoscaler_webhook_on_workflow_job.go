@@ -0,0 +1,59 @@
+package actionssummerwindnet
+
+import (
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-github/v47/github"
+)
+
+// MatchWorkflowJobEvent returns a function that returns true when the scale-up trigger
+// has a workflowJob condition that matches the workflow_job event.
+// workflowName is passed separately as it needs to be parsed from the raw payload.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchWorkflowJobEvent(event *github.WorkflowJobEvent, workflowName string) func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+	return func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+		g := scaleUpTrigger.GitHubEvent
+
+		if g == nil {
+			return false
+		}
+
+		wj := g.WorkflowJob
+
+		if wj == nil {
+			return false
+		}
+
+		if len(wj.Labels) > 0 {
+			jobLabels := map[string]struct{}{}
+			for _, l := range event.GetWorkflowJob().Labels {
+				jobLabels[l] = struct{}{}
+			}
+
+			for _, l := range wj.Labels {
+				if _, ok := jobLabels[l]; !ok {
+					return false
+				}
+			}
+		}
+
+		if len(wj.Repositories) > 0 {
+			var matched bool
+
+			for _, repository := range wj.Repositories {
+				if repository == event.Repo.GetName() {
+					matched = true
+					break
+				}
+			}
+
+			if !matched {
+				return false
+			}
+		}
+
+		if len(wj.WorkflowNames) > 0 && !matchGlobPatterns(wj.WorkflowNames, workflowName) {
+			return false
+		}
+
+		return true
+	}
+}
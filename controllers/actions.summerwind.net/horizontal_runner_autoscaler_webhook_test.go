@@ -41,6 +41,279 @@ func TestWebhookPing(t *testing.T) {
 	)
 }
 
+func TestWebhookCheckRun(t *testing.T) {
+	setupTest := func() github.CheckRunEvent {
+		f, err := os.Open("testdata/org_webhook_check_run_payload.json")
+		if err != nil {
+			t.Fatalf("could not open the fixture: %s", err)
+		}
+		defer f.Close()
+		var e github.CheckRunEvent
+		if err := json.NewDecoder(f).Decode(&e); err != nil {
+			t.Fatalf("invalid json: %s", err)
+		}
+
+		return e
+	}
+	newInitObjs := func(checkRun actionsv1alpha1.CheckRunSpec) []runtime.Object {
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							CheckRun: &checkRun,
+						},
+						Amount: 2,
+					},
+				},
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Organization: "MYORG",
+						},
+					},
+				},
+			},
+		}
+
+		return []runtime.Object{hra, rd}
+	}
+	t.Run("Successful", func(t *testing.T) {
+		e := setupTest()
+
+		testServerWithInitObjs(t,
+			"check_run",
+			&e,
+			200,
+			"scaled test-name by 2",
+			newInitObjs(actionsv1alpha1.CheckRunSpec{
+				Types:        []string{"created"},
+				Status:       "queued",
+				Names:        []string{"valid*"},
+				Repositories: []string{"MYREPO"},
+			}),
+		)
+	})
+	t.Run("WrongType", func(t *testing.T) {
+		e := setupTest()
+
+		testServerWithInitObjs(t,
+			"check_run",
+			&e,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			newInitObjs(actionsv1alpha1.CheckRunSpec{
+				Types: []string{"completed"},
+			}),
+		)
+	})
+	t.Run("WrongName", func(t *testing.T) {
+		e := setupTest()
+
+		testServerWithInitObjs(t,
+			"check_run",
+			&e,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			newInitObjs(actionsv1alpha1.CheckRunSpec{
+				Names: []string{"build*", "!validate"},
+			}),
+		)
+	})
+	t.Run("WrongNameWithLeadingWildcard", func(t *testing.T) {
+		e := setupTest()
+
+		testServerWithInitObjs(t,
+			"check_run",
+			&e,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			newInitObjs(actionsv1alpha1.CheckRunSpec{
+				Names: []string{"*-release"},
+			}),
+		)
+	})
+	t.Run("WrongRepository", func(t *testing.T) {
+		e := setupTest()
+
+		testServerWithInitObjs(t,
+			"check_run",
+			&e,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			newInitObjs(actionsv1alpha1.CheckRunSpec{
+				Repositories: []string{"OTHERREPO"},
+			}),
+		)
+	})
+}
+
+func TestWebhookPullRequest(t *testing.T) {
+	newEvent := func(action, baseRef string) *github.PullRequestEvent {
+		return &github.PullRequestEvent{
+			Action: github.String(action),
+			PullRequest: &github.PullRequest{
+				Base: &github.PullRequestBranch{
+					Ref: github.String(baseRef),
+				},
+			},
+			Repo: &github.Repository{
+				Name: github.String("myrepo"),
+				Owner: &github.User{
+					Login: github.String("myorg"),
+					Type:  github.String("Organization"),
+				},
+			},
+		}
+	}
+	initObjs := []runtime.Object{
+		&actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							PullRequest: &actionsv1alpha1.PullRequestSpec{
+								Types:    []string{"opened", "synchronize"},
+								Branches: []string{"main", "release-*"},
+							},
+						},
+					},
+				},
+			},
+		},
+		&actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Repository: "myorg/myrepo",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("Successful", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"pull_request",
+			newEvent("synchronize", "release-1.0"),
+			200,
+			"scaled test-name by 1",
+			initObjs,
+		)
+	})
+	t.Run("WrongType", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"pull_request",
+			newEvent("closed", "main"),
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			initObjs,
+		)
+	})
+	t.Run("WrongBranch", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"pull_request",
+			newEvent("opened", "feature"),
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			initObjs,
+		)
+	})
+}
+
+func TestWebhookPush(t *testing.T) {
+	event := &github.PushEvent{
+		Repo: &github.PushEventRepository{
+			Name: github.String("myrepo"),
+			Owner: &github.User{
+				Login: github.String("myorg"),
+				Type:  github.String("Organization"),
+			},
+		},
+	}
+	newInitObjs := func(trigger actionsv1alpha1.GitHubEventScaleUpTriggerSpec) []runtime.Object {
+		return []runtime.Object{
+			&actionsv1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-name",
+				},
+				Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+						Name: "test-name",
+					},
+					ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+						{
+							GitHubEvent: &trigger,
+						},
+					},
+				},
+			},
+			&actionsv1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-name",
+				},
+				Spec: actionsv1alpha1.RunnerDeploymentSpec{
+					Template: actionsv1alpha1.RunnerTemplate{
+						Spec: actionsv1alpha1.RunnerSpec{
+							RunnerConfig: actionsv1alpha1.RunnerConfig{
+								Repository: "myorg/myrepo",
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("Successful", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"push",
+			event,
+			200,
+			"scaled test-name by 1",
+			newInitObjs(actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+				Push: &actionsv1alpha1.PushSpec{},
+			}),
+		)
+	})
+	t.Run("NoPushTrigger", func(t *testing.T) {
+		testServerWithInitObjs(t,
+			"push",
+			event,
+			200,
+			"no horizontalrunnerautoscaler to scale for this github event",
+			newInitObjs(actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+				PullRequest: &actionsv1alpha1.PullRequestSpec{},
+			}),
+		)
+	})
+}
+
 func TestWebhookWorkflowJob(t *testing.T) {
 	setupTest := func() github.WorkflowJobEvent {
 		f, err := os.Open("testdata/org_webhook_workflow_job_payload.json")
@@ -101,6 +374,55 @@ func TestWebhookWorkflowJob(t *testing.T) {
 			initObjs,
 		)
 	})
+	t.Run("InProgress", func(t *testing.T) {
+		e := setupTest()
+		e.Action = github.String("in_progress")
+		e.WorkflowJob.RunnerName = github.String("test-name-abcde")
+
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+						},
+					},
+				},
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Organization: "MYORG",
+							Labels:       []string{"label1"},
+						},
+					},
+				},
+			},
+		}
+
+		initObjs := []runtime.Object{hra, rd}
+
+		testServerWithInitObjs(t,
+			"workflow_job",
+			&e,
+			200,
+			"assigned runner test-name-abcde to workflow job 1234567890 on test-name",
+			initObjs,
+		)
+	})
 	t.Run("WrongLabels", func(t *testing.T) {
 		e := setupTest()
 		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
@@ -147,6 +469,78 @@ func TestWebhookWorkflowJob(t *testing.T) {
 			initObjs,
 		)
 	})
+	t.Run("MultipleTriggers", func(t *testing.T) {
+		e := setupTest()
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{
+								Labels: []string{"label2"},
+							},
+						},
+						Amount: 3,
+					},
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{
+								Repositories: []string{"OTHERREPO"},
+							},
+						},
+						Amount: 4,
+					},
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{
+								Labels:       []string{"label1"},
+								Repositories: []string{"MYREPO"},
+							},
+						},
+						Amount: 2,
+					},
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+						},
+						Amount: 5,
+					},
+				},
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Organization: "MYORG",
+							Labels:       []string{"label1", "label2"},
+						},
+					},
+				},
+			},
+		}
+
+		initObjs := []runtime.Object{hra, rd}
+
+		testServerWithInitObjs(t,
+			"workflow_job",
+			&e,
+			200,
+			"scaled test-name by 2",
+			initObjs,
+		)
+	})
 	// This test verifies that the old way of matching labels doesn't work anymore
 	t.Run("OldLabels", func(t *testing.T) {
 		e := setupTest()
@@ -361,6 +755,115 @@ func TestWebhookWorkflowJobWithSelfHostedLabel(t *testing.T) {
 	})
 }
 
+func TestMatchGlobPatterns(t *testing.T) {
+	testcases := []struct {
+		patterns []string
+		s        string
+		want     bool
+	}{
+		{patterns: []string{"main", "release-*"}, s: "release-1.0", want: true},
+		{patterns: []string{"main", "release-*"}, s: "feature", want: false},
+		{patterns: []string{"*-release"}, s: "v1-release", want: true},
+		// A leading wildcard pattern doesn't match a name without its literal part
+		{patterns: []string{"*-release"}, s: "main", want: false},
+		{patterns: []string{"!*-release"}, s: "main", want: true},
+		{patterns: []string{""}, s: "main", want: false},
+	}
+
+	for _, tc := range testcases {
+		if got := matchGlobPatterns(tc.patterns, tc.s); got != tc.want {
+			t.Errorf("%v against %s: want %v, got %v", tc.patterns, tc.s, tc.want, got)
+		}
+	}
+}
+
+func TestMatchWorkflowJobEvent(t *testing.T) {
+	event := &github.WorkflowJobEvent{
+		WorkflowJob: &github.WorkflowJob{
+			Labels: []string{"self-hosted", "linux", "gpu"},
+		},
+		Repo: &github.Repository{
+			Name: github.String("myrepo"),
+		},
+	}
+
+	testcases := []struct {
+		name string
+		spec *actionsv1alpha1.WorkflowJobSpec
+		want bool
+	}{
+		{
+			name: "no workflowJob trigger",
+			want: false,
+		},
+		{
+			name: "empty",
+			spec: &actionsv1alpha1.WorkflowJobSpec{},
+			want: true,
+		},
+		{
+			name: "labels",
+			spec: &actionsv1alpha1.WorkflowJobSpec{Labels: []string{"gpu", "linux"}},
+			want: true,
+		},
+		{
+			name: "missing label",
+			spec: &actionsv1alpha1.WorkflowJobSpec{Labels: []string{"gpu", "windows"}},
+			want: false,
+		},
+		{
+			name: "repositories",
+			spec: &actionsv1alpha1.WorkflowJobSpec{Repositories: []string{"other", "myrepo"}},
+			want: true,
+		},
+		{
+			name: "wrong repository",
+			spec: &actionsv1alpha1.WorkflowJobSpec{Repositories: []string{"other"}},
+			want: false,
+		},
+		{
+			name: "workflow names",
+			spec: &actionsv1alpha1.WorkflowJobSpec{WorkflowNames: []string{"lint", "build-*"}},
+			want: true,
+		},
+		{
+			name: "wrong workflow name",
+			spec: &actionsv1alpha1.WorkflowJobSpec{WorkflowNames: []string{"deploy*"}},
+			want: false,
+		},
+		{
+			name: "workflow name with leading wildcard",
+			spec: &actionsv1alpha1.WorkflowJobSpec{WorkflowNames: []string{"*-linux"}},
+			want: true,
+		},
+		{
+			name: "wrong workflow name with leading wildcard",
+			spec: &actionsv1alpha1.WorkflowJobSpec{WorkflowNames: []string{"*-windows"}},
+			want: false,
+		},
+	}
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			trigger := actionsv1alpha1.ScaleUpTrigger{
+				GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+					WorkflowJob: tc.spec,
+				},
+			}
+
+			got := webhook.MatchWorkflowJobEvent(event, "build-linux")(trigger)
+
+			if got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
 func TestGetRequest(t *testing.T) {
 	hra := HorizontalRunnerAutoscalerGitHubWebhook{}
 	request, _ := http.NewRequest(http.MethodGet, "/", nil)
@@ -394,9 +897,21 @@ func TestGetValidCapacityReservations(t *testing.T) {
 				},
 			},
 		},
+		Status: actionsv1alpha1.HorizontalRunnerAutoscalerStatus{
+			CapacityReservations: []actionsv1alpha1.CapacityReservation{
+				{
+					ExpirationTime: metav1.Time{Time: now.Add(-time.Second)},
+					Replicas:       4,
+				},
+				{
+					ExpirationTime: metav1.Time{Time: now.Add(time.Second)},
+					Replicas:       5,
+				},
+			},
+		},
 	}
 
-	revs := getValidCapacityReservations(hra)
+	revs := getValidCapacityReservationsAt(hra, now)
 
 	var count int
 
@@ -404,13 +919,36 @@ func TestGetValidCapacityReservations(t *testing.T) {
 		count += r.Replicas
 	}
 
-	want := 3
+	want := 8
 
 	if count != want {
 		t.Errorf("want %d, got %d", want, count)
 	}
 }
 
+func TestGetCapacityReservationsMovedToStatus(t *testing.T) {
+	now := time.Now()
+
+	moved := actionsv1alpha1.CapacityReservation{
+		ExpirationTime: metav1.Time{Time: now.Add(time.Second)},
+		Replicas:       3,
+	}
+
+	hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+		Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+			CapacityReservations: []actionsv1alpha1.CapacityReservation{moved},
+		},
+		Status: actionsv1alpha1.HorizontalRunnerAutoscalerStatus{
+			CapacityReservations: []actionsv1alpha1.CapacityReservation{moved},
+		},
+	}
+
+	// The reservation yet to be removed from the spec is counted only once
+	if got := getCapacityReservations(hra); len(got) != 1 {
+		t.Errorf("want 1, got %d", len(got))
+	}
+}
+
 func installTestLogger(webhook *HorizontalRunnerAutoscalerGitHubWebhook) *bytes.Buffer {
 	logs := &bytes.Buffer{}
 
@@ -0,0 +1,339 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultScaleUpStabilizationWindow   = 0
+	defaultScaleDownStabilizationWindow = 5 * time.Minute
+)
+
+// scalingBehaviorResult is the outcome of applying HRA's spec.behavior to the recommended desired replicas.
+type scalingBehaviorResult struct {
+	desiredReplicas int
+	recommendations []v1alpha1.ScaleRecommendation
+	scaleEvents     []v1alpha1.ScaleEvent
+}
+
+// applyScalingBehavior stabilizes and rate-limits the recommended desired replicas according to the behavior,
+// in the same way as Kubernetes HPA does.
+// See https://kubernetes.io/docs/tasks/run-application/horizontal-pod-autoscale/#configurable-scaling-behavior
+//
+// The returned recommendations and scale events are supposed to be saved in HRA's status
+// so that they can be used in the next reconciliation.
+func applyScalingBehavior(now time.Time, behavior v1alpha1.ScalingBehavior, status v1alpha1.HorizontalRunnerAutoscalerStatus, minReplicas, recommended int) (*scalingBehaviorResult, error) {
+	upWindow := getStabilizationWindow(behavior.ScaleUp, defaultScaleUpStabilizationWindow)
+	downWindow := getStabilizationWindow(behavior.ScaleDown, defaultScaleDownStabilizationWindow)
+
+	longestWindow := upWindow
+	if downWindow > longestWindow {
+		longestWindow = downWindow
+	}
+
+	recommendations := appendRecommendation(status.Recommendations, now, recommended)
+	// Drop recommendations that are not in effect within any of the windows anymore.
+	recommendations = recommendationsWithinWindow(recommendations, now, longestWindow)
+
+	if status.DesiredReplicas == nil {
+		return &scalingBehaviorResult{
+			desiredReplicas: recommended,
+			recommendations: recommendations,
+		}, nil
+	}
+
+	current := *status.DesiredReplicas
+
+	// The lowest recommendation within the scale-up window is the upper bound of the scale-up,
+	// and the highest recommendation within the scale-down window is the lower bound of the scale-down.
+	upRecommendation := recommended
+	downRecommendation := recommended
+
+	for _, r := range recommendationsWithinWindow(recommendations, now, upWindow) {
+		if r.Replicas < upRecommendation {
+			upRecommendation = r.Replicas
+		}
+	}
+
+	for _, r := range recommendationsWithinWindow(recommendations, now, downWindow) {
+		if r.Replicas > downRecommendation {
+			downRecommendation = r.Replicas
+		}
+	}
+
+	desired := current
+	if desired < upRecommendation {
+		desired = upRecommendation
+	}
+	if desired > downRecommendation {
+		desired = downRecommendation
+	}
+
+	if desired > current {
+		limit, err := calculateScaleUpLimit(current, status.ScaleEvents, now, behavior.ScaleUp)
+		if err != nil {
+			return nil, err
+		}
+
+		if desired > limit {
+			desired = limit
+		}
+	} else if desired < current {
+		limit, err := calculateScaleDownLimit(current, status.ScaleEvents, now, behavior.ScaleDown)
+		if err != nil {
+			return nil, err
+		}
+
+		if limit < minReplicas {
+			limit = minReplicas
+		}
+
+		if desired < limit {
+			desired = limit
+		}
+	}
+
+	scaleEvents := pruneScaleEvents(status.ScaleEvents, now, getLongestPolicyPeriod(behavior))
+
+	if desired != current {
+		scaleEvents = append(scaleEvents, v1alpha1.ScaleEvent{
+			Timestamp:     metav1.Time{Time: now},
+			ReplicaChange: desired - current,
+		})
+	}
+
+	return &scalingBehaviorResult{
+		desiredReplicas: desired,
+		recommendations: recommendations,
+		scaleEvents:     scaleEvents,
+	}, nil
+}
+
+// clampScaleEvent replaces the scale event recorded by applyScalingBehavior with the replica change actually applied,
+// after e.g. the capacity limit lowered the desired replicas further.
+// Otherwise the runners that were never added would keep consuming the scale-up policies until the period passes.
+func (r *scalingBehaviorResult) clampScaleEvent(now time.Time, current, applied int) {
+	events := r.scaleEvents
+
+	// applyScalingBehavior records a scale event only when it changes the desired replicas
+	if r.desiredReplicas != current && len(events) > 0 {
+		events = events[:len(events)-1]
+	}
+
+	scaleEvents := make([]v1alpha1.ScaleEvent, 0, len(events)+1)
+	scaleEvents = append(scaleEvents, events...)
+
+	if applied != current {
+		scaleEvents = append(scaleEvents, v1alpha1.ScaleEvent{
+			Timestamp:     metav1.Time{Time: now},
+			ReplicaChange: applied - current,
+		})
+	}
+
+	if len(scaleEvents) == 0 {
+		scaleEvents = nil
+	}
+
+	r.scaleEvents = scaleEvents
+}
+
+// getStabilizationWindow returns zero when the rules are omitted,
+// so that the direction is not stabilized at all, or is delayed by ScaleDownDelaySecondsAfterScaleUp instead.
+func getStabilizationWindow(rules *v1alpha1.ScalingRules, defaultWindow time.Duration) time.Duration {
+	if rules == nil {
+		return 0
+	}
+
+	if rules.StabilizationWindowSeconds == nil {
+		return defaultWindow
+	}
+
+	return time.Duration(*rules.StabilizationWindowSeconds) * time.Second
+}
+
+func getLongestPolicyPeriod(behavior v1alpha1.ScalingBehavior) time.Duration {
+	var longest time.Duration
+
+	for _, rules := range []*v1alpha1.ScalingRules{behavior.ScaleUp, behavior.ScaleDown} {
+		if rules == nil {
+			continue
+		}
+
+		for _, p := range rules.Policies {
+			if d := time.Duration(p.PeriodSeconds) * time.Second; d > longest {
+				longest = d
+			}
+		}
+	}
+
+	return longest
+}
+
+// appendRecommendation records the recommendation only when it differs from the last one.
+// Each recommendation is considered to be in effect until the next one,
+// which keeps the history short even though the HRA is reconciled very often.
+func appendRecommendation(recommendations []v1alpha1.ScaleRecommendation, now time.Time, replicas int) []v1alpha1.ScaleRecommendation {
+	if n := len(recommendations); n > 0 && recommendations[n-1].Replicas == replicas {
+		return recommendations
+	}
+
+	r := make([]v1alpha1.ScaleRecommendation, 0, len(recommendations)+1)
+	r = append(r, recommendations...)
+	r = append(r, v1alpha1.ScaleRecommendation{Timestamp: metav1.Time{Time: now}, Replicas: replicas})
+
+	return r
+}
+
+// recommendationsWithinWindow returns the recommendations that were in effect at some point within the window,
+// which includes the last recommendation made before the window started.
+func recommendationsWithinWindow(recommendations []v1alpha1.ScaleRecommendation, now time.Time, window time.Duration) []v1alpha1.ScaleRecommendation {
+	start := now.Add(-window)
+
+	for i := len(recommendations) - 1; i >= 0; i-- {
+		if !recommendations[i].Timestamp.Time.After(start) {
+			return recommendations[i:]
+		}
+	}
+
+	return recommendations
+}
+
+func pruneScaleEvents(events []v1alpha1.ScaleEvent, now time.Time, period time.Duration) []v1alpha1.ScaleEvent {
+	var r []v1alpha1.ScaleEvent
+
+	for _, e := range events {
+		if e.Timestamp.Time.After(now.Add(-period)) {
+			r = append(r, e)
+		}
+	}
+
+	return r
+}
+
+// getReplicasChangePerPeriod returns the number of runners added (scaleUp=true) or removed (scaleUp=false)
+// within the period.
+func getReplicasChangePerPeriod(events []v1alpha1.ScaleEvent, now time.Time, periodSeconds int, scaleUp bool) int {
+	var changed int
+
+	start := now.Add(-time.Duration(periodSeconds) * time.Second)
+
+	for _, e := range events {
+		if !e.Timestamp.Time.After(start) {
+			continue
+		}
+
+		if scaleUp && e.ReplicaChange > 0 {
+			changed += e.ReplicaChange
+		} else if !scaleUp && e.ReplicaChange < 0 {
+			changed -= e.ReplicaChange
+		}
+	}
+
+	return changed
+}
+
+func calculateScaleUpLimit(current int, events []v1alpha1.ScaleEvent, now time.Time, rules *v1alpha1.ScalingRules) (int, error) {
+	if rules == nil || (len(rules.Policies) == 0 && rules.SelectPolicy != v1alpha1.ScalingPolicySelectDisabled) {
+		return math.MaxInt32, nil
+	}
+
+	var (
+		result int
+		pick   func(int, int) int
+	)
+
+	switch rules.SelectPolicy {
+	case v1alpha1.ScalingPolicySelectDisabled:
+		return current, nil
+	case v1alpha1.ScalingPolicySelectMin:
+		result, pick = math.MaxInt32, minInt
+	case "", v1alpha1.ScalingPolicySelectMax:
+		result, pick = math.MinInt32, maxInt
+	default:
+		return 0, fmt.Errorf("validating behavior: unsupported selectPolicy %q", rules.SelectPolicy)
+	}
+
+	for _, p := range rules.Policies {
+		periodStartReplicas := current - getReplicasChangePerPeriod(events, now, p.PeriodSeconds, true)
+
+		var proposed int
+
+		switch p.Type {
+		case v1alpha1.ScalingPolicyTypeRunners:
+			proposed = periodStartReplicas + p.Value
+		case v1alpha1.ScalingPolicyTypePercent:
+			proposed = int(math.Ceil(float64(periodStartReplicas) * (1 + float64(p.Value)/100)))
+
+			// Any percentage of zero runners is zero, which would never let the runners scale up from zero
+			if proposed <= 0 && p.Value > 0 {
+				proposed = 1
+			}
+		default:
+			return 0, fmt.Errorf("validating behavior: unsupported policy type %q", p.Type)
+		}
+
+		result = pick(result, proposed)
+	}
+
+	return result, nil
+}
+
+func calculateScaleDownLimit(current int, events []v1alpha1.ScaleEvent, now time.Time, rules *v1alpha1.ScalingRules) (int, error) {
+	if rules == nil || (len(rules.Policies) == 0 && rules.SelectPolicy != v1alpha1.ScalingPolicySelectDisabled) {
+		return math.MinInt32, nil
+	}
+
+	var (
+		result int
+		pick   func(int, int) int
+	)
+
+	switch rules.SelectPolicy {
+	case v1alpha1.ScalingPolicySelectDisabled:
+		return current, nil
+	case v1alpha1.ScalingPolicySelectMin:
+		result, pick = math.MinInt32, maxInt
+	case "", v1alpha1.ScalingPolicySelectMax:
+		result, pick = math.MaxInt32, minInt
+	default:
+		return 0, fmt.Errorf("validating behavior: unsupported selectPolicy %q", rules.SelectPolicy)
+	}
+
+	for _, p := range rules.Policies {
+		periodStartReplicas := current + getReplicasChangePerPeriod(events, now, p.PeriodSeconds, false)
+
+		var proposed int
+
+		switch p.Type {
+		case v1alpha1.ScalingPolicyTypeRunners:
+			proposed = periodStartReplicas - p.Value
+		case v1alpha1.ScalingPolicyTypePercent:
+			proposed = int(float64(periodStartReplicas) * (1 - float64(p.Value)/100))
+		default:
+			return 0, fmt.Errorf("validating behavior: unsupported policy type %q", p.Type)
+		}
+
+		result = pick(result, proposed)
+	}
+
+	return result, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
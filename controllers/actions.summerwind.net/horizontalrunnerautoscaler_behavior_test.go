@@ -0,0 +1,312 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyScalingBehavior(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	ago := func(d time.Duration) metav1.Time {
+		return metav1.Time{Time: now.Add(-d)}
+	}
+
+	testcases := []struct {
+		name        string
+		behavior    v1alpha1.ScalingBehavior
+		status      v1alpha1.HorizontalRunnerAutoscalerStatus
+		min         int
+		recommended int
+
+		want                int
+		wantRecommendations []v1alpha1.ScaleRecommendation
+		wantScaleEvents     []v1alpha1.ScaleEvent
+		err                 string
+	}{
+		{
+			name:        "first reconciliation",
+			behavior:    v1alpha1.ScalingBehavior{ScaleDown: &v1alpha1.ScalingRules{}},
+			recommended: 3,
+			want:        3,
+			wantRecommendations: []v1alpha1.ScaleRecommendation{
+				{Timestamp: ago(0), Replicas: 3},
+			},
+		},
+		{
+			name:     "scale up without rules",
+			behavior: v1alpha1.ScalingBehavior{},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(1),
+			},
+			recommended: 10,
+			want:        10,
+			wantRecommendations: []v1alpha1.ScaleRecommendation{
+				{Timestamp: ago(0), Replicas: 10},
+			},
+			wantScaleEvents: []v1alpha1.ScaleEvent{
+				{Timestamp: ago(0), ReplicaChange: 9},
+			},
+		},
+		{
+			name: "scale up limited by runners per period",
+			behavior: v1alpha1.ScalingBehavior{
+				ScaleUp: &v1alpha1.ScalingRules{
+					Policies: []v1alpha1.ScalingPolicy{
+						{Type: v1alpha1.ScalingPolicyTypeRunners, Value: 4, PeriodSeconds: 60},
+					},
+				},
+			},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(3),
+				ScaleEvents: []v1alpha1.ScaleEvent{
+					// Outside of the period
+					{Timestamp: ago(90 * time.Second), ReplicaChange: 1},
+					{Timestamp: ago(30 * time.Second), ReplicaChange: 2},
+				},
+			},
+			recommended: 10,
+			// 3 - 2 + 4
+			want: 5,
+			wantRecommendations: []v1alpha1.ScaleRecommendation{
+				{Timestamp: ago(0), Replicas: 10},
+			},
+			wantScaleEvents: []v1alpha1.ScaleEvent{
+				{Timestamp: ago(30 * time.Second), ReplicaChange: 2},
+				{Timestamp: ago(0), ReplicaChange: 2},
+			},
+		},
+		{
+			name: "scale up with max select policy",
+			behavior: v1alpha1.ScalingBehavior{
+				ScaleUp: &v1alpha1.ScalingRules{
+					Policies: []v1alpha1.ScalingPolicy{
+						{Type: v1alpha1.ScalingPolicyTypeRunners, Value: 4, PeriodSeconds: 60},
+						{Type: v1alpha1.ScalingPolicyTypePercent, Value: 100, PeriodSeconds: 60},
+					},
+				},
+			},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(10),
+			},
+			recommended: 100,
+			want:        20,
+			wantRecommendations: []v1alpha1.ScaleRecommendation{
+				{Timestamp: ago(0), Replicas: 100},
+			},
+			wantScaleEvents: []v1alpha1.ScaleEvent{
+				{Timestamp: ago(0), ReplicaChange: 10},
+			},
+		},
+		{
+			name: "scale up with min select policy",
+			behavior: v1alpha1.ScalingBehavior{
+				ScaleUp: &v1alpha1.ScalingRules{
+					SelectPolicy: v1alpha1.ScalingPolicySelectMin,
+					Policies: []v1alpha1.ScalingPolicy{
+						{Type: v1alpha1.ScalingPolicyTypeRunners, Value: 4, PeriodSeconds: 60},
+						{Type: v1alpha1.ScalingPolicyTypePercent, Value: 100, PeriodSeconds: 60},
+					},
+				},
+			},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(10),
+			},
+			recommended: 100,
+			want:        14,
+			wantRecommendations: []v1alpha1.ScaleRecommendation{
+				{Timestamp: ago(0), Replicas: 100},
+			},
+			wantScaleEvents: []v1alpha1.ScaleEvent{
+				{Timestamp: ago(0), ReplicaChange: 4},
+			},
+		},
+		{
+			name: "scale up from zero by percent",
+			behavior: v1alpha1.ScalingBehavior{
+				ScaleUp: &v1alpha1.ScalingRules{
+					Policies: []v1alpha1.ScalingPolicy{
+						{Type: v1alpha1.ScalingPolicyTypePercent, Value: 100, PeriodSeconds: 60},
+					},
+				},
+			},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(0),
+			},
+			recommended: 5,
+			// A Percent policy allows at least one runner when scaling up from zero
+			want: 1,
+			wantRecommendations: []v1alpha1.ScaleRecommendation{
+				{Timestamp: ago(0), Replicas: 5},
+			},
+			wantScaleEvents: []v1alpha1.ScaleEvent{
+				{Timestamp: ago(0), ReplicaChange: 1},
+			},
+		},
+		{
+			name: "scale up disabled",
+			behavior: v1alpha1.ScalingBehavior{
+				ScaleUp: &v1alpha1.ScalingRules{
+					SelectPolicy: v1alpha1.ScalingPolicySelectDisabled,
+				},
+			},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(2),
+			},
+			recommended: 5,
+			want:        2,
+			wantRecommendations: []v1alpha1.ScaleRecommendation{
+				{Timestamp: ago(0), Replicas: 5},
+			},
+		},
+		{
+			name: "scale down stabilized by the default window",
+			behavior: v1alpha1.ScalingBehavior{
+				ScaleDown: &v1alpha1.ScalingRules{},
+			},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(8),
+				Recommendations: []v1alpha1.ScaleRecommendation{
+					// Dropped as it's been replaced by the next recommendation before the window started
+					{Timestamp: ago(10 * time.Minute), Replicas: 10},
+					{Timestamp: ago(6 * time.Minute), Replicas: 8},
+					{Timestamp: ago(2 * time.Minute), Replicas: 6},
+				},
+			},
+			recommended: 2,
+			want:        8,
+			wantRecommendations: []v1alpha1.ScaleRecommendation{
+				{Timestamp: ago(6 * time.Minute), Replicas: 8},
+				{Timestamp: ago(2 * time.Minute), Replicas: 6},
+				{Timestamp: ago(0), Replicas: 2},
+			},
+		},
+		{
+			name: "scale down after the window",
+			behavior: v1alpha1.ScalingBehavior{
+				ScaleDown: &v1alpha1.ScalingRules{
+					StabilizationWindowSeconds: intPtr(60),
+				},
+			},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(8),
+				Recommendations: []v1alpha1.ScaleRecommendation{
+					{Timestamp: ago(6 * time.Minute), Replicas: 8},
+					{Timestamp: ago(2 * time.Minute), Replicas: 6},
+				},
+			},
+			recommended: 2,
+			want:        6,
+			wantRecommendations: []v1alpha1.ScaleRecommendation{
+				{Timestamp: ago(2 * time.Minute), Replicas: 6},
+				{Timestamp: ago(0), Replicas: 2},
+			},
+			wantScaleEvents: []v1alpha1.ScaleEvent{
+				{Timestamp: ago(0), ReplicaChange: -2},
+			},
+		},
+		{
+			name: "scale down limited by percent per period",
+			behavior: v1alpha1.ScalingBehavior{
+				ScaleDown: &v1alpha1.ScalingRules{
+					StabilizationWindowSeconds: intPtr(0),
+					Policies: []v1alpha1.ScalingPolicy{
+						{Type: v1alpha1.ScalingPolicyTypePercent, Value: 10, PeriodSeconds: 300},
+					},
+				},
+			},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(18),
+				ScaleEvents: []v1alpha1.ScaleEvent{
+					{Timestamp: ago(time.Minute), ReplicaChange: -2},
+				},
+			},
+			recommended: 0,
+			// The period started with 20 runners, of which 10% can be removed.
+			want: 18,
+			wantRecommendations: []v1alpha1.ScaleRecommendation{
+				{Timestamp: ago(0), Replicas: 0},
+			},
+			wantScaleEvents: []v1alpha1.ScaleEvent{
+				{Timestamp: ago(time.Minute), ReplicaChange: -2},
+			},
+		},
+		{
+			name: "scale down limited by min replicas",
+			behavior: v1alpha1.ScalingBehavior{
+				ScaleDown: &v1alpha1.ScalingRules{
+					StabilizationWindowSeconds: intPtr(0),
+					Policies: []v1alpha1.ScalingPolicy{
+						{Type: v1alpha1.ScalingPolicyTypeRunners, Value: 10, PeriodSeconds: 60},
+					},
+				},
+			},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(5),
+			},
+			min:         3,
+			recommended: 3,
+			want:        3,
+			wantRecommendations: []v1alpha1.ScaleRecommendation{
+				{Timestamp: ago(0), Replicas: 3},
+			},
+			wantScaleEvents: []v1alpha1.ScaleEvent{
+				{Timestamp: ago(0), ReplicaChange: -2},
+			},
+		},
+		{
+			name: "unsupported policy type",
+			behavior: v1alpha1.ScalingBehavior{
+				ScaleUp: &v1alpha1.ScalingRules{
+					Policies: []v1alpha1.ScalingPolicy{
+						{Type: "Pods", Value: 1, PeriodSeconds: 60},
+					},
+				},
+			},
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(1),
+			},
+			recommended: 2,
+			err:         `validating behavior: unsupported policy type "Pods"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := applyScalingBehavior(now, tc.behavior, tc.status, tc.min, tc.recommended)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("unexpected error: want %q, got %v", tc.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.desiredReplicas != tc.want {
+				t.Errorf("unexpected desired replicas: want %d, got %d", tc.want, got.desiredReplicas)
+			}
+
+			if d := cmp.Diff(tc.wantRecommendations, got.recommendations); d != "" {
+				t.Errorf("unexpected recommendations: (-want, +got)\n%s", d)
+			}
+
+			if d := cmp.Diff(tc.wantScaleEvents, got.scaleEvents); d != "" {
+				t.Errorf("unexpected scale events: (-want, +got)\n%s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,424 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// capacityLimit is the maximum number of runner pods the cluster can run for the scale target.
+type capacityLimit struct {
+	// replicas is the maximum number of replicas
+	replicas int
+	// reason tells which capacity limited the replicas, like `resourcequota/compute requests.cpu`
+	reason string
+}
+
+// runnerPodSpecFromRunnerSpec returns the subset of the spec of the runner pods created for the runner spec
+// that affects the scheduling of the pods.
+// The runner and docker containers are defaulted the same way as RunnerReconciler.newPod.
+func runnerPodSpecFromRunnerSpec(spec v1alpha1.RunnerSpec) corev1.PodSpec {
+	var containers []corev1.Container
+
+	if len(spec.Containers) == 0 {
+		containers = append(containers, corev1.Container{Name: "runner"})
+
+		if (spec.DockerEnabled == nil || *spec.DockerEnabled) && (spec.DockerdWithinRunnerContainer == nil || !*spec.DockerdWithinRunnerContainer) {
+			containers = append(containers, corev1.Container{Name: "docker"})
+		}
+	} else {
+		containers = append(containers, spec.Containers...)
+	}
+
+	for i, c := range containers {
+		var defaults corev1.ResourceRequirements
+
+		switch c.Name {
+		case "runner":
+			defaults = spec.Resources
+		case "docker":
+			defaults = spec.DockerdContainerResources
+		default:
+			continue
+		}
+
+		if len(c.Resources.Requests) == 0 {
+			containers[i].Resources.Requests = defaults.Requests
+		}
+
+		if len(c.Resources.Limits) == 0 {
+			containers[i].Resources.Limits = defaults.Limits
+		}
+	}
+
+	containers = append(containers, spec.SidecarContainers...)
+
+	return corev1.PodSpec{
+		Containers:     containers,
+		InitContainers: spec.InitContainers,
+		NodeSelector:   spec.NodeSelector,
+		Tolerations:    spec.Tolerations,
+	}
+}
+
+// podResources returns the effective resource requests and limits of a pod with the spec,
+// which are computed the same way as the scheduler and ResourceQuota do.
+// The requests of a container default to its limits, and each init container runs alone before the containers.
+func podResources(spec corev1.PodSpec) (requests, limits corev1.ResourceList) {
+	requests, limits = corev1.ResourceList{}, corev1.ResourceList{}
+
+	for _, c := range spec.Containers {
+		addResourceList(requests, containerRequests(c))
+		addResourceList(limits, c.Resources.Limits)
+	}
+
+	for _, c := range spec.InitContainers {
+		maxResourceList(requests, containerRequests(c))
+		maxResourceList(limits, c.Resources.Limits)
+	}
+
+	addResourceList(requests, spec.Overhead)
+	addResourceList(limits, spec.Overhead)
+
+	return requests, limits
+}
+
+func containerRequests(c corev1.Container) corev1.ResourceList {
+	requests := c.Resources.Requests.DeepCopy()
+	if requests == nil {
+		requests = corev1.ResourceList{}
+	}
+
+	for name, q := range c.Resources.Limits {
+		if _, ok := requests[name]; !ok {
+			requests[name] = q.DeepCopy()
+		}
+	}
+
+	return requests
+}
+
+func addResourceList(list, other corev1.ResourceList) {
+	for name, q := range other {
+		if v, ok := list[name]; ok {
+			v.Add(q)
+			list[name] = v
+		} else {
+			list[name] = q.DeepCopy()
+		}
+	}
+}
+
+func maxResourceList(list, other corev1.ResourceList) {
+	for name, q := range other {
+		if v, ok := list[name]; !ok || q.Cmp(v) > 0 {
+			list[name] = q.DeepCopy()
+		}
+	}
+}
+
+// fitPods returns the number of pods with the requests that fit in the available resources,
+// along with the name of the resource that limited the number.
+// Resources the pod doesn't request never limit the number.
+// It returns -1 when none of the resources limited the number.
+func fitPods(available, requests corev1.ResourceList) (int, corev1.ResourceName) {
+	n := -1
+
+	var limitedBy corev1.ResourceName
+
+	for name, q := range requests {
+		perPod := q.MilliValue()
+		if perPod <= 0 {
+			continue
+		}
+
+		a, ok := available[name]
+		if !ok {
+			continue
+		}
+
+		fit := int(a.MilliValue() / perPod)
+		if fit < 0 {
+			fit = 0
+		}
+
+		if n < 0 || fit < n {
+			n = fit
+			limitedBy = name
+		}
+	}
+
+	return n, limitedBy
+}
+
+// resourceQuotaHeadroom returns the remaining amount of each resource of the quota,
+// keyed by the resource name of the pod requests or limits that consume it.
+func resourceQuotaHeadroom(quota corev1.ResourceQuota) (corev1.ResourceList, corev1.ResourceList) {
+	requestsHeadroom, limitsHeadroom := corev1.ResourceList{}, corev1.ResourceList{}
+
+	for name, hard := range quota.Status.Hard {
+		used := quota.Status.Used[name]
+
+		headroom := hard.DeepCopy()
+		headroom.Sub(used)
+
+		n := string(name)
+
+		switch {
+		case strings.HasPrefix(n, "requests."):
+			requestsHeadroom[corev1.ResourceName(strings.TrimPrefix(n, "requests."))] = headroom
+		case strings.HasPrefix(n, "limits."):
+			limitsHeadroom[corev1.ResourceName(strings.TrimPrefix(n, "limits."))] = headroom
+		case name == corev1.ResourceCPU, name == corev1.ResourceMemory, name == corev1.ResourceEphemeralStorage:
+			requestsHeadroom[name] = headroom
+		}
+	}
+
+	return requestsHeadroom, limitsHeadroom
+}
+
+// fitPodsInResourceQuotas returns the number of additional pods with the requests and the limits
+// that fit in the headroom of all the quotas, or nil when no quota limits the number.
+func fitPodsInResourceQuotas(quotas []corev1.ResourceQuota, requests, limits corev1.ResourceList) *capacityLimit {
+	var limit *capacityLimit
+
+	for _, quota := range quotas {
+		// Scoped quotas may not apply to the runner pods, which can't be determined without the pods
+		if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+			continue
+		}
+
+		requestsHeadroom, limitsHeadroom := resourceQuotaHeadroom(quota)
+
+		onePod := corev1.ResourceList{}
+		podsHeadroom := corev1.ResourceList{}
+
+		for _, name := range []corev1.ResourceName{corev1.ResourcePods, "count/pods"} {
+			if hard, ok := quota.Status.Hard[name]; ok {
+				headroom := hard.DeepCopy()
+				headroom.Sub(quota.Status.Used[name])
+				podsHeadroom[name] = headroom
+				onePod[name] = *resource.NewQuantity(1, resource.DecimalSI)
+			}
+		}
+
+		for _, c := range []struct {
+			prefix              string
+			headroom, resources corev1.ResourceList
+		}{
+			{prefix: "requests.", headroom: requestsHeadroom, resources: requests},
+			{prefix: "limits.", headroom: limitsHeadroom, resources: limits},
+			{headroom: podsHeadroom, resources: onePod},
+		} {
+			fit, name := fitPods(c.headroom, c.resources)
+			if fit < 0 {
+				continue
+			}
+
+			if limit == nil || fit < limit.replicas {
+				limit = &capacityLimit{
+					replicas: fit,
+					reason:   fmt.Sprintf("resourcequota/%s %s%s", quota.Name, c.prefix, name),
+				}
+			}
+		}
+	}
+
+	return limit
+}
+
+// nodeMatchesPodSpec returns true when the pod with the spec can be scheduled onto the node,
+// judging only by the node selector, the tolerations, and the readiness and the schedulability of the node.
+func nodeMatchesPodSpec(node corev1.Node, spec corev1.PodSpec) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+
+	ready := false
+
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady && c.Status == corev1.ConditionTrue {
+			ready = true
+		}
+	}
+
+	if !ready {
+		return false
+	}
+
+	if !labels.SelectorFromSet(spec.NodeSelector).Matches(labels.Set(node.Labels)) {
+		return false
+	}
+
+TAINT:
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+
+		for _, toleration := range spec.Tolerations {
+			if toleration.ToleratesTaint(taint) {
+				continue TAINT
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// fitPodsOnNodes returns the number of additional pods with the spec and the requests that fit in the
+// unrequested allocatable resources of the nodes matching the spec.
+func fitPodsOnNodes(nodes []corev1.Node, pods []corev1.Pod, spec corev1.PodSpec, requests corev1.ResourceList) *capacityLimit {
+	requested := map[string]corev1.ResourceList{}
+
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		r, ok := requested[pod.Spec.NodeName]
+		if !ok {
+			r = corev1.ResourceList{}
+			requested[pod.Spec.NodeName] = r
+		}
+
+		podRequests, _ := podResources(pod.Spec)
+
+		addResourceList(r, podRequests)
+		addResourceList(r, corev1.ResourceList{corev1.ResourcePods: *resource.NewQuantity(1, resource.DecimalSI)})
+	}
+
+	perPod := requests.DeepCopy()
+	perPod[corev1.ResourcePods] = *resource.NewQuantity(1, resource.DecimalSI)
+
+	var (
+		total     int
+		matched   int
+		limitedBy = map[corev1.ResourceName]struct{}{}
+	)
+
+	for _, node := range nodes {
+		if !nodeMatchesPodSpec(node, spec) {
+			continue
+		}
+
+		matched++
+
+		available := node.Status.Allocatable.DeepCopy()
+
+		for name, q := range requested[node.Name] {
+			if v, ok := available[name]; ok {
+				v.Sub(q)
+				available[name] = v
+			}
+		}
+
+		fit, name := fitPods(available, perPod)
+		if fit < 0 {
+			continue
+		}
+
+		if fit == 0 {
+			limitedBy[name] = struct{}{}
+		}
+
+		total += fit
+	}
+
+	var names []string
+
+	for name := range limitedBy {
+		names = append(names, string(name))
+	}
+
+	sort.Strings(names)
+
+	reason := fmt.Sprintf("%d matching nodes", matched)
+
+	if len(names) > 0 {
+		reason = fmt.Sprintf("%s full on %s", reason, strings.Join(names, ","))
+	}
+
+	return &capacityLimit{
+		replicas: total,
+		reason:   fmt.Sprintf("nodes/allocatable %s", reason),
+	}
+}
+
+// computeCapacityLimit returns the maximum number of replicas the cluster can run for the scale target
+// according to the capacity limit, or nil when there's no limit.
+// The runner pods of the scale target that already consume the capacity are added to the number of pods that fit.
+func (r *HorizontalRunnerAutoscalerReconciler) computeCapacityLimit(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget, spec v1alpha1.CapacityLimit) (*capacityLimit, error) {
+	if !spec.ResourceQuota && !spec.NodeAllocatable {
+		return nil, nil
+	}
+
+	runnerPodList, err := r.listRunnerPods(ctx, hra, st)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing, scheduled int
+
+	for _, p := range runnerPodList.Items {
+		if p.Status.Phase == corev1.PodSucceeded || p.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		existing++
+
+		if p.Spec.NodeName != "" {
+			scheduled++
+		}
+	}
+
+	requests, limits := podResources(st.podSpec)
+
+	var limit *capacityLimit
+
+	if spec.ResourceQuota {
+		var quotaList corev1.ResourceQuotaList
+		if err := r.Client.List(ctx, &quotaList, client.InNamespace(hra.Namespace)); err != nil {
+			return nil, fmt.Errorf("listing resourcequotas: %w", err)
+		}
+
+		// The existing runner pods, even pending ones, are already counted in the used amount of the quotas
+		if l := fitPodsInResourceQuotas(quotaList.Items, requests, limits); l != nil {
+			l.replicas += existing
+			limit = l
+		}
+	}
+
+	if spec.NodeAllocatable {
+		var nodeList corev1.NodeList
+		if err := r.Client.List(ctx, &nodeList); err != nil {
+			return nil, fmt.Errorf("listing nodes: %w", err)
+		}
+
+		var podList corev1.PodList
+		if err := r.Client.List(ctx, &podList); err != nil {
+			return nil, fmt.Errorf("listing pods: %w", err)
+		}
+
+		// Only the scheduled runner pods consume the allocatable resources of the nodes
+		l := fitPodsOnNodes(nodeList.Items, podList.Items, st.podSpec, requests)
+		l.replicas += scheduled
+
+		if limit == nil || l.replicas < limit.replicas {
+			limit = l
+		}
+	}
+
+	return limit, nil
+}
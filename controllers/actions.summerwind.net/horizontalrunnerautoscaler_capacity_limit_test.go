@@ -0,0 +1,390 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func resourceList(cpu, memory string) corev1.ResourceList {
+	l := corev1.ResourceList{}
+
+	if cpu != "" {
+		l[corev1.ResourceCPU] = resource.MustParse(cpu)
+	}
+
+	if memory != "" {
+		l[corev1.ResourceMemory] = resource.MustParse(memory)
+	}
+
+	return l
+}
+
+func TestRunnerPodResources(t *testing.T) {
+	spec := v1alpha1.RunnerSpec{
+		RunnerPodSpec: v1alpha1.RunnerPodSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: resourceList("1", "2Gi"),
+			},
+			DockerdContainerResources: corev1.ResourceRequirements{
+				// The requests default to the limits
+				Limits: resourceList("500m", "1Gi"),
+			},
+			SidecarContainers: []corev1.Container{
+				{Name: "sidecar", Resources: corev1.ResourceRequirements{Requests: resourceList("100m", "")}},
+			},
+			InitContainers: []corev1.Container{
+				// Only the memory exceeds the sum of the containers
+				{Name: "init", Resources: corev1.ResourceRequirements{Requests: resourceList("1", "4Gi")}},
+			},
+		},
+	}
+
+	requests, limits := podResources(runnerPodSpecFromRunnerSpec(spec))
+
+	for name, want := range resourceList("1600m", "4Gi") {
+		if got := requests[name]; got.Cmp(want) != 0 {
+			t.Errorf("unexpected %s requests: want %s, got %s", name, want.String(), got.String())
+		}
+	}
+
+	for name, want := range resourceList("500m", "1Gi") {
+		if got := limits[name]; got.Cmp(want) != 0 {
+			t.Errorf("unexpected %s limits: want %s, got %s", name, want.String(), got.String())
+		}
+	}
+}
+
+func TestFitPodsInResourceQuotas(t *testing.T) {
+	quota := func(name string, hard, used corev1.ResourceList) corev1.ResourceQuota {
+		return corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.ResourceQuotaStatus{Hard: hard, Used: used},
+		}
+	}
+
+	requests := resourceList("1", "2Gi")
+	limits := resourceList("2", "2Gi")
+
+	testcases := []struct {
+		name   string
+		quotas []corev1.ResourceQuota
+		want   *capacityLimit
+	}{
+		{
+			name: "no quotas",
+		},
+		{
+			name: "requests",
+			quotas: []corev1.ResourceQuota{
+				quota("compute", corev1.ResourceList{
+					"requests.cpu":    resource.MustParse("10"),
+					"requests.memory": resource.MustParse("100Gi"),
+				}, corev1.ResourceList{
+					"requests.cpu":    resource.MustParse("6500m"),
+					"requests.memory": resource.MustParse("10Gi"),
+				}),
+			},
+			want: &capacityLimit{replicas: 3, reason: "resourcequota/compute requests.cpu"},
+		},
+		{
+			name: "limits and pods",
+			quotas: []corev1.ResourceQuota{
+				quota("compute", corev1.ResourceList{
+					"limits.cpu": resource.MustParse("10"),
+				}, corev1.ResourceList{
+					"limits.cpu": resource.MustParse("4"),
+				}),
+				quota("pods", corev1.ResourceList{
+					"pods": resource.MustParse("20"),
+				}, corev1.ResourceList{
+					"pods": resource.MustParse("18"),
+				}),
+			},
+			want: &capacityLimit{replicas: 2, reason: "resourcequota/pods pods"},
+		},
+		{
+			name: "exhausted",
+			quotas: []corev1.ResourceQuota{
+				quota("compute", corev1.ResourceList{
+					"memory": resource.MustParse("8Gi"),
+				}, corev1.ResourceList{
+					"memory": resource.MustParse("9Gi"),
+				}),
+			},
+			want: &capacityLimit{replicas: 0, reason: "resourcequota/compute requests.memory"},
+		},
+		{
+			name: "scoped",
+			quotas: []corev1.ResourceQuota{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "best-effort"},
+					Spec:       corev1.ResourceQuotaSpec{Scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort}},
+					Status: corev1.ResourceQuotaStatus{
+						Hard: corev1.ResourceList{"pods": resource.MustParse("1")},
+						Used: corev1.ResourceList{"pods": resource.MustParse("1")},
+					},
+				},
+			},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			got := fitPodsInResourceQuotas(tc.quotas, requests, limits)
+
+			if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+				t.Errorf("unexpected capacity limit: want %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestComputeCapacityLimit(t *testing.T) {
+	node := func(name string, labels map[string]string, taints []corev1.Taint, ready bool) *corev1.Node {
+		status := corev1.ConditionTrue
+		if !ready {
+			status = corev1.ConditionFalse
+		}
+
+		allocatable := resourceList("4", "16Gi")
+		allocatable[corev1.ResourcePods] = resource.MustParse("110")
+
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec:       corev1.NodeSpec{Taints: taints},
+			Status: corev1.NodeStatus{
+				Allocatable: allocatable,
+				Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+			},
+		}
+	}
+
+	pod := func(name, nodeName string, labels map[string]string, cpu string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: labels},
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+				Containers: []corev1.Container{
+					{Name: "main", Resources: corev1.ResourceRequirements{Requests: resourceList(cpu, "")}},
+				},
+			},
+		}
+	}
+
+	runnerLabels := map[string]string{LabelKeyRunnerDeploymentName: "testrd"}
+	taint := corev1.Taint{Key: "runners", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	pool := map[string]string{"pool": "runners"}
+
+	objs := []runtime.Object{
+		node("runners-1", pool, []corev1.Taint{taint}, true),
+		node("runners-2", pool, []corev1.Taint{taint}, true),
+		// Not ready
+		node("runners-3", pool, []corev1.Taint{taint}, false),
+		// The taint is not tolerated
+		node("runners-4", pool, []corev1.Taint{{Key: "gpu", Effect: corev1.TaintEffectNoSchedule}}, true),
+		// The node selector doesn't match
+		node("other", nil, nil, true),
+		// 1 of 4 CPUs is available on runners-1, and 3 on runners-2
+		pod("runner-1", "runners-1", runnerLabels, "1"),
+		pod("runner-2", "runners-1", runnerLabels, "1"),
+		pod("app", "runners-1", nil, "1"),
+		pod("runner-3", "runners-2", runnerLabels, "1"),
+		pod("runner-pending", "", runnerLabels, "1"),
+		&corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "compute"},
+			Status: corev1.ResourceQuotaStatus{
+				Hard: corev1.ResourceList{"requests.cpu": resource.MustParse("10")},
+				Used: corev1.ResourceList{"requests.cpu": resource.MustParse("5")},
+			},
+		},
+	}
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "testhra"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "testrd"},
+		},
+	}
+
+	dockerEnabled := false
+
+	st := scaleTarget{
+		podSpec: runnerPodSpecFromRunnerSpec(v1alpha1.RunnerSpec{
+			RunnerConfig: v1alpha1.RunnerConfig{DockerEnabled: &dockerEnabled},
+			RunnerPodSpec: v1alpha1.RunnerPodSpec{
+				Resources:    corev1.ResourceRequirements{Requests: resourceList("1", "")},
+				NodeSelector: pool,
+				Tolerations:  []corev1.Toleration{{Key: "runners", Operator: corev1.TolerationOpExists}},
+			},
+		}),
+	}
+
+	testcases := []struct {
+		name string
+		spec v1alpha1.CapacityLimit
+		want *capacityLimit
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name: "resource quota",
+			spec: v1alpha1.CapacityLimit{ResourceQuota: true},
+			// 4 existing runner pods including the pending one, plus 5 CPUs of headroom
+			want: &capacityLimit{replicas: 9, reason: "resourcequota/compute requests.cpu"},
+		},
+		{
+			name: "node allocatable",
+			spec: v1alpha1.CapacityLimit{NodeAllocatable: true},
+			// 3 scheduled runner pods, plus 1 and 3 CPUs available on the matching nodes
+			want: &capacityLimit{replicas: 7, reason: "nodes/allocatable 2 matching nodes"},
+		},
+		{
+			name: "both",
+			spec: v1alpha1.CapacityLimit{ResourceQuota: true, NodeAllocatable: true},
+			want: &capacityLimit{replicas: 7, reason: "nodes/allocatable 2 matching nodes"},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := &HorizontalRunnerAutoscalerReconciler{
+				Client: fake.NewClientBuilder().WithScheme(sc).WithRuntimeObjects(objs...).Build(),
+				Log: zap.New(func(o *zap.Options) {
+					o.Development = true
+				}),
+			}
+
+			got, err := r.computeCapacityLimit(context.Background(), hra, st, tc.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+				t.Errorf("unexpected capacity limit: want %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestScalingBehaviorWithCapacityLimit(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	runnerLabels := map[string]string{LabelKeyRunnerDeploymentName: "testrd"}
+
+	var objs []runtime.Object
+
+	for _, name := range []string{"runner-1", "runner-2", "runner-3"} {
+		objs = append(objs, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: runnerLabels},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "main", Resources: corev1.ResourceRequirements{Requests: resourceList("1", "")}},
+				},
+			},
+		})
+	}
+
+	// 2 CPUs of headroom, so that 5 runners fit in the quota
+	objs = append(objs, &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "compute"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{"requests.cpu": resource.MustParse("5")},
+			Used: corev1.ResourceList{"requests.cpu": resource.MustParse("3")},
+		},
+	})
+
+	r := &HorizontalRunnerAutoscalerReconciler{
+		Client: fake.NewClientBuilder().WithScheme(sc).WithRuntimeObjects(objs...).Build(),
+		Log: zap.New(func(o *zap.Options) {
+			o.Development = true
+		}),
+	}
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "testhra"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "testrd"},
+		},
+	}
+
+	dockerEnabled := false
+
+	st := scaleTarget{
+		podSpec: runnerPodSpecFromRunnerSpec(v1alpha1.RunnerSpec{
+			RunnerConfig: v1alpha1.RunnerConfig{DockerEnabled: &dockerEnabled},
+			RunnerPodSpec: v1alpha1.RunnerPodSpec{
+				Resources: corev1.ResourceRequirements{Requests: resourceList("1", "")},
+			},
+		}),
+	}
+
+	behavior := v1alpha1.ScalingBehavior{
+		ScaleUp: &v1alpha1.ScalingRules{
+			Policies: []v1alpha1.ScalingPolicy{
+				{Type: v1alpha1.ScalingPolicyTypeRunners, Value: 4, PeriodSeconds: 60},
+			},
+		},
+	}
+
+	current := 3
+
+	result, err := applyScalingBehavior(now, behavior, v1alpha1.HorizontalRunnerAutoscalerStatus{DesiredReplicas: &current}, 0, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.desiredReplicas != 7 {
+		t.Fatalf("unexpected desired replicas by behavior: want 7, got %d", result.desiredReplicas)
+	}
+
+	limit, err := r.computeCapacityLimit(context.Background(), hra, st, v1alpha1.CapacityLimit{ResourceQuota: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if limit == nil || limit.replicas != 5 {
+		t.Fatalf("unexpected capacity limit: want 5 replicas, got %+v", limit)
+	}
+
+	result.clampScaleEvent(now, current, limit.replicas)
+
+	// Only the 2 runners actually added count towards the scale-up policy
+	want := []v1alpha1.ScaleEvent{
+		{Timestamp: metav1.Time{Time: now}, ReplicaChange: 2},
+	}
+
+	if d := cmp.Diff(want, result.scaleEvents); d != "" {
+		t.Errorf("unexpected scale events: (-want, +got)\n%s", d)
+	}
+
+	applied := limit.replicas
+
+	next, err := applyScalingBehavior(now.Add(30*time.Second), behavior, v1alpha1.HorizontalRunnerAutoscalerStatus{
+		DesiredReplicas: &applied,
+		Recommendations: result.recommendations,
+		ScaleEvents:     result.scaleEvents,
+	}, 0, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 5 - 2 + 4, once the capacity frees up
+	if next.desiredReplicas != 7 {
+		t.Errorf("unexpected desired replicas in the next reconciliation: want 7, got %d", next.desiredReplicas)
+	}
+}
@@ -0,0 +1,304 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	arcgithub "github.com/actions/actions-runner-controller/github"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DefaultCapacityReservationSyncPeriod  = time.Minute
+	DefaultCapacityReservationSyncLookups = 30
+)
+
+// capacityReservationSyncTimes remembers when the capacity reservations of each HRA were last synced with GitHub,
+// so that the status of each workflow job is looked up at most once per sync period no matter how often the HRA is reconciled.
+// It also remembers the last workflow job looked up for each HRA, so that the next sync resumes from there
+// when there are more reservations than the lookups allowed per sync.
+type capacityReservationSyncTimes struct {
+	mu      sync.Mutex
+	m       map[types.NamespacedName]time.Time
+	cursors map[types.NamespacedName]int64
+}
+
+func (c *capacityReservationSyncTimes) due(key types.NamespacedName, now time.Time, period time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	last, ok := c.m[key]
+
+	return !ok || now.Sub(last) >= period
+}
+
+func (c *capacityReservationSyncTimes) set(key types.NamespacedName, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.m == nil {
+		c.m = map[types.NamespacedName]time.Time{}
+	}
+
+	c.m[key] = now
+}
+
+func (c *capacityReservationSyncTimes) cursor(key types.NamespacedName) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cursors[key]
+}
+
+func (c *capacityReservationSyncTimes) setCursor(key types.NamespacedName, jobID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cursors == nil {
+		c.cursors = map[types.NamespacedName]int64{}
+	}
+
+	c.cursors[key] = jobID
+}
+
+func (c *capacityReservationSyncTimes) delete(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.m, key)
+	delete(c.cursors, key)
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) capacityReservationSyncPeriod() time.Duration {
+	if r.CapacityReservationSyncPeriod <= 0 {
+		return DefaultCapacityReservationSyncPeriod
+	}
+
+	return r.CapacityReservationSyncPeriod
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) capacityReservationSyncLookups() int {
+	if r.CapacityReservationSyncLookups <= 0 {
+		return DefaultCapacityReservationSyncLookups
+	}
+
+	return r.CapacityReservationSyncLookups
+}
+
+// hasWorkflowJobCapacityReservations returns true when the HRA has one or more unexpired capacity reservations
+// that can be synced with the status of their workflow jobs.
+func hasWorkflowJobCapacityReservations(hra v1alpha1.HorizontalRunnerAutoscaler, now time.Time) bool {
+	for _, res := range hra.Status.CapacityReservations {
+		if res.WorkflowJobID != 0 && res.Repository != "" && res.ExpirationTime.Time.After(now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getEarliestCapacityReservationExpirationTime returns the time at which the earliest unexpired capacity reservation of the HRA expires,
+// or the zero time when there's none.
+func getEarliestCapacityReservationExpirationTime(hra v1alpha1.HorizontalRunnerAutoscaler, now time.Time) time.Time {
+	var earliest time.Time
+
+	for _, res := range getCapacityReservations(&hra) {
+		t := res.ExpirationTime.Time
+
+		if t.After(now) && (earliest.IsZero() || t.Before(earliest)) {
+			earliest = t
+		}
+	}
+
+	return earliest
+}
+
+// getLiveCapacityReservations returns the capacity reservations of the HRAs that target the same scale target as the dry-run HRA
+// and are not in dry-run mode.
+func (r *HorizontalRunnerAutoscalerReconciler) getLiveCapacityReservations(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) ([]v1alpha1.CapacityReservation, error) {
+	var hraList v1alpha1.HorizontalRunnerAutoscalerList
+
+	if err := r.List(ctx, &hraList, client.InNamespace(hra.Namespace)); err != nil {
+		return nil, err
+	}
+
+	kind := func(ref v1alpha1.ScaleTargetRef) string {
+		if ref.Kind == "" {
+			return "RunnerDeployment"
+		}
+
+		if !isRunnerScaleTargetRef(ref) {
+			return ref.APIVersion + "/" + ref.Kind
+		}
+
+		return ref.Kind
+	}
+
+	var reservations []v1alpha1.CapacityReservation
+
+	for i := range hraList.Items {
+		live := &hraList.Items[i]
+
+		if live.Name == hra.Name || live.Spec.DryRun {
+			continue
+		}
+
+		if live.Spec.ScaleTargetRef.Name != hra.Spec.ScaleTargetRef.Name || kind(live.Spec.ScaleTargetRef) != kind(hra.Spec.ScaleTargetRef) {
+			continue
+		}
+
+		reservations = append(reservations, getCapacityReservations(live)...)
+	}
+
+	return reservations, nil
+}
+
+// syncCapacityReservations reconciles the capacity reservations made for workflow jobs against the status of the jobs on GitHub,
+// so that a lost "completed" webhook delivery doesn't leave the scale target over-provisioned until the reservation expires,
+// and a job that has been queued for long doesn't lose its reservation too early.
+//
+// A reservation is expired when its job has completed or no longer exists, extended when its job is still queued,
+// and kept as is when its job is in progress or the status of its job could not be determined.
+// At most capacityReservationSyncLookups jobs are looked up per sync, and the rest are kept as is until their turn comes in the following syncs.
+// It returns the updated reservations, and true when any reservation has been changed.
+func (r *HorizontalRunnerAutoscalerReconciler) syncCapacityReservations(ctx context.Context, log logr.Logger, ghc *arcgithub.Client, hra v1alpha1.HorizontalRunnerAutoscaler, now time.Time) ([]v1alpha1.CapacityReservation, bool) {
+	var (
+		reservations []v1alpha1.CapacityReservation
+		changed      bool
+	)
+
+	period := r.capacityReservationSyncPeriod()
+
+	key := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}
+
+	lookups, cursor := workflowJobsToLookUp(hra.Status.CapacityReservations, now, r.capacityReservationSyncTimes.cursor(key), r.capacityReservationSyncLookups())
+
+	r.capacityReservationSyncTimes.setCursor(key, cursor)
+
+	for _, res := range hra.Status.CapacityReservations {
+		if res.WorkflowJobID == 0 || res.Repository == "" || !res.ExpirationTime.Time.After(now) || !lookups[res.WorkflowJobID] {
+			reservations = append(reservations, res)
+			continue
+		}
+
+		log := log.WithValues("workflowJob.ID", res.WorkflowJobID, "repository", res.Repository)
+
+		status, err := getWorkflowJobStatus(ctx, ghc, res.Repository, res.WorkflowJobID)
+		if err != nil {
+			log.Error(err, "Unable to get the workflow job status. The capacity reservation is kept as is")
+
+			reservations = append(reservations, res)
+			continue
+		}
+
+		switch status {
+		case "", "completed":
+			log.V(1).Info("Expiring the capacity reservation for the workflow job that is no longer queued or in progress", "status", status)
+
+			changed = true
+		case "queued":
+			duration := capacityReservationDuration(hra, res)
+
+			// Extend the reservation only when it's about to expire before the next sync, to avoid updating the HRA on every sync
+			if res.ExpirationTime.Time.Sub(now) < 2*period && duration > 0 {
+				res.ExpirationTime = metav1.Time{Time: now.Add(duration)}
+
+				log.V(1).Info("Extending the capacity reservation for the workflow job that is still queued", "expirationTime", res.ExpirationTime)
+
+				changed = true
+			}
+
+			reservations = append(reservations, res)
+		default:
+			reservations = append(reservations, res)
+		}
+	}
+
+	return reservations, changed
+}
+
+// workflowJobsToLookUp returns the IDs of up to limit workflow jobs of the unexpired reservations to be looked up in this sync,
+// starting from the one next to the cursor in the order of the job IDs and wrapping around,
+// so that every job is looked up in turn no matter how many reservations there are.
+// It also returns the cursor for the next sync, which is the last job ID to be looked up.
+func workflowJobsToLookUp(reservations []v1alpha1.CapacityReservation, now time.Time, cursor int64, limit int) (map[int64]bool, int64) {
+	var ids []int64
+
+	seen := map[int64]bool{}
+
+	for _, res := range reservations {
+		if res.WorkflowJobID == 0 || res.Repository == "" || !res.ExpirationTime.Time.After(now) || seen[res.WorkflowJobID] {
+			continue
+		}
+
+		seen[res.WorkflowJobID] = true
+
+		ids = append(ids, res.WorkflowJobID)
+	}
+
+	if len(ids) <= limit {
+		return seen, cursor
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	start := sort.Search(len(ids), func(i int) bool {
+		return ids[i] > cursor
+	})
+
+	lookups := map[int64]bool{}
+
+	var last int64
+
+	for i := 0; i < limit; i++ {
+		last = ids[(start+i)%len(ids)]
+
+		lookups[last] = true
+	}
+
+	return lookups, last
+}
+
+// capacityReservationDuration returns the duration of the scale-up trigger that resulted in the reservation,
+// or the original duration of the reservation when the trigger is unknown.
+func capacityReservationDuration(hra v1alpha1.HorizontalRunnerAutoscaler, res v1alpha1.CapacityReservation) time.Duration {
+	if i := res.ScaleUpTriggerIndex; i != nil && *i >= 0 && *i < len(hra.Spec.ScaleUpTriggers) {
+		return getScaleUpTriggerDuration(hra.Spec.ScaleUpTriggers[*i]).Duration
+	}
+
+	if res.EffectiveTime.IsZero() {
+		return 0
+	}
+
+	return res.ExpirationTime.Time.Sub(res.EffectiveTime.Time)
+}
+
+// getWorkflowJobStatus returns the status of the workflow job, or an empty string when the job doesn't exist.
+func getWorkflowJobStatus(ctx context.Context, ghc *arcgithub.Client, repository string, jobID int64) (string, error) {
+	parts := strings.Split(repository, "/")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid repository name: %q", repository)
+	}
+
+	job, res, err := ghc.Actions.GetWorkflowJobByID(ctx, parts[0], parts[1], jobID)
+	if res != nil && res.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return job.GetStatus(), nil
+}
@@ -0,0 +1,244 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestSyncCapacityReservations(t *testing.T) {
+	mux := http.NewServeMux()
+
+	for id, status := range map[int]string{1: "queued", 2: "in_progress", 3: "completed", 4: "queued"} {
+		id, status := id, status
+
+		mux.HandleFunc(fmt.Sprintf("/repos/test/valid/actions/jobs/%d", id), func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"id": %d, "status": %q}`, id, status)
+		})
+	}
+
+	mux.HandleFunc("/repos/test/valid/actions/jobs/5", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ghc := newGithubClient(server)
+
+	now := time.Now().Round(time.Second)
+
+	triggerIndex := 0
+
+	reservation := func(jobID int64, expiresIn time.Duration) v1alpha1.CapacityReservation {
+		return v1alpha1.CapacityReservation{
+			EffectiveTime:       metav1.Time{Time: now.Add(expiresIn - 10*time.Minute)},
+			ExpirationTime:      metav1.Time{Time: now.Add(expiresIn)},
+			Replicas:            1,
+			ScaleUpTriggerIndex: &triggerIndex,
+			WorkflowJobID:       jobID,
+			Repository:          "test/valid",
+		}
+	}
+
+	extended := func(res v1alpha1.CapacityReservation) v1alpha1.CapacityReservation {
+		res.ExpirationTime = metav1.Time{Time: now.Add(30 * time.Minute)}
+		return res
+	}
+
+	withoutJob := v1alpha1.CapacityReservation{
+		ExpirationTime: metav1.Time{Time: now.Add(time.Minute)},
+		Replicas:       1,
+	}
+
+	expired := reservation(3, -time.Minute)
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleUpTriggers: []v1alpha1.ScaleUpTrigger{
+				{Duration: metav1.Duration{Duration: 30 * time.Minute}},
+			},
+		},
+		Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+			CapacityReservations: []v1alpha1.CapacityReservation{
+				// Queued and about to expire
+				reservation(1, time.Minute),
+				// In progress
+				reservation(2, time.Minute),
+				// Completed
+				reservation(3, time.Minute),
+				// Queued but far from expiration
+				reservation(4, 9*time.Minute),
+				// Unable to get the status
+				reservation(5, time.Minute),
+				// Not found
+				reservation(6, time.Minute),
+				withoutJob,
+				expired,
+			},
+		},
+	}
+
+	r := &HorizontalRunnerAutoscalerReconciler{}
+
+	log := zap.New(func(o *zap.Options) {
+		o.Development = true
+	})
+
+	got, changed := r.syncCapacityReservations(context.Background(), log, ghc, hra, now)
+
+	if !changed {
+		t.Error("capacity reservations are expected to be changed")
+	}
+
+	want := []v1alpha1.CapacityReservation{
+		extended(reservation(1, time.Minute)),
+		reservation(2, time.Minute),
+		reservation(4, 9*time.Minute),
+		reservation(5, time.Minute),
+		withoutJob,
+		expired,
+	}
+
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("unexpected capacity reservations: (-want, +got)\n%s", d)
+	}
+
+	if !hasWorkflowJobCapacityReservations(hra, now) {
+		t.Error("the HRA is expected to have capacity reservations for workflow jobs")
+	}
+
+	if hasWorkflowJobCapacityReservations(v1alpha1.HorizontalRunnerAutoscaler{
+		Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+			CapacityReservations: []v1alpha1.CapacityReservation{withoutJob, expired},
+		},
+	}, now) {
+		t.Error("the HRA is expected to have no capacity reservations for workflow jobs")
+	}
+}
+
+func TestSyncCapacityReservationsLookupLimit(t *testing.T) {
+	var lookups []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups = append(lookups, r.URL.Path)
+
+		fmt.Fprint(w, `{"status": "in_progress"}`)
+	}))
+	defer server.Close()
+
+	ghc := newGithubClient(server)
+
+	now := time.Now().Round(time.Second)
+
+	var reservations []v1alpha1.CapacityReservation
+
+	// In the reverse order of the job IDs, to verify that the jobs are rotated in the order of the IDs
+	for _, id := range []int64{5, 4, 3, 2, 1} {
+		reservations = append(reservations, v1alpha1.CapacityReservation{
+			ExpirationTime: metav1.Time{Time: now.Add(10 * time.Minute)},
+			Replicas:       1,
+			WorkflowJobID:  id,
+			Repository:     "test/valid",
+		})
+	}
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+		Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+			CapacityReservations: reservations,
+		},
+	}
+
+	r := &HorizontalRunnerAutoscalerReconciler{
+		CapacityReservationSyncLookups: 2,
+	}
+
+	log := zap.New(func(o *zap.Options) {
+		o.Development = true
+	})
+
+	job := func(id int) string {
+		return fmt.Sprintf("/repos/test/valid/actions/jobs/%d", id)
+	}
+
+	// The jobs are looked up in the order of the reservations
+	for _, want := range [][]string{
+		{job(2), job(1)},
+		{job(4), job(3)},
+		// Wraps around to the first job
+		{job(5), job(1)},
+	} {
+		lookups = nil
+
+		got, changed := r.syncCapacityReservations(context.Background(), log, ghc, hra, now)
+
+		if changed {
+			t.Error("capacity reservations are not expected to be changed")
+		}
+
+		if d := cmp.Diff(reservations, got); d != "" {
+			t.Errorf("unexpected capacity reservations: (-want, +got)\n%s", d)
+		}
+
+		if d := cmp.Diff(want, lookups); d != "" {
+			t.Errorf("unexpected lookups: (-want, +got)\n%s", d)
+		}
+	}
+}
+
+func TestGetLiveCapacityReservations(t *testing.T) {
+	now := time.Now().Round(time.Second)
+
+	reservation := func(replicas int) v1alpha1.CapacityReservation {
+		return v1alpha1.CapacityReservation{
+			ExpirationTime: metav1.Time{Time: now.Add(time.Minute)},
+			Replicas:       replicas,
+		}
+	}
+
+	hra := func(name, kind, target string, dryRun bool, reservations ...v1alpha1.CapacityReservation) *v1alpha1.HorizontalRunnerAutoscaler {
+		return &v1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: v1alpha1.ScaleTargetRef{Kind: kind, Name: target},
+				DryRun:         dryRun,
+			},
+			Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				CapacityReservations: reservations,
+			},
+		}
+	}
+
+	dryRun := hra("dryrun", "", "rd", true, reservation(1))
+
+	r := &HorizontalRunnerAutoscalerReconciler{
+		Client: fake.NewClientBuilder().WithScheme(sc).WithRuntimeObjects(
+			dryRun,
+			hra("live", "RunnerDeployment", "rd", false, reservation(2)),
+			hra("another-dryrun", "RunnerDeployment", "rd", true, reservation(3)),
+			hra("another-target", "RunnerDeployment", "rd2", false, reservation(4)),
+			hra("another-kind", "RunnerSet", "rd", false, reservation(5)),
+		).Build(),
+	}
+
+	got, err := r.getLiveCapacityReservations(context.Background(), *dryRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []v1alpha1.CapacityReservation{reservation(2)}
+
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("unexpected capacity reservations: (-want, +got)\n%s", d)
+	}
+}
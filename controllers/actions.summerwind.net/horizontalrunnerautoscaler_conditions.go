@@ -0,0 +1,147 @@
+package actionssummerwindnet
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-github/v47/github"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Reasons of the HRA status conditions. They follow the ones of Kubernetes HPA where applicable,
+// so that `kubectl describe hra` reads like `kubectl describe hpa`.
+const (
+	// AbleToScale
+	hraReasonSucceededRescale  = "SucceededRescale"
+	hraReasonReadyForNewScale  = "ReadyForNewScale"
+	hraReasonFailedUpdateScale = "FailedUpdateScale"
+	hraReasonDryRun            = "DryRun"
+
+	// ScalingActive
+	hraReasonValidMetricFound      = "ValidMetricFound"
+	hraReasonNoMetrics             = "NoMetrics"
+	hraReasonGitHubAPIError        = "GitHubAPIError"
+	hraReasonFailedComputeMetrics  = "FailedComputeMetrics"
+	hraReasonFailedGetGitHubClient = "FailedGetGitHubClient"
+
+	// ScalingLimited
+	hraReasonInsufficientCapacity = "InsufficientCapacity"
+	hraReasonTooManyReplicas      = "TooManyReplicas"
+	hraReasonTooFewReplicas       = "TooFewReplicas"
+	hraReasonScaleDownDelayed     = "ScaleDownDelayed"
+	hraReasonScalingBehavior      = "ScalingBehavior"
+	hraReasonDesiredWithinRange   = "DesiredWithinRange"
+)
+
+// setHorizontalRunnerAutoscalerConditions sets the conditions on the HRA's status.
+// The last transition time of each condition is updated only when its status changes.
+func setHorizontalRunnerAutoscalerConditions(hra *v1alpha1.HorizontalRunnerAutoscaler, conditions ...metav1.Condition) {
+	for _, c := range conditions {
+		c.ObservedGeneration = hra.Generation
+
+		meta.SetStatusCondition(&hra.Status.Conditions, c)
+	}
+}
+
+// isGitHubAPIError returns true when the error is returned by the GitHub API, including rate limiting.
+func isGitHubAPIError(err error) bool {
+	var (
+		errRes    *github.ErrorResponse
+		rateLimit *github.RateLimitError
+		abuse     *github.AbuseRateLimitError
+	)
+
+	return errors.As(err, &errRes) || errors.As(err, &rateLimit) || errors.As(err, &abuse)
+}
+
+// newAbleToScaleCondition returns the AbleToScale condition after the HRA tried to update the scale target
+// from currentReplicas to desiredReplicas.
+func newAbleToScaleCondition(dryRun bool, currentReplicas, desiredReplicas int, err error) metav1.Condition {
+	c := metav1.Condition{Type: v1alpha1.HorizontalRunnerAutoscalerConditionAbleToScale}
+
+	switch {
+	case dryRun:
+		c.Status = metav1.ConditionFalse
+		c.Reason = hraReasonDryRun
+		c.Message = fmt.Sprintf("the HRA would update the scale target to %d replicas but doesn't in dry-run mode", desiredReplicas)
+	case err != nil:
+		c.Status = metav1.ConditionFalse
+		c.Reason = hraReasonFailedUpdateScale
+		c.Message = fmt.Sprintf("the HRA was unable to update the scale target: %v", err)
+	case currentReplicas != desiredReplicas:
+		c.Status = metav1.ConditionTrue
+		c.Reason = hraReasonSucceededRescale
+		c.Message = fmt.Sprintf("the HRA updated the scale target from %d to %d replicas", currentReplicas, desiredReplicas)
+	default:
+		c.Status = metav1.ConditionTrue
+		c.Reason = hraReasonReadyForNewScale
+		c.Message = "the HRA is ready to update the scale target"
+	}
+
+	return c
+}
+
+// newScalingActiveCondition returns the ScalingActive condition after the HRA computed the metrics.
+func newScalingActiveCondition(metrics []v1alpha1.MetricStatus, err error) metav1.Condition {
+	c := metav1.Condition{Type: v1alpha1.HorizontalRunnerAutoscalerConditionScalingActive}
+
+	switch {
+	case err != nil && isGitHubAPIError(err):
+		c.Status = metav1.ConditionFalse
+		c.Reason = hraReasonGitHubAPIError
+		c.Message = fmt.Sprintf("the HRA was unable to compute the metrics due to GitHub API error: %v", err)
+	case err != nil:
+		c.Status = metav1.ConditionFalse
+		c.Reason = hraReasonFailedComputeMetrics
+		c.Message = fmt.Sprintf("the HRA was unable to compute the metrics: %v", err)
+	case len(metrics) == 0:
+		// Scaling is still active as the capacity reservations added by the webhook-based autoscaler are taken into account
+		c.Status = metav1.ConditionTrue
+		c.Reason = hraReasonNoMetrics
+		c.Message = "no metrics are configured, so the desired replicas is determined by minReplicas and capacity reservations"
+	default:
+		c.Status = metav1.ConditionTrue
+		c.Reason = hraReasonValidMetricFound
+		c.Message = "the HRA was able to compute the desired replicas from the metrics"
+	}
+
+	return c
+}
+
+// newScalingLimitedCondition returns the ScalingLimited condition from the computation of the desired replicas
+// and the desired replicas after the scaling behavior is applied.
+func newScalingLimitedCondition(c *replicasComputation, desiredReplicas int) metav1.Condition {
+	cond := metav1.Condition{
+		Type:   v1alpha1.HorizontalRunnerAutoscalerConditionScalingLimited,
+		Status: metav1.ConditionTrue,
+	}
+
+	unbounded := c.suggestedReplicas + c.reservedReplicas
+
+	switch {
+	case c.capacityLimit != nil:
+		cond.Reason = hraReasonInsufficientCapacity
+		cond.Message = fmt.Sprintf("the desired replicas of %d is capped to %d by the cluster capacity: %s", c.capacityLimitedReplicas, desiredReplicas, c.capacityLimit.reason)
+	case c.scaleDownDelayUntil != nil:
+		cond.Reason = hraReasonScaleDownDelayed
+		cond.Message = fmt.Sprintf("the desired replicas of %d is kept at %d until the scale down delay passes at %s", unbounded, c.desiredReplicas, c.scaleDownDelayUntil.Format(time.RFC3339))
+	case c.maxReplicas != nil && unbounded > *c.maxReplicas:
+		cond.Reason = hraReasonTooManyReplicas
+		cond.Message = fmt.Sprintf("the desired replicas of %d is capped by maxReplicas of %d", unbounded, *c.maxReplicas)
+	case unbounded < c.minReplicas:
+		cond.Reason = hraReasonTooFewReplicas
+		cond.Message = fmt.Sprintf("the desired replicas of %d is raised to minReplicas of %d", unbounded, c.minReplicas)
+	case desiredReplicas != c.desiredReplicas:
+		cond.Reason = hraReasonScalingBehavior
+		cond.Message = fmt.Sprintf("the desired replicas of %d is limited to %d by the scaling behavior", c.desiredReplicas, desiredReplicas)
+	default:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = hraReasonDesiredWithinRange
+		cond.Message = fmt.Sprintf("the desired replicas of %d is within the acceptable range", desiredReplicas)
+	}
+
+	return cond
+}
@@ -0,0 +1,165 @@
+package actionssummerwindnet
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-github/v47/github"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewScalingLimitedCondition(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	until := time.Date(2022, 10, 28, 11, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		computation     replicasComputation
+		desiredReplicas int
+		status          metav1.ConditionStatus
+		reason          string
+		message         string
+	}{
+		{
+			computation:     replicasComputation{desiredReplicas: 10, suggestedReplicas: 8, reservedReplicas: 4, minReplicas: 1, maxReplicas: intPtr(10)},
+			desiredReplicas: 10,
+			status:          metav1.ConditionTrue,
+			reason:          hraReasonTooManyReplicas,
+			message:         "the desired replicas of 12 is capped by maxReplicas of 10",
+		},
+		{
+			computation:     replicasComputation{desiredReplicas: 2, suggestedReplicas: 1, minReplicas: 2, maxReplicas: intPtr(10)},
+			desiredReplicas: 2,
+			status:          metav1.ConditionTrue,
+			reason:          hraReasonTooFewReplicas,
+			message:         "the desired replicas of 1 is raised to minReplicas of 2",
+		},
+		{
+			computation:     replicasComputation{desiredReplicas: 5, suggestedReplicas: 3, minReplicas: 1, maxReplicas: intPtr(10), scaleDownDelayUntil: &until},
+			desiredReplicas: 5,
+			status:          metav1.ConditionTrue,
+			reason:          hraReasonScaleDownDelayed,
+			message:         "the desired replicas of 3 is kept at 5 until the scale down delay passes at 2022-10-28T11:00:00Z",
+		},
+		{
+			computation:     replicasComputation{desiredReplicas: 8, suggestedReplicas: 8, minReplicas: 1, maxReplicas: intPtr(10)},
+			desiredReplicas: 4,
+			status:          metav1.ConditionTrue,
+			reason:          hraReasonScalingBehavior,
+			message:         "the desired replicas of 8 is limited to 4 by the scaling behavior",
+		},
+		{
+			computation:     replicasComputation{desiredReplicas: 3, suggestedReplicas: 2, reservedReplicas: 1, minReplicas: 1, maxReplicas: intPtr(10)},
+			desiredReplicas: 3,
+			status:          metav1.ConditionFalse,
+			reason:          hraReasonDesiredWithinRange,
+			message:         "the desired replicas of 3 is within the acceptable range",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.reason, func(t *testing.T) {
+			got := newScalingLimitedCondition(&tc.computation, tc.desiredReplicas)
+
+			if got.Type != v1alpha1.HorizontalRunnerAutoscalerConditionScalingLimited {
+				t.Errorf("unexpected type: %s", got.Type)
+			}
+
+			if got.Status != tc.status || got.Reason != tc.reason || got.Message != tc.message {
+				t.Errorf("unexpected condition: want %s %s %q, got %s %s %q", tc.status, tc.reason, tc.message, got.Status, got.Reason, got.Message)
+			}
+		})
+	}
+}
+
+func TestNewScalingActiveCondition(t *testing.T) {
+	metrics := []v1alpha1.MetricStatus{{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy, Value: "busy=1 replicas=2"}}
+
+	testcases := []struct {
+		name    string
+		metrics []v1alpha1.MetricStatus
+		err     error
+		status  metav1.ConditionStatus
+		reason  string
+	}{
+		{
+			name:    "valid metric",
+			metrics: metrics,
+			status:  metav1.ConditionTrue,
+			reason:  hraReasonValidMetricFound,
+		},
+		{
+			name:   "no metrics",
+			status: metav1.ConditionTrue,
+			reason: hraReasonNoMetrics,
+		},
+		{
+			name:   "rate limited",
+			err:    fmt.Errorf("failed to list runners: %w", &github.RateLimitError{Message: "API rate limit exceeded"}),
+			status: metav1.ConditionFalse,
+			reason: hraReasonGitHubAPIError,
+		},
+		{
+			name:   "invalid metric",
+			err:    errors.New("validating autoscaling metrics: unsupported metric type \"Foo\""),
+			status: metav1.ConditionFalse,
+			reason: hraReasonFailedComputeMetrics,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			got := newScalingActiveCondition(tc.metrics, tc.err)
+
+			if got.Status != tc.status || got.Reason != tc.reason {
+				t.Errorf("unexpected condition: want %s %s, got %s %s: %s", tc.status, tc.reason, got.Status, got.Reason, got.Message)
+			}
+		})
+	}
+}
+
+func TestSetHorizontalRunnerAutoscalerConditions(t *testing.T) {
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+	}
+
+	setHorizontalRunnerAutoscalerConditions(hra, newAbleToScaleCondition(false, 1, 3, nil))
+
+	if len(hra.Status.Conditions) != 1 {
+		t.Fatalf("unexpected number of conditions: %d", len(hra.Status.Conditions))
+	}
+
+	transitioned := hra.Status.Conditions[0].LastTransitionTime
+
+	if transitioned.IsZero() {
+		t.Errorf("last transition time is not set")
+	}
+
+	if g := hra.Status.Conditions[0].ObservedGeneration; g != 2 {
+		t.Errorf("unexpected observed generation: want 2, got %d", g)
+	}
+
+	// The condition stays true, so only the reason and the message are updated
+	setHorizontalRunnerAutoscalerConditions(hra, newAbleToScaleCondition(false, 3, 3, nil))
+
+	got := hra.Status.Conditions[0]
+
+	if got.Reason != hraReasonReadyForNewScale || !got.LastTransitionTime.Equal(&transitioned) {
+		t.Errorf("unexpected condition: %+v", got)
+	}
+
+	setHorizontalRunnerAutoscalerConditions(hra, newAbleToScaleCondition(false, 3, 4, errors.New("conflict")))
+
+	if got := hra.Status.Conditions[0]; got.Status != metav1.ConditionFalse || got.Reason != hraReasonFailedUpdateScale {
+		t.Errorf("unexpected condition: %+v", got)
+	}
+}
@@ -19,16 +19,21 @@ package actionssummerwindnet
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/go-logr/logr"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/scale"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -53,6 +58,34 @@ type HorizontalRunnerAutoscalerReconciler struct {
 	Scheme                *runtime.Scheme
 	DefaultScaleDownDelay time.Duration
 	Name                  string
+
+	// PrometheusHTTPClient is used to run the query of the PrometheusQuery metric.
+	// A client with the default timeout is used when omitted.
+	PrometheusHTTPClient *http.Client
+
+	// WorkflowRunPoller is shared among HRAs to poll the workflow runs of the TotalNumberOfQueuedAndInProgressWorkflowRuns metric.
+	// A poller with the default interval and concurrency is used when omitted.
+	WorkflowRunPoller *WorkflowRunPoller
+
+	// CapacityReservationSyncPeriod is the minimum interval between syncs of the capacity reservations of an HRA
+	// with the status of their workflow jobs on GitHub.
+	// DefaultCapacityReservationSyncPeriod is used when omitted.
+	CapacityReservationSyncPeriod time.Duration
+
+	// CapacityReservationSyncLookups is the maximum number of workflow jobs looked up on GitHub per sync of the capacity reservations of an HRA.
+	// DefaultCapacityReservationSyncLookups is used when omitted.
+	CapacityReservationSyncLookups int
+
+	// ScaleClient is used to scale the scale target via the scale subresource when it's neither RunnerDeployment nor RunnerSet.
+	// HRAs targeting such resources are not reconciled when omitted.
+	ScaleClient scale.ScalesGetter
+
+	workflowRunPollerOnce        sync.Once
+	repositoryDiscovery          repositoryDiscoveryCache
+	capacityReservationSyncTimes capacityReservationSyncTimes
+
+	// clock returns the current time. It's replaced by the autoscale simulator to reconcile HRAs on a virtual clock.
+	clock func() time.Time
 }
 
 const defaultReplicas = 1
@@ -62,6 +95,8 @@ const defaultReplicas = 1
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers/finalizers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=resourcequotas,verbs=get;list;watch
 
 func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("horizontalrunnerautoscaler", req.NamespacedName)
@@ -73,12 +108,23 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 
 	if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
 		r.GitHubClient.DeinitForHRA(&hra)
+		r.capacityReservationSyncTimes.delete(req.NamespacedName)
 
 		return ctrl.Result{}, nil
 	}
 
 	metrics.SetHorizontalRunnerAutoscalerSpec(hra.ObjectMeta, hra.Spec)
 
+	if isScaledByKEDA(hra) {
+		log.V(1).Info("Skipping this HRA as it is scaled by KEDA via the external scaler")
+
+		return ctrl.Result{}, nil
+	}
+
+	if !isRunnerScaleTargetRef(hra.Spec.ScaleTargetRef) {
+		return r.reconcileScaleSubresource(ctx, req, log, hra)
+	}
+
 	kind := hra.Spec.ScaleTargetRef.Kind
 
 	switch kind {
@@ -104,7 +150,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 
 			var effectiveTime *time.Time
 
-			for _, r := range hra.Spec.CapacityReservations {
+			for _, r := range getCapacityReservations(&hra) {
 				t := r.EffectiveTime
 				if effectiveTime == nil || effectiveTime.Before(t.Time) {
 					effectiveTime = &t.Time
@@ -146,55 +192,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 			return ctrl.Result{}, nil
 		}
 
-		var replicas *int
-
-		if rs.Spec.Replicas != nil {
-			v := int(*rs.Spec.Replicas)
-			replicas = &v
-		}
-
-		st := scaleTarget{
-			st:         rs.Name,
-			kind:       "runnerset",
-			enterprise: rs.Spec.Enterprise,
-			org:        rs.Spec.Organization,
-			repo:       rs.Spec.Repository,
-			replicas:   replicas,
-			labels:     rs.Spec.RunnerConfig.Labels,
-			getRunnerMap: func() (map[string]struct{}, error) {
-				// return the list of runners in namespace. Horizontal Runner Autoscaler should only be responsible for scaling resources in its own ns.
-				var runnerPodList corev1.PodList
-
-				var opts []client.ListOption
-
-				opts = append(opts, client.InNamespace(rs.Namespace))
-
-				selector, err := metav1.LabelSelectorAsSelector(rs.Spec.Selector)
-				if err != nil {
-					return nil, err
-				}
-
-				opts = append(opts, client.MatchingLabelsSelector{Selector: selector})
-
-				r.Log.V(2).Info("Finding runnerset's runner pods with selector", "ns", rs.Namespace)
-
-				if err := r.List(
-					ctx,
-					&runnerPodList,
-					opts...,
-				); err != nil {
-					if !kerrors.IsNotFound(err) {
-						return nil, err
-					}
-				}
-				runnerMap := make(map[string]struct{})
-				for _, items := range runnerPodList.Items {
-					runnerMap[items.Name] = struct{}{}
-				}
-
-				return runnerMap, nil
-			},
-		}
+		st := r.scaleTargetFromRS(ctx, rs)
 
 		return r.reconcile(ctx, req, log, hra, st, func(newDesiredReplicas int) error {
 			var replicas *int
@@ -208,7 +206,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 
 			var effectiveTime *time.Time
 
-			for _, r := range hra.Spec.CapacityReservations {
+			for _, r := range getCapacityReservations(&hra) {
 				t := r.EffectiveTime
 				if effectiveTime == nil || effectiveTime.Before(t.Time) {
 					effectiveTime = &t.Time
@@ -240,7 +238,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 		})
 	}
 
-	log.Info(fmt.Sprintf("Unsupported scale target %s %s: kind %s is not supported. valid kinds are %s and %s", kind, hra.Spec.ScaleTargetRef.Name, kind, "RunnerDeployment", "RunnerSet"))
+	log.Info(fmt.Sprintf("Unsupported scale target %s %s: kind %s is not supported. valid kinds are %s and %s, or any kind that exposes the scale subresource with apiVersion", kind, hra.Spec.ScaleTargetRef.Name, kind, "RunnerDeployment", "RunnerSet"))
 
 	return ctrl.Result{}, nil
 }
@@ -252,8 +250,10 @@ func (r *HorizontalRunnerAutoscalerReconciler) scaleTargetFromRD(ctx context.Con
 		enterprise: rd.Spec.Template.Spec.Enterprise,
 		org:        rd.Spec.Template.Spec.Organization,
 		repo:       rd.Spec.Template.Spec.Repository,
+		group:      rd.Spec.Template.Spec.Group,
 		replicas:   rd.Spec.Replicas,
-		labels:     rd.Spec.Template.Spec.RunnerConfig.Labels,
+		labels:     newRunnerLabelMatcher(rd.Spec.Template.Spec.RunnerConfig, rd.Spec.Template.Spec.NodeSelector),
+		podSpec:    runnerPodSpecFromRunnerSpec(rd.Spec.Template.Spec),
 		getRunnerMap: func() (map[string]struct{}, error) {
 			// return the list of runners in namespace. Horizontal Runner Autoscaler should only be responsible for scaling resources in its own ns.
 			var runnerList v1alpha1.RunnerList
@@ -292,17 +292,81 @@ func (r *HorizontalRunnerAutoscalerReconciler) scaleTargetFromRD(ctx context.Con
 	return st
 }
 
+func (r *HorizontalRunnerAutoscalerReconciler) scaleTargetFromRS(ctx context.Context, rs v1alpha1.RunnerSet) scaleTarget {
+	var replicas *int
+
+	if rs.Spec.Replicas != nil {
+		v := int(*rs.Spec.Replicas)
+		replicas = &v
+	}
+
+	st := scaleTarget{
+		st:         rs.Name,
+		kind:       "runnerset",
+		enterprise: rs.Spec.Enterprise,
+		org:        rs.Spec.Organization,
+		repo:       rs.Spec.Repository,
+		group:      rs.Spec.Group,
+		replicas:   replicas,
+		labels:     newRunnerLabelMatcher(rs.Spec.RunnerConfig, rs.Spec.Template.Spec.NodeSelector),
+		podSpec:    rs.Spec.Template.Spec,
+		getRunnerMap: func() (map[string]struct{}, error) {
+			// return the list of runners in namespace. Horizontal Runner Autoscaler should only be responsible for scaling resources in its own ns.
+			var runnerPodList corev1.PodList
+
+			var opts []client.ListOption
+
+			opts = append(opts, client.InNamespace(rs.Namespace))
+
+			selector, err := metav1.LabelSelectorAsSelector(rs.Spec.Selector)
+			if err != nil {
+				return nil, err
+			}
+
+			opts = append(opts, client.MatchingLabelsSelector{Selector: selector})
+
+			r.Log.V(2).Info("Finding runnerset's runner pods with selector", "ns", rs.Namespace)
+
+			if err := r.List(
+				ctx,
+				&runnerPodList,
+				opts...,
+			); err != nil {
+				if !kerrors.IsNotFound(err) {
+					return nil, err
+				}
+			}
+			runnerMap := make(map[string]struct{})
+			for _, items := range runnerPodList.Items {
+				runnerMap[items.Name] = struct{}{}
+			}
+
+			return runnerMap, nil
+		},
+	}
+
+	return st
+}
+
 type scaleTarget struct {
 	st, kind              string
 	enterprise, repo, org string
+	group                 string
 	replicas              *int
-	labels                []string
+	labels                *runnerLabelMatcher
+
+	// podSpec is the spec of the runner pods, which is used to compute the capacity limit
+	podSpec corev1.PodSpec
+
+	// selector selects the runner pods of the scale target exposed via the scale subresource.
+	// The runner pods are selected by the name of the RunnerDeployment or RunnerSet when omitted.
+	selector labels.Selector
 
 	getRunnerMap func() (map[string]struct{}, error)
 }
 
 func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, req ctrl.Request, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget, updatedDesiredReplicas func(int) error) (ctrl.Result, error) {
-	now := time.Now()
+	now := r.now()
 
 	minReplicas, active, upcoming, err := r.getMinReplicas(log, now, hra)
 	if err != nil {
@@ -313,50 +377,201 @@ func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, re
 
 	ghc, err := r.GitHubClient.InitForHRA(context.Background(), &hra)
 	if err != nil {
+		r.patchStatusConditions(ctx, log, hra, metav1.Condition{
+			Type:    v1alpha1.HorizontalRunnerAutoscalerConditionScalingActive,
+			Status:  metav1.ConditionFalse,
+			Reason:  hraReasonFailedGetGitHubClient,
+			Message: fmt.Sprintf("the HRA was unable to initialize the GitHub client: %v", err),
+		})
+
 		return ctrl.Result{}, err
 	}
 
-	newDesiredReplicas, err := r.computeReplicasWithCache(ghc, log, now, st, hra, minReplicas)
+	var (
+		reservations = hra.Status.CapacityReservations
+		changed      bool
+	)
+
+	syncDue := hasWorkflowJobCapacityReservations(hra, now) && r.capacityReservationSyncTimes.due(req.NamespacedName, now, r.capacityReservationSyncPeriod())
+
+	if syncDue {
+		reservations, changed = r.syncCapacityReservations(ctx, log, ghc, hra, now)
+	}
+
+	// Expired reservations are garbage-collected here, as the webhook-based autoscaler removes them only when it receives another event for the HRA
+	if valid := filterValidCapacityReservations(reservations, now); len(valid) != len(reservations) {
+		log.V(1).Info("Removing expired capacity reservations", "expired", len(reservations)-len(valid))
+
+		reservations = valid
+		changed = true
+	}
+
+	if changed {
+		synced := hra.DeepCopy()
+		synced.Status.CapacityReservations = reservations
+
+		// The optimistic lock prevents us from dropping the reservations added by the webhook-based autoscaler in the meantime
+		if err := r.Status().Patch(ctx, synced, client.MergeFromWithOptions(&hra, client.MergeFromWithOptimisticLock{})); err != nil {
+			if kerrors.IsConflict(err) {
+				log.V(1).Info("Retrying to update capacity reservations due to conflict")
+
+				return ctrl.Result{Requeue: true}, nil
+			}
+
+			return ctrl.Result{}, fmt.Errorf("patching horizontalrunnerautoscaler status to update capacity reservations: %w", err)
+		}
+
+		hra = *synced
+	}
+
+	if syncDue {
+		r.capacityReservationSyncTimes.set(req.NamespacedName, now)
+	}
+
+	// Fields other than minReplicas, like maxReplicas and metrics, can be overridden on schedule too
+	overridden := applyScheduledOverrides(hra, active)
+
+	if hra.Spec.DryRun {
+		reservations, err := r.getLiveCapacityReservations(ctx, hra)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// The webhook-based autoscaler never adds capacity reservations to dry-run HRAs,
+		// so the reservations of the live HRAs of the same scale target are taken into account instead.
+		overridden = *overridden.DeepCopy()
+		overridden.Status.CapacityReservations = append(overridden.Status.CapacityReservations, reservations...)
+	}
+
+	computation, err := r.computeReplicasWithCache(ctx, ghc, log, now, st, overridden, minReplicas)
 	if err != nil {
 		r.Recorder.Event(&hra, corev1.EventTypeNormal, "RunnerAutoscalingFailure", err.Error())
 
 		log.Error(err, "Could not compute replicas")
 
+		r.patchStatusConditions(ctx, log, hra, newScalingActiveCondition(nil, err))
+
 		return ctrl.Result{}, err
 	}
 
-	if err := updatedDesiredReplicas(newDesiredReplicas); err != nil {
+	newDesiredReplicas := computation.desiredReplicas
+
+	var behaviorResult *scalingBehaviorResult
+
+	if b := hra.Spec.Behavior; b != nil {
+		behaviorResult, err = applyScalingBehavior(now, *b, hra.Status, minReplicas, newDesiredReplicas)
+		if err != nil {
+			r.Recorder.Event(&hra, corev1.EventTypeNormal, "RunnerAutoscalingFailure", err.Error())
+
+			log.Error(err, "Could not apply scaling behavior")
+
+			return ctrl.Result{}, err
+		}
+
+		if behaviorResult.desiredReplicas != newDesiredReplicas {
+			log.V(1).Info(
+				fmt.Sprintf("Scaling behavior changed desired replicas from %d to %d", newDesiredReplicas, behaviorResult.desiredReplicas),
+			)
+		}
+
+		newDesiredReplicas = behaviorResult.desiredReplicas
+	}
+
+	if l := hra.Spec.CapacityLimit; l != nil {
+		limit, err := r.computeCapacityLimit(ctx, hra, st, *l)
+		if err != nil {
+			// The capacity limit is best-effort, so that e.g. missing permissions to list nodes don't stop scaling
+			r.Recorder.Event(&hra, corev1.EventTypeNormal, "RunnerAutoscalingFailure", fmt.Sprintf("Could not compute capacity limit: %v", err))
+
+			log.Error(err, "Could not compute capacity limit")
+		} else if limit != nil {
+			// The capacity limit never reduces the desired replicas below minReplicas
+			if limit.replicas < minReplicas {
+				limit.replicas = minReplicas
+			}
+
+			if newDesiredReplicas > limit.replicas {
+				log.V(1).Info(
+					fmt.Sprintf("Capacity limit changed desired replicas from %d to %d", newDesiredReplicas, limit.replicas),
+					"reason", limit.reason,
+				)
+
+				computation.capacityLimit = limit
+				computation.capacityLimitedReplicas = newDesiredReplicas
+
+				newDesiredReplicas = limit.replicas
+
+				if behaviorResult != nil && hra.Status.DesiredReplicas != nil {
+					behaviorResult.clampScaleEvent(now, *hra.Status.DesiredReplicas, newDesiredReplicas)
+				}
+			}
+		}
+	}
+
+	var dryRunSummary string
+
+	currentReplicas := getIntOrDefault(st.replicas, defaultReplicas)
+
+	if hra.Spec.DryRun {
+		dryRunSummary = fmt.Sprintf("current=%d %s", currentReplicas, computation)
+
+		if behaviorResult != nil && computation.desiredReplicas != behaviorResult.desiredReplicas {
+			dryRunSummary = fmt.Sprintf("%s behavior=%d", dryRunSummary, behaviorResult.desiredReplicas)
+		}
+
+		log.V(1).Info("Skipped updating the scale target in dry-run mode", "summary", dryRunSummary)
+
+		if hra.Status.DryRunSummary == nil || *hra.Status.DryRunSummary != dryRunSummary {
+			r.Recorder.Event(&hra, corev1.EventTypeNormal, "DryRun", fmt.Sprintf("Would scale %s %s to %d replicas: %s", st.kind, st.st, newDesiredReplicas, dryRunSummary))
+		}
+	} else if err := updatedDesiredReplicas(newDesiredReplicas); err != nil {
+		r.patchStatusConditions(ctx, log, hra, newAbleToScaleCondition(false, currentReplicas, newDesiredReplicas, err))
+
 		return ctrl.Result{}, err
 	}
 
 	updated := hra.DeepCopy()
 
+	// In dry-run mode, the status is updated as if the scale target was scaled,
+	// so that the scale down delay and the scaling behavior are simulated across reconciliations.
+	if dryRunSummary != "" {
+		updated.Status.DryRunSummary = &dryRunSummary
+	} else {
+		updated.Status.DryRunSummary = nil
+	}
+
+	if behaviorResult != nil {
+		updated.Status.Recommendations = behaviorResult.recommendations
+		updated.Status.ScaleEvents = behaviorResult.scaleEvents
+	} else {
+		updated.Status.Recommendations = nil
+		updated.Status.ScaleEvents = nil
+	}
+
 	if hra.Status.DesiredReplicas == nil || *hra.Status.DesiredReplicas != newDesiredReplicas {
 		if (hra.Status.DesiredReplicas == nil && newDesiredReplicas > 1) ||
 			(hra.Status.DesiredReplicas != nil && newDesiredReplicas > *hra.Status.DesiredReplicas) {
 
-			updated.Status.LastSuccessfulScaleOutTime = &metav1.Time{Time: time.Now()}
+			updated.Status.LastSuccessfulScaleOutTime = &metav1.Time{Time: now}
 		}
 
 		updated.Status.DesiredReplicas = &newDesiredReplicas
 	}
 
-	var overridesSummary string
+	if !hra.Spec.DryRun && currentReplicas != newDesiredReplicas {
+		updated.Status.LastScaleTime = &metav1.Time{Time: now}
+	}
 
-	if (active != nil && upcoming == nil) || (active != nil && upcoming != nil && active.Period.EndTime.Before(upcoming.Period.StartTime)) {
-		after := defaultReplicas
-		if hra.Spec.MinReplicas != nil && *hra.Spec.MinReplicas >= 0 {
-			after = *hra.Spec.MinReplicas
-		}
+	updated.Status.CurrentMetrics = computation.metrics
+	updated.Status.ActiveCapacityReservations = len(filterValidCapacityReservations(getCapacityReservations(&overridden), now))
 
-		overridesSummary = fmt.Sprintf("min=%d time=%s", after, active.Period.EndTime)
-	}
+	setHorizontalRunnerAutoscalerConditions(updated,
+		newAbleToScaleCondition(hra.Spec.DryRun, currentReplicas, newDesiredReplicas, nil),
+		newScalingActiveCondition(computation.metrics, nil),
+		newScalingLimitedCondition(computation, newDesiredReplicas),
+	)
 
-	if active == nil && upcoming != nil || (active != nil && upcoming != nil && active.Period.EndTime.After(upcoming.Period.StartTime)) {
-		if upcoming.ScheduledOverride.MinReplicas != nil {
-			overridesSummary = fmt.Sprintf("min=%d time=%s", *upcoming.ScheduledOverride.MinReplicas, upcoming.Period.StartTime)
-		}
-	}
+	overridesSummary := summarizeScheduledOverrides(active, upcoming)
 
 	if overridesSummary != "" {
 		updated.Status.ScheduledOverridesSummary = &overridesSummary
@@ -372,7 +587,45 @@ func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, re
 		}
 	}
 
-	return ctrl.Result{}, nil
+	var requeueAfter time.Duration
+
+	// Sync the capacity reservations periodically even when the sync period of the controller is longer
+	if hasWorkflowJobCapacityReservations(hra, now) {
+		requeueAfter = r.capacityReservationSyncPeriod()
+	}
+
+	// Scale down as soon as the earliest capacity reservation expires
+	if t := getEarliestCapacityReservationExpirationTime(hra, now); !t.IsZero() {
+		if d := t.Sub(now); requeueAfter == 0 || d < requeueAfter {
+			requeueAfter = d
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) now() time.Time {
+	if r.clock != nil {
+		return r.clock()
+	}
+
+	return time.Now()
+}
+
+// patchStatusConditions records the conditions on the HRA's status on a best-effort basis,
+// so that `kubectl describe hra` tells why the HRA failed to scale even though the reconciliation is aborted.
+func (r *HorizontalRunnerAutoscalerReconciler) patchStatusConditions(ctx context.Context, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler, conditions ...metav1.Condition) {
+	updated := hra.DeepCopy()
+
+	setHorizontalRunnerAutoscalerConditions(updated, conditions...)
+
+	if reflect.DeepEqual(hra.Status, updated.Status) {
+		return
+	}
+
+	if err := r.Status().Patch(ctx, updated, client.MergeFrom(&hra)); err != nil {
+		log.Error(err, "Failed to update status conditions")
+	}
 }
 
 func (r *HorizontalRunnerAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -394,9 +647,11 @@ type Override struct {
 	Period            Period
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, *Override, *Override, error) {
-	var minReplicas *int
-	var active, upcoming *Override
+// matchScheduledOverrides returns all the active scheduled overrides in the order of priority,
+// and the upcoming scheduled override that starts the earliest.
+func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) ([]Override, *Override, error) {
+	var active []Override
+	var upcoming *Override
 
 	for _, o := range hra.Spec.ScheduledOverrides {
 		log.V(1).Info(
@@ -404,36 +659,31 @@ func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(log logr.
 			"now", now,
 			"startTime", o.StartTime,
 			"endTime", o.EndTime,
+			"cron", o.Cron,
+			"duration", o.Duration.Duration,
+			"timeZone", o.TimeZone,
 			"frequency", o.RecurrenceRule.Frequency,
 			"untilTime", o.RecurrenceRule.UntilTime,
 		)
 
-		a, u, err := MatchSchedule(
-			now, o.StartTime.Time, o.EndTime.Time,
-			RecurrenceRule{
-				Frequency: o.RecurrenceRule.Frequency,
-				UntilTime: o.RecurrenceRule.UntilTime.Time,
-			},
-		)
+		a, u, err := matchScheduledOverride(now, o)
 		if err != nil {
-			return minReplicas, nil, nil, err
+			return nil, nil, err
 		}
 
-		// Use the first when there are two or more active scheduled overrides,
+		// All the active scheduled overrides are returned so that each field is overridden by
+		// the earliest scheduled override that has the field,
 		// as the spec defines that the earlier scheduled override is prioritized higher than later ones.
-		if a != nil && active == nil {
-			active = &Override{Period: *a, ScheduledOverride: o}
-
-			if o.MinReplicas != nil {
-				minReplicas = o.MinReplicas
+		if a != nil {
+			active = append(active, Override{Period: *a, ScheduledOverride: o})
 
-				log.V(1).Info(
-					"Found active scheduled override",
-					"activeStartTime", a.StartTime,
-					"activeEndTime", a.EndTime,
-					"activeMinReplicas", minReplicas,
-				)
-			}
+			log.V(1).Info(
+				"Found active scheduled override",
+				"activeStartTime", a.StartTime,
+				"activeEndTime", a.EndTime,
+				"activeMinReplicas", o.MinReplicas,
+				"activeMaxReplicas", o.MaxReplicas,
+			)
 		}
 
 		if u != nil && (upcoming == nil || u.StartTime.Before(upcoming.Period.StartTime)) {
@@ -444,35 +694,249 @@ func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(log logr.
 				"upcomingStartTime", u.StartTime,
 				"upcomingEndTime", u.EndTime,
 				"upcomingMinReplicas", o.MinReplicas,
+				"upcomingMaxReplicas", o.MaxReplicas,
 			)
 		}
 	}
 
-	return minReplicas, active, upcoming, nil
+	return active, upcoming, nil
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) getMinReplicas(log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) (int, *Override, *Override, error) {
-	minReplicas := defaultReplicas
-	if hra.Spec.MinReplicas != nil && *hra.Spec.MinReplicas >= 0 {
-		minReplicas = *hra.Spec.MinReplicas
+func matchScheduledOverride(now time.Time, o v1alpha1.ScheduledOverride) (*Period, *Period, error) {
+	var loc *time.Location
+
+	if o.TimeZone != "" {
+		l, err := time.LoadLocation(o.TimeZone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid timeZone %q: %w", o.TimeZone, err)
+		}
+
+		loc = l
 	}
 
-	m, active, upcoming, err := r.matchScheduledOverrides(log, now, hra)
+	if o.Cron != "" {
+		if !o.StartTime.IsZero() || !o.EndTime.IsZero() || o.RecurrenceRule.Frequency != "" {
+			return nil, nil, fmt.Errorf("scheduled override with cron %q cannot have startTime, endTime, or recurrenceRule.frequency", o.Cron)
+		}
+
+		return MatchCronSchedule(now, o.Cron, o.Duration.Duration, loc, o.RecurrenceRule.UntilTime.Time)
+	}
+
+	startTime, endTime := o.StartTime.Time, o.EndTime.Time
+
+	// Recurrences are computed in the time zone so that they follow its daylight saving time
+	if loc != nil {
+		startTime, endTime = startTime.In(loc), endTime.In(loc)
+	}
+
+	return MatchSchedule(
+		now, startTime, endTime,
+		RecurrenceRule{
+			Frequency: o.RecurrenceRule.Frequency,
+			UntilTime: o.RecurrenceRule.UntilTime.Time,
+		},
+	)
+}
+
+// applyScheduledOverrides returns a copy of the HRA whose spec is overridden by the active scheduled overrides.
+// Each field is overridden by the earliest active scheduled override that has the field.
+func applyScheduledOverrides(hra v1alpha1.HorizontalRunnerAutoscaler, active []Override) v1alpha1.HorizontalRunnerAutoscaler {
+	if len(active) == 0 {
+		return hra
+	}
+
+	overridden := hra.DeepCopy()
+	spec := &overridden.Spec
+
+	// Iterate in the reverse order so that earlier overrides take precedence
+	for i := len(active) - 1; i >= 0; i-- {
+		o := active[i].ScheduledOverride
+
+		if o.MinReplicas != nil {
+			spec.MinReplicas = o.MinReplicas
+		}
+
+		if o.MaxReplicas != nil {
+			spec.MaxReplicas = o.MaxReplicas
+		}
+
+		if o.ScaleDownDelaySecondsAfterScaleUp != nil {
+			spec.ScaleDownDelaySecondsAfterScaleUp = o.ScaleDownDelaySecondsAfterScaleUp
+		}
+
+		for _, mo := range o.Metrics {
+			for j := range spec.Metrics {
+				m := &spec.Metrics[j]
+
+				if m.Type != mo.Type {
+					continue
+				}
+
+				if mo.ScaleUpThreshold != "" {
+					m.ScaleUpThreshold = mo.ScaleUpThreshold
+				}
+
+				if mo.ScaleDownThreshold != "" {
+					m.ScaleDownThreshold = mo.ScaleDownThreshold
+				}
+
+				if mo.TargetValuePerRunner != "" && m.Prometheus != nil {
+					m.Prometheus.TargetValuePerRunner = mo.TargetValuePerRunner
+				}
+			}
+		}
+	}
+
+	return *overridden
+}
+
+// summarizeScheduledOverride returns the fields overridden by the scheduled override, like `min=1 max=10`.
+func summarizeScheduledOverride(o v1alpha1.ScheduledOverride) []string {
+	var fields []string
+
+	if o.MinReplicas != nil {
+		fields = append(fields, fmt.Sprintf("min=%d", *o.MinReplicas))
+	}
+
+	if o.MaxReplicas != nil {
+		fields = append(fields, fmt.Sprintf("max=%d", *o.MaxReplicas))
+	}
+
+	if o.ScaleDownDelaySecondsAfterScaleUp != nil {
+		fields = append(fields, fmt.Sprintf("scaleDownDelay=%s", time.Duration(*o.ScaleDownDelaySecondsAfterScaleUp)*time.Second))
+	}
+
+	for _, m := range o.Metrics {
+		if m.ScaleUpThreshold != "" {
+			fields = append(fields, fmt.Sprintf("%s.scaleUpThreshold=%s", m.Type, m.ScaleUpThreshold))
+		}
+
+		if m.ScaleDownThreshold != "" {
+			fields = append(fields, fmt.Sprintf("%s.scaleDownThreshold=%s", m.Type, m.ScaleDownThreshold))
+		}
+
+		if m.TargetValuePerRunner != "" {
+			fields = append(fields, fmt.Sprintf("%s.targetValuePerRunner=%s", m.Type, m.TargetValuePerRunner))
+		}
+	}
+
+	return fields
+}
+
+// summarizeScheduledOverrides returns the summary of all the fields overridden by the active scheduled overrides
+// and the earliest time at which one of them ends.
+// When there's no active scheduled override, it returns the summary of the upcoming one and its start time instead.
+func summarizeScheduledOverrides(active []Override, upcoming *Override) string {
+	if len(active) == 0 {
+		if upcoming == nil {
+			return ""
+		}
+
+		fields := summarizeScheduledOverride(upcoming.ScheduledOverride)
+		if len(fields) == 0 {
+			return ""
+		}
+
+		return fmt.Sprintf("%s from=%s", strings.Join(fields, " "), upcoming.Period.StartTime)
+	}
+
+	var (
+		fields []string
+		seen   = map[string]struct{}{}
+		until  time.Time
+	)
+
+	for _, a := range active {
+		contributed := false
+
+		for _, f := range summarizeScheduledOverride(a.ScheduledOverride) {
+			// The earlier override takes precedence for the same field
+			name := f[:strings.Index(f, "=")]
+			if _, ok := seen[name]; ok {
+				continue
+			}
+
+			seen[name] = struct{}{}
+			fields = append(fields, f)
+			contributed = true
+		}
+
+		if contributed && (until.IsZero() || a.Period.EndTime.Before(until)) {
+			until = a.Period.EndTime
+		}
+	}
+
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s until=%s", strings.Join(fields, " "), until)
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) getMinReplicas(log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) (int, []Override, *Override, error) {
+	active, upcoming, err := r.matchScheduledOverrides(log, now, hra)
 	if err != nil {
 		return 0, nil, nil, err
-	} else if m != nil {
-		minReplicas = *m
+	}
+
+	hra = applyScheduledOverrides(hra, active)
+
+	minReplicas := defaultReplicas
+	if hra.Spec.MinReplicas != nil && *hra.Spec.MinReplicas >= 0 {
+		minReplicas = *hra.Spec.MinReplicas
 	}
 
 	return minReplicas, active, upcoming, nil
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ghc *arcgithub.Client, log logr.Logger, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, minReplicas int) (int, error) {
+// replicasComputation is the breakdown of the desired replicas computed by computeReplicasWithCache.
+type replicasComputation struct {
+	desiredReplicas     int
+	suggestedReplicas   int
+	reservedReplicas    int
+	minReplicas         int
+	maxReplicas         *int
+	scaleDownDelayUntil *time.Time
+
+	// metrics is the observation of each metric used to compute the suggested replicas.
+	metrics []v1alpha1.MetricStatus
+
+	// capacityLimit is the capacity limit that reduced capacityLimitedReplicas, which is the desired replicas
+	// after the scaling behavior is applied. It's nil when the desired replicas is within the capacity limit.
+	capacityLimit           *capacityLimit
+	capacityLimitedReplicas int
+}
+
+// String returns the summary of the computation, like `desired=5 suggested=3 reserved=2 min=1 max=10`.
+func (c replicasComputation) String() string {
+	fields := []string{
+		fmt.Sprintf("desired=%d", c.desiredReplicas),
+		fmt.Sprintf("suggested=%d", c.suggestedReplicas),
+		fmt.Sprintf("reserved=%d", c.reservedReplicas),
+		fmt.Sprintf("min=%d", c.minReplicas),
+	}
+
+	if c.maxReplicas != nil {
+		fields = append(fields, fmt.Sprintf("max=%d", *c.maxReplicas))
+	}
+
+	if c.scaleDownDelayUntil != nil {
+		fields = append(fields, fmt.Sprintf("scaleDownDelayUntil=%s", c.scaleDownDelayUntil.Format(time.RFC3339)))
+	}
+
+	if c.capacityLimit != nil {
+		fields = append(fields, fmt.Sprintf("capacity=%d", c.capacityLimit.replicas))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ctx context.Context, ghc *arcgithub.Client, log logr.Logger, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, minReplicas int) (*replicasComputation, error) {
 	var suggestedReplicas int
 
-	v, err := r.suggestDesiredReplicas(ghc, st, hra)
+	v, metricStatuses, err := r.suggestDesiredReplicas(ctx, ghc, now, st, hra)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	if v == nil {
@@ -483,7 +947,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ghc *arc
 
 	var reserved int
 
-	for _, reservation := range hra.Spec.CapacityReservations {
+	for _, reservation := range getCapacityReservations(&hra) {
 		if reservation.ExpirationTime.Time.After(now) {
 			reserved += reservation.Replicas
 		}
@@ -498,12 +962,15 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ghc *arc
 	}
 
 	//
-	// Delay scaling-down for ScaleDownDelaySecondsAfterScaleUp or DefaultScaleDownDelay
+	// Delay scaling-down for ScaleDownDelaySecondsAfterScaleUp or DefaultScaleDownDelay.
+	// Behavior.ScaleDown replaces it when specified.
 	//
 
 	var scaleDownDelay time.Duration
 
-	if hra.Spec.ScaleDownDelaySecondsAfterScaleUp != nil {
+	if b := hra.Spec.Behavior; b != nil && b.ScaleDown != nil {
+		scaleDownDelay = 0
+	} else if hra.Spec.ScaleDownDelaySecondsAfterScaleUp != nil {
 		scaleDownDelay = time.Duration(*hra.Spec.ScaleDownDelaySecondsAfterScaleUp) * time.Second
 	} else {
 		scaleDownDelay = r.DefaultScaleDownDelay
@@ -550,5 +1017,13 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(ghc *arc
 		kvs...,
 	)
 
-	return newDesiredReplicas, nil
+	return &replicasComputation{
+		desiredReplicas:     newDesiredReplicas,
+		suggestedReplicas:   suggestedReplicas,
+		reservedReplicas:    reserved,
+		minReplicas:         minReplicas,
+		maxReplicas:         hra.Spec.MaxReplicas,
+		scaleDownDelayUntil: scaleDownDelayUntil,
+		metrics:             metricStatuses,
+	}, nil
 }
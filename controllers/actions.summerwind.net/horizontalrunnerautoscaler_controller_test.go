@@ -0,0 +1,184 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyScheduledOverrides(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			MinReplicas:                       intPtr(1),
+			MaxReplicas:                       intPtr(10),
+			ScaleDownDelaySecondsAfterScaleUp: intPtr(600),
+			Metrics: []v1alpha1.MetricSpec{
+				{
+					Type:               v1alpha1.AutoscalingMetricTypePercentageRunnersBusy,
+					ScaleUpThreshold:   "0.75",
+					ScaleDownThreshold: "0.25",
+				},
+				{
+					Type: v1alpha1.AutoscalingMetricTypePrometheusQuery,
+					Prometheus: &v1alpha1.PrometheusMetricSpec{
+						Query:                "sum(queued)",
+						TargetValuePerRunner: "1",
+					},
+				},
+			},
+		},
+	}
+
+	active := []Override{
+		{
+			ScheduledOverride: v1alpha1.ScheduledOverride{
+				MinReplicas: intPtr(5),
+				Metrics: []v1alpha1.MetricOverride{
+					{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy, ScaleUpThreshold: "0.5"},
+				},
+			},
+		},
+		{
+			ScheduledOverride: v1alpha1.ScheduledOverride{
+				MinReplicas:                       intPtr(3),
+				MaxReplicas:                       intPtr(20),
+				ScaleDownDelaySecondsAfterScaleUp: intPtr(60),
+				Metrics: []v1alpha1.MetricOverride{
+					{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy, ScaleUpThreshold: "0.9", ScaleDownThreshold: "0.1"},
+					{Type: v1alpha1.AutoscalingMetricTypePrometheusQuery, TargetValuePerRunner: "2"},
+				},
+			},
+		},
+	}
+
+	got := applyScheduledOverrides(hra, active)
+
+	want := v1alpha1.HorizontalRunnerAutoscalerSpec{
+		// The earlier override takes precedence
+		MinReplicas:                       intPtr(5),
+		MaxReplicas:                       intPtr(20),
+		ScaleDownDelaySecondsAfterScaleUp: intPtr(60),
+		Metrics: []v1alpha1.MetricSpec{
+			{
+				Type:               v1alpha1.AutoscalingMetricTypePercentageRunnersBusy,
+				ScaleUpThreshold:   "0.5",
+				ScaleDownThreshold: "0.1",
+			},
+			{
+				Type: v1alpha1.AutoscalingMetricTypePrometheusQuery,
+				Prometheus: &v1alpha1.PrometheusMetricSpec{
+					Query:                "sum(queued)",
+					TargetValuePerRunner: "2",
+				},
+			},
+		},
+	}
+
+	if d := cmp.Diff(want, got.Spec); d != "" {
+		t.Errorf("unexpected spec: (-want, +got)\n%s", d)
+	}
+
+	// The original HRA must not be modified
+	if *hra.Spec.MinReplicas != 1 || hra.Spec.Metrics[1].Prometheus.TargetValuePerRunner != "1" {
+		t.Errorf("the original HRA was modified: %+v", hra.Spec)
+	}
+}
+
+func TestSummarizeScheduledOverrides(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	t0 := time.Date(2022, 10, 28, 9, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		name     string
+		active   []Override
+		upcoming *Override
+		want     string
+	}{
+		{
+			name: "none",
+			want: "",
+		},
+		{
+			name: "upcoming",
+			upcoming: &Override{
+				ScheduledOverride: v1alpha1.ScheduledOverride{MinReplicas: intPtr(3)},
+				Period:            Period{StartTime: t0, EndTime: t0.Add(time.Hour)},
+			},
+			want: "min=3 from=2022-10-28 09:00:00 +0000 UTC",
+		},
+		{
+			name: "active",
+			active: []Override{
+				{
+					ScheduledOverride: v1alpha1.ScheduledOverride{
+						MinReplicas: intPtr(3),
+						Metrics: []v1alpha1.MetricOverride{
+							{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy, ScaleUpThreshold: "0.5"},
+						},
+					},
+					Period: Period{StartTime: t0, EndTime: t0.Add(9 * time.Hour)},
+				},
+				{
+					ScheduledOverride: v1alpha1.ScheduledOverride{
+						MinReplicas:                       intPtr(1),
+						MaxReplicas:                       intPtr(20),
+						ScaleDownDelaySecondsAfterScaleUp: intPtr(60),
+					},
+					Period: Period{StartTime: t0, EndTime: t0.Add(2 * time.Hour)},
+				},
+				{
+					// Doesn't contribute to the summary as the field is overridden by the earlier one
+					ScheduledOverride: v1alpha1.ScheduledOverride{
+						MinReplicas: intPtr(0),
+					},
+					Period: Period{StartTime: t0, EndTime: t0.Add(time.Hour)},
+				},
+			},
+			upcoming: &Override{
+				ScheduledOverride: v1alpha1.ScheduledOverride{MinReplicas: intPtr(5)},
+				Period:            Period{StartTime: t0.Add(24 * time.Hour), EndTime: t0.Add(25 * time.Hour)},
+			},
+			want: "min=3 PercentageRunnersBusy.scaleUpThreshold=0.5 max=20 scaleDownDelay=1m0s until=2022-10-28 11:00:00 +0000 UTC",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			got := summarizeScheduledOverrides(tc.active, tc.upcoming)
+			if got != tc.want {
+				t.Errorf("unexpected summary: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestReplicasComputationString(t *testing.T) {
+	max := 10
+	until := time.Date(2022, 10, 28, 11, 0, 0, 0, time.UTC)
+
+	c := replicasComputation{
+		desiredReplicas:     5,
+		suggestedReplicas:   3,
+		reservedReplicas:    2,
+		minReplicas:         1,
+		maxReplicas:         &max,
+		scaleDownDelayUntil: &until,
+	}
+
+	want := "desired=5 suggested=3 reserved=2 min=1 max=10 scaleDownDelayUntil=2022-10-28T11:00:00Z"
+
+	if got := c.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
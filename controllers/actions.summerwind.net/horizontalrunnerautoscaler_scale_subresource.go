@@ -0,0 +1,183 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// isRunnerScaleTargetRef returns true when the scale target is either a RunnerDeployment or a RunnerSet,
+// which is scaled by patching the resource directly instead of via the scale subresource.
+func isRunnerScaleTargetRef(ref v1alpha1.ScaleTargetRef) bool {
+	if ref.APIVersion == "" {
+		return true
+	}
+
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return false
+	}
+
+	return gv.Group == v1alpha1.GroupVersion.Group
+}
+
+// scaleTargetFromUnstructured returns the scale target with the runner configuration read from the resource.
+// The resource is either a wrapper of RunnerDeployment that has the runner spec at `spec.template.spec`,
+// or a wrapper of RunnerSet that has the runner config at `spec`.
+// The runner configuration is left empty when it's not found, so that only the metrics
+// that don't depend on GitHub runners, like PrometheusQuery, work for the resource.
+func scaleTargetFromUnstructured(obj *unstructured.Unstructured) scaleTarget {
+	st := scaleTarget{
+		st:   obj.GetName(),
+		kind: strings.ToLower(obj.GetKind()),
+	}
+
+	if m, ok, _ := unstructured.NestedMap(obj.Object, "spec", "template", "spec"); ok {
+		var spec v1alpha1.RunnerSpec
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &spec); err == nil && hasRunnerScope(spec.RunnerConfig) {
+			st.enterprise = spec.Enterprise
+			st.org = spec.Organization
+			st.repo = spec.Repository
+			st.group = spec.Group
+			st.labels = newRunnerLabelMatcher(spec.RunnerConfig, spec.NodeSelector)
+			st.podSpec = runnerPodSpecFromRunnerSpec(spec)
+
+			return st
+		}
+	}
+
+	if m, ok, _ := unstructured.NestedMap(obj.Object, "spec"); ok {
+		var spec v1alpha1.RunnerSetSpec
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &spec); err == nil && hasRunnerScope(spec.RunnerConfig) {
+			st.enterprise = spec.Enterprise
+			st.org = spec.Organization
+			st.repo = spec.Repository
+			st.group = spec.Group
+			st.labels = newRunnerLabelMatcher(spec.RunnerConfig, spec.Template.Spec.NodeSelector)
+			st.podSpec = spec.Template.Spec
+		}
+	}
+
+	return st
+}
+
+func hasRunnerScope(c v1alpha1.RunnerConfig) bool {
+	return c.Enterprise != "" || c.Organization != "" || c.Repository != ""
+}
+
+// reconcileScaleSubresource reconciles the HRA whose scale target is neither RunnerDeployment nor RunnerSet,
+// by reading and updating the desired replicas via the scale subresource of the target.
+func (r *HorizontalRunnerAutoscalerReconciler) reconcileScaleSubresource(ctx context.Context, req ctrl.Request, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler) (ctrl.Result, error) {
+	ref := hra.Spec.ScaleTargetRef
+
+	if r.ScaleClient == nil {
+		log.Info(fmt.Sprintf("Unsupported scale target %s %s %s: the scale client is not configured", ref.APIVersion, ref.Kind, ref.Name))
+
+		return ctrl.Result{}, nil
+	}
+
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		log.Info(fmt.Sprintf("Unsupported scale target %s %s %s: invalid apiVersion: %v", ref.APIVersion, ref.Kind, ref.Name, err))
+
+		return ctrl.Result{}, nil
+	}
+
+	gvk := gv.WithKind(ref.Kind)
+
+	mapping, err := r.Client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		log.Error(err, "Could not find the resource of the scale target", "apiVersion", ref.APIVersion, "kind", ref.Kind)
+
+		return ctrl.Result{}, err
+	}
+
+	gr := mapping.Resource.GroupResource()
+
+	var obj unstructured.Unstructured
+	obj.SetGroupVersionKind(gvk)
+
+	if err := r.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: ref.Name}, &obj); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !obj.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	sc, err := r.ScaleClient.Scales(req.Namespace).Get(ctx, gr, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			log.Info(fmt.Sprintf("Unsupported scale target %s %s %s: the scale subresource is not found", ref.APIVersion, ref.Kind, ref.Name))
+
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	st := scaleTargetFromUnstructured(&obj)
+
+	replicas := int(sc.Spec.Replicas)
+	st.replicas = &replicas
+
+	if sc.Status.Selector != "" {
+		selector, err := labels.Parse(sc.Status.Selector)
+		if err != nil {
+			log.Error(err, "Could not parse the selector of the scale target", "selector", sc.Status.Selector)
+
+			return ctrl.Result{}, nil
+		}
+
+		st.selector = selector
+	}
+
+	st.getRunnerMap = func() (map[string]struct{}, error) {
+		runnerMap := make(map[string]struct{})
+
+		// Without the selector, we can't tell which pods are runners of the scale target
+		if st.selector == nil {
+			return runnerMap, nil
+		}
+
+		var runnerPodList corev1.PodList
+
+		if err := r.List(ctx, &runnerPodList, client.InNamespace(req.Namespace), client.MatchingLabelsSelector{Selector: st.selector}); err != nil {
+			return nil, err
+		}
+
+		for _, items := range runnerPodList.Items {
+			runnerMap[items.Name] = struct{}{}
+		}
+
+		return runnerMap, nil
+	}
+
+	return r.reconcile(ctx, req, log, hra, st, func(newDesiredReplicas int) error {
+		if int(sc.Spec.Replicas) == newDesiredReplicas {
+			return nil
+		}
+
+		updated := sc.DeepCopy()
+		updated.Spec.Replicas = int32(newDesiredReplicas)
+
+		if _, err := r.ScaleClient.Scales(req.Namespace).Update(ctx, gr, updated, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("updating the scale of %s %s to have %d replicas: %w", gr.String(), ref.Name, newDesiredReplicas, err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,123 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestIsRunnerScaleTargetRef(t *testing.T) {
+	testcases := []struct {
+		ref  v1alpha1.ScaleTargetRef
+		want bool
+	}{
+		{ref: v1alpha1.ScaleTargetRef{Name: "rd"}, want: true},
+		{ref: v1alpha1.ScaleTargetRef{Kind: "RunnerSet", Name: "rs"}, want: true},
+		{ref: v1alpha1.ScaleTargetRef{APIVersion: "actions.summerwind.dev/v1alpha1", Kind: "RunnerDeployment", Name: "rd"}, want: true},
+		{ref: v1alpha1.ScaleTargetRef{APIVersion: "example.com/v1", Kind: "RunnerPool", Name: "pool"}, want: false},
+		{ref: v1alpha1.ScaleTargetRef{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "sts"}, want: false},
+	}
+
+	for _, tc := range testcases {
+		if got := isRunnerScaleTargetRef(tc.ref); got != tc.want {
+			t.Errorf("unexpected result for %+v: want %v, got %v", tc.ref, tc.want, got)
+		}
+	}
+}
+
+func TestScaleTargetFromUnstructured(t *testing.T) {
+	newObj := func(spec map[string]interface{}) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "RunnerPool",
+			"metadata": map[string]interface{}{
+				"namespace": "default",
+				"name":      "pool",
+			},
+		}}
+
+		if spec != nil {
+			obj.Object["spec"] = spec
+		}
+
+		return obj
+	}
+
+	testcases := []struct {
+		name  string
+		obj   *unstructured.Unstructured
+		org   string
+		repo  string
+		group string
+		// containers is the number of the containers of the runner pod
+		containers int
+	}{
+		{
+			name: "runnerdeployment wrapper",
+			obj: newObj(map[string]interface{}{
+				"replicas": int64(2),
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"organization": "myorg",
+						"group":        "mygroup",
+						"image":        "example/runner:latest",
+					},
+				},
+			}),
+			org:   "myorg",
+			group: "mygroup",
+			// The runner and the docker sidecar
+			containers: 2,
+		},
+		{
+			name: "runnerset wrapper",
+			obj: newObj(map[string]interface{}{
+				"repository": "myorg/myrepo",
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "runner", "image": "example/runner:latest"},
+						},
+					},
+				},
+			}),
+			repo:       "myorg/myrepo",
+			containers: 1,
+		},
+		{
+			name: "no runner config",
+			obj: newObj(map[string]interface{}{
+				"replicas": int64(2),
+			}),
+		},
+		{
+			name: "no spec",
+			obj:  newObj(nil),
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			st := scaleTargetFromUnstructured(tc.obj)
+
+			if st.st != "pool" || st.kind != "runnerpool" {
+				t.Errorf("unexpected scale target: %s %s", st.kind, st.st)
+			}
+
+			if st.org != tc.org || st.repo != tc.repo || st.group != tc.group {
+				t.Errorf("unexpected runner config: want org=%q repo=%q group=%q, got org=%q repo=%q group=%q", tc.org, tc.repo, tc.group, st.org, st.repo, st.group)
+			}
+
+			if n := len(st.podSpec.Containers); n != tc.containers {
+				t.Errorf("unexpected number of runner containers: want %d, got %d", tc.containers, n)
+			}
+
+			if (st.labels != nil) != (tc.org != "" || tc.repo != "") {
+				t.Errorf("unexpected runner label matcher: %v", st.labels)
+			}
+		})
+	}
+}
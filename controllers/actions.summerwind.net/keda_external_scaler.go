@@ -0,0 +1,263 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/pkg/externalscaler"
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// DefaultKEDAStreamIsActiveInterval is the default interval between the checks of StreamIsActive
+	DefaultKEDAStreamIsActiveInterval = 30 * time.Second
+
+	// kedaMetadataKeyHorizontalRunnerAutoscalerName is the key of the trigger metadata of the ScaledObject
+	// that names the HRA in the same namespace to serve the desired replicas of.
+	kedaMetadataKeyHorizontalRunnerAutoscalerName = "horizontalRunnerAutoscalerName"
+
+	// kedaMetricName is the name of the metric reported to KEDA.
+	// Its value is the desired replicas, which KEDA turns into the replicas of the scale target with the target size of 1.
+	kedaMetricName = "desiredReplicas"
+)
+
+// isScaledByKEDA returns true when the HRA's desired replicas is served to KEDA via the external scaler
+// instead of being applied by the HRA controller.
+func isScaledByKEDA(hra v1alpha1.HorizontalRunnerAutoscaler) bool {
+	return hra.Annotations[AnnotationKeyExternalScaler] == AnnotationValueExternalScalerKEDA
+}
+
+// KEDAExternalScaler serves the desired replicas of HRAs to KEDA ScaledObjects as a KEDA external scaler,
+// so that ARC's metrics and the capacity reservations of the webhook-based autoscaler can be used
+// by teams that scale RunnerDeployments and RunnerSets with KEDA.
+//
+// The desired replicas is computed in the same way as the HRA controller does, except that
+// the scale down delay, the scaling behavior and the capacity limit are left to KEDA and the HPA managed by it.
+type KEDAExternalScaler struct {
+	externalscaler.UnimplementedExternalScalerServer
+
+	// Autoscaler is used to compute the desired replicas of HRAs
+	Autoscaler *HorizontalRunnerAutoscalerReconciler
+	Log        logr.Logger
+
+	// BindAddress is the address the gRPC server listens on
+	BindAddress string
+
+	// StreamIsActiveInterval is the interval between the checks of the HRA for StreamIsActive.
+	// DefaultKEDAStreamIsActiveInterval is used when omitted.
+	StreamIsActiveInterval time.Duration
+}
+
+// Start runs the gRPC server until the context is canceled. It implements manager.Runnable.
+func (s *KEDAExternalScaler) Start(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.BindAddress)
+	if err != nil {
+		return err
+	}
+
+	srv := grpc.NewServer()
+
+	externalscaler.RegisterExternalScalerServer(srv, s)
+
+	go func() {
+		<-ctx.Done()
+
+		srv.GracefulStop()
+	}()
+
+	s.Log.Info("Starting KEDA external scaler", "addr", s.BindAddress)
+
+	return srv.Serve(lis)
+}
+
+// NeedLeaderElection returns false so that every replica of the controller manager serves KEDA.
+func (s *KEDAExternalScaler) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *KEDAExternalScaler) IsActive(ctx context.Context, ref *externalscaler.ScaledObjectRef) (*externalscaler.IsActiveResponse, error) {
+	desired, err := s.desiredReplicas(ctx, ref)
+	if err != nil {
+		return nil, s.status("IsActive", err)
+	}
+
+	return &externalscaler.IsActiveResponse{Result: desired > 0}, nil
+}
+
+// StreamIsActive sends the IsActiveResponse whenever the activeness of the HRA changes,
+// until the client closes the stream or the HRA fails to be computed.
+func (s *KEDAExternalScaler) StreamIsActive(ref *externalscaler.ScaledObjectRef, stream externalscaler.ExternalScaler_StreamIsActiveServer) error {
+	ctx := stream.Context()
+
+	interval := s.StreamIsActiveInterval
+	if interval == 0 {
+		interval = DefaultKEDAStreamIsActiveInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *bool
+
+	for {
+		desired, err := s.desiredReplicas(ctx, ref)
+		if err != nil {
+			return s.status("StreamIsActive", err)
+		}
+
+		active := desired > 0
+
+		if last == nil || *last != active {
+			if err := stream.Send(&externalscaler.IsActiveResponse{Result: active}); err != nil {
+				return err
+			}
+
+			last = &active
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *KEDAExternalScaler) GetMetricSpec(ctx context.Context, ref *externalscaler.ScaledObjectRef) (*externalscaler.GetMetricSpecResponse, error) {
+	return &externalscaler.GetMetricSpecResponse{
+		MetricSpecs: []*externalscaler.MetricSpec{
+			{MetricName: kedaMetricName, TargetSize: 1},
+		},
+	}, nil
+}
+
+func (s *KEDAExternalScaler) GetMetrics(ctx context.Context, req *externalscaler.GetMetricsRequest) (*externalscaler.GetMetricsResponse, error) {
+	desired, err := s.desiredReplicas(ctx, req.GetScaledObjectRef())
+	if err != nil {
+		return nil, s.status("GetMetrics", err)
+	}
+
+	return &externalscaler.GetMetricsResponse{
+		MetricValues: []*externalscaler.MetricValue{
+			{MetricName: kedaMetricName, MetricValue: int64(desired)},
+		},
+	}, nil
+}
+
+// status returns the gRPC status of the error, logging the ones that aren't caused by the request.
+// Errors without a status are reported as internal errors, or unavailable for GitHub API errors
+// so that KEDA treats them as transient.
+func (s *KEDAExternalScaler) status(method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		s.Log.V(1).Info("Rejected KEDA external scaler request", "method", method, "error", err.Error())
+
+		return err
+	}
+
+	s.Log.Error(err, "Failed to serve KEDA external scaler request", "method", method)
+
+	if isGitHubAPIError(err) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+// desiredReplicas returns the desired replicas of the HRA named in the trigger metadata of the ScaledObject.
+func (s *KEDAExternalScaler) desiredReplicas(ctx context.Context, ref *externalscaler.ScaledObjectRef) (int, error) {
+	name := ref.GetScalerMetadata()[kedaMetadataKeyHorizontalRunnerAutoscalerName]
+	if name == "" {
+		return 0, status.Errorf(codes.InvalidArgument, "%s is missing in the metadata of scaledobject %s/%s", kedaMetadataKeyHorizontalRunnerAutoscalerName, ref.GetNamespace(), ref.GetName())
+	}
+
+	r := s.Autoscaler
+
+	nsName := types.NamespacedName{Namespace: ref.GetNamespace(), Name: name}
+
+	var hra v1alpha1.HorizontalRunnerAutoscaler
+	if err := r.Get(ctx, nsName, &hra); err != nil {
+		if kerrors.IsNotFound(err) {
+			return 0, status.Errorf(codes.NotFound, "horizontalrunnerautoscaler %s is not found", nsName)
+		}
+
+		return 0, err
+	}
+
+	if !isScaledByKEDA(hra) {
+		return 0, status.Errorf(codes.FailedPrecondition, "horizontalrunnerautoscaler %s must be annotated with %s=%s to be scaled by KEDA", nsName, AnnotationKeyExternalScaler, AnnotationValueExternalScalerKEDA)
+	}
+
+	st, err := s.scaleTarget(ctx, hra)
+	if err != nil {
+		return 0, err
+	}
+
+	log := s.Log.WithValues("horizontalrunnerautoscaler", nsName)
+
+	now := time.Now()
+
+	minReplicas, active, _, err := r.getMinReplicas(log, now, hra)
+	if err != nil {
+		return 0, status.Errorf(codes.FailedPrecondition, "computing min replicas: %v", err)
+	}
+
+	ghc, err := r.GitHubClient.InitForHRA(ctx, &hra)
+	if err != nil {
+		return 0, err
+	}
+
+	overridden := applyScheduledOverrides(hra, active)
+
+	// The scale down delay is left to the HPA managed by KEDA, which has its own stabilization window.
+	// Besides, the desired replicas in the status is never updated as the HRA controller skips this HRA.
+	overridden.Status.DesiredReplicas = nil
+
+	computation, err := r.computeReplicasWithCache(ctx, ghc, log, now, st, overridden, minReplicas)
+	if err != nil {
+		return 0, err
+	}
+
+	return computation.desiredReplicas, nil
+}
+
+func (s *KEDAExternalScaler) scaleTarget(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) (scaleTarget, error) {
+	ref := hra.Spec.ScaleTargetRef
+
+	nsName := types.NamespacedName{Namespace: hra.Namespace, Name: ref.Name}
+
+	if isRunnerScaleTargetRef(ref) {
+		switch ref.Kind {
+		case "", "RunnerDeployment":
+			var rd v1alpha1.RunnerDeployment
+			if err := s.Autoscaler.Get(ctx, nsName, &rd); err != nil {
+				if kerrors.IsNotFound(err) {
+					return scaleTarget{}, status.Errorf(codes.NotFound, "runnerdeployment %s is not found", nsName)
+				}
+
+				return scaleTarget{}, err
+			}
+
+			return s.Autoscaler.scaleTargetFromRD(ctx, rd), nil
+		case "RunnerSet":
+			var rs v1alpha1.RunnerSet
+			if err := s.Autoscaler.Get(ctx, nsName, &rs); err != nil {
+				if kerrors.IsNotFound(err) {
+					return scaleTarget{}, status.Errorf(codes.NotFound, "runnerset %s is not found", nsName)
+				}
+
+				return scaleTarget{}, err
+			}
+
+			return s.Autoscaler.scaleTargetFromRS(ctx, rs), nil
+		}
+	}
+
+	return scaleTarget{}, status.Errorf(codes.FailedPrecondition, "unsupported scale target %s %s: only RunnerDeployment and RunnerSet are supported by the KEDA external scaler", ref.Kind, ref.Name)
+}
@@ -0,0 +1,193 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/actions/actions-runner-controller/github/fake"
+	"github.com/actions/actions-runner-controller/pkg/externalscaler"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestKEDAExternalScaler(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	now := time.Now()
+
+	newHRA := func(name string, annotations map[string]string) *v1alpha1.HorizontalRunnerAutoscaler {
+		return &v1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        name,
+				Annotations: annotations,
+			},
+			Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "testrd"},
+				MinReplicas:    intPtr(2),
+				MaxReplicas:    intPtr(10),
+			},
+			Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				CapacityReservations: []v1alpha1.CapacityReservation{
+					{Replicas: 3, ExpirationTime: metav1.Time{Time: now.Add(time.Hour)}},
+					// Expired
+					{Replicas: 1, ExpirationTime: metav1.Time{Time: now.Add(-time.Minute)}},
+				},
+			},
+		}
+	}
+
+	objs := []runtime.Object{
+		newHRA("keda", map[string]string{AnnotationKeyExternalScaler: AnnotationValueExternalScalerKEDA}),
+		newHRA("controller", nil),
+		&v1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "testrd"},
+			Spec: v1alpha1.RunnerDeploymentSpec{
+				Template: v1alpha1.RunnerTemplate{
+					Spec: v1alpha1.RunnerSpec{
+						RunnerConfig: v1alpha1.RunnerConfig{Repository: "test/valid"},
+					},
+				},
+			},
+		},
+	}
+
+	ref := func(metadata map[string]string) *externalscaler.ScaledObjectRef {
+		return &externalscaler.ScaledObjectRef{Name: "so", Namespace: "default", ScalerMetadata: metadata}
+	}
+
+	testcases := []struct {
+		name string
+		call func(ctx context.Context, s *KEDAExternalScaler) (int64, error)
+		code codes.Code
+		// want is the expected metric value, or the IsActive result encoded as 0 or 1
+		want int64
+	}{
+		{
+			name: "get metric spec",
+			call: func(ctx context.Context, s *KEDAExternalScaler) (int64, error) {
+				res, err := s.GetMetricSpec(ctx, ref(map[string]string{kedaMetadataKeyHorizontalRunnerAutoscalerName: "keda"}))
+				if err != nil {
+					return 0, err
+				}
+
+				spec := res.GetMetricSpecs()[0]
+				if spec.GetMetricName() != kedaMetricName {
+					t.Errorf("unexpected metric name: want %s, got %s", kedaMetricName, spec.GetMetricName())
+				}
+
+				return spec.GetTargetSize(), nil
+			},
+			want: 1,
+		},
+		{
+			name: "get metrics",
+			call: func(ctx context.Context, s *KEDAExternalScaler) (int64, error) {
+				res, err := s.GetMetrics(ctx, &externalscaler.GetMetricsRequest{
+					ScaledObjectRef: ref(map[string]string{kedaMetadataKeyHorizontalRunnerAutoscalerName: "keda"}),
+					MetricName:      kedaMetricName,
+				})
+				if err != nil {
+					return 0, err
+				}
+
+				value := res.GetMetricValues()[0]
+				if value.GetMetricName() != kedaMetricName {
+					t.Errorf("unexpected metric name: want %s, got %s", kedaMetricName, value.GetMetricName())
+				}
+
+				return value.GetMetricValue(), nil
+			},
+			// minReplicas plus the unexpired reservation
+			want: 5,
+		},
+		{
+			name: "is active",
+			call: isActive(ref(map[string]string{kedaMetadataKeyHorizontalRunnerAutoscalerName: "keda"})),
+			want: 1,
+		},
+		{
+			name: "not annotated",
+			call: func(ctx context.Context, s *KEDAExternalScaler) (int64, error) {
+				_, err := s.GetMetrics(ctx, &externalscaler.GetMetricsRequest{
+					ScaledObjectRef: ref(map[string]string{kedaMetadataKeyHorizontalRunnerAutoscalerName: "controller"}),
+					MetricName:      kedaMetricName,
+				})
+
+				return 0, err
+			},
+			code: codes.FailedPrecondition,
+		},
+		{
+			name: "not found",
+			call: isActive(ref(map[string]string{kedaMetadataKeyHorizontalRunnerAutoscalerName: "missing"})),
+			code: codes.NotFound,
+		},
+		{
+			name: "missing metadata",
+			call: isActive(ref(nil)),
+			code: codes.InvalidArgument,
+		},
+	}
+
+	server := fake.NewServer()
+	defer server.Close()
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			log := zap.New(func(o *zap.Options) {
+				o.Development = true
+			})
+
+			client := clientfake.NewClientBuilder().WithScheme(sc).WithRuntimeObjects(objs...).Build()
+
+			s := &KEDAExternalScaler{
+				Autoscaler: &HorizontalRunnerAutoscalerReconciler{
+					Client:       client,
+					GitHubClient: NewMultiGitHubClient(client, newGithubClient(server)),
+					Log:          log,
+				},
+				Log: log,
+			}
+
+			got, err := tc.call(context.Background(), s)
+
+			if code := status.Code(err); code != tc.code {
+				t.Fatalf("unexpected code: want %s, got %s: %v", tc.code, code, err)
+			}
+
+			if tc.code != codes.OK {
+				return
+			}
+
+			if got != tc.want {
+				t.Errorf("unexpected result: want %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func isActive(ref *externalscaler.ScaledObjectRef) func(ctx context.Context, s *KEDAExternalScaler) (int64, error) {
+	return func(ctx context.Context, s *KEDAExternalScaler) (int64, error) {
+		res, err := s.IsActive(ctx, ref)
+		if err != nil {
+			return 0, err
+		}
+
+		if res.GetResult() {
+			return 1, nil
+		}
+
+		return 0, nil
+	}
+}
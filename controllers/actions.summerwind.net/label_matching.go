@@ -0,0 +1,163 @@
+package actionssummerwindnet
+
+import (
+	"strings"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+)
+
+// defaultImplicitRunnerLabels is the labels every runner managed by ARC is assumed to have,
+// regardless of the labels declared in the runner spec.
+var defaultImplicitRunnerLabels = []string{"self-hosted"}
+
+var (
+	// nodeSelectorOSLabels and nodeSelectorArchLabels map the well-known node labels to
+	// the OS and architecture labels that GitHub assigns to self-hosted runners.
+	nodeSelectorOSLabels = map[string]string{
+		"linux":   "linux",
+		"windows": "windows",
+	}
+	nodeSelectorArchLabels = map[string]string{
+		"amd64": "x64",
+		"arm64": "arm64",
+		"arm":   "arm",
+	}
+
+	// githubDefaultRunnerLabels is the labels GitHub assigns to every self-hosted runner on registration,
+	// which therefore don't need to be registered explicitly.
+	githubDefaultRunnerLabels = []string{"self-hosted", "linux", "windows", "macos", "x64", "arm", "arm64"}
+)
+
+// runnerLabelMatcher tells if the runners of a RunnerDeployment or a RunnerSet can run a workflow job,
+// according to the runner labels and the label matching policy of the runners.
+//
+// It's shared by the webhook-based autoscaler, the pull-based metrics, and the runner registration,
+// so that they all agree on the labels the runners have.
+type runnerLabelMatcher struct {
+	policy v1alpha1.LabelMatchingPolicy
+
+	// explicit are the labels declared in the runner config.
+	explicit []string
+	// implicit are the labels the runners are assumed to have without declaring them.
+	implicit []string
+}
+
+// newRunnerLabelMatcher returns the matcher for the runners that are configured with the runner config,
+// and scheduled onto the nodes with the node selector.
+func newRunnerLabelMatcher(config v1alpha1.RunnerConfig, nodeSelector map[string]string) *runnerLabelMatcher {
+	var policy v1alpha1.LabelMatchingPolicy
+	if config.LabelMatching != nil {
+		policy = *config.LabelMatching
+	}
+
+	m := &runnerLabelMatcher{policy: policy, explicit: config.Labels}
+
+	m.implicit = m.implicitLabels(nodeSelector)
+
+	return m
+}
+
+func (m *runnerLabelMatcher) implicitLabels(nodeSelector map[string]string) []string {
+	var labels []string
+
+	labels = append(labels, defaultImplicitRunnerLabels...)
+	labels = append(labels, m.policy.ImplicitLabels...)
+
+	if m.policy.ImplicitLabelsFromNodeSelector {
+		for _, k := range []string{"kubernetes.io/os", "beta.kubernetes.io/os"} {
+			if l, ok := nodeSelectorOSLabels[nodeSelector[k]]; ok {
+				labels = append(labels, l)
+				break
+			}
+		}
+
+		for _, k := range []string{"kubernetes.io/arch", "beta.kubernetes.io/arch"} {
+			if l, ok := nodeSelectorArchLabels[nodeSelector[k]]; ok {
+				labels = append(labels, l)
+				break
+			}
+		}
+	}
+
+	return labels
+}
+
+func (m *runnerLabelMatcher) normalize(l string) string {
+	if m.policy.CaseInsensitive {
+		return strings.ToLower(l)
+	}
+
+	return l
+}
+
+// matches returns true when every label of the job is either one of the runner labels or ignored.
+// The additional labels are treated as if the runners had them.
+func (m *runnerLabelMatcher) matches(jobLabels []string, additionalLabels ...string) bool {
+	available := make(map[string]struct{}, len(m.explicit)+len(m.implicit)+len(additionalLabels))
+
+	for _, l := range m.explicit {
+		available[m.normalize(l)] = struct{}{}
+	}
+
+	for _, l := range m.implicit {
+		available[m.normalize(l)] = struct{}{}
+	}
+
+	for _, l := range additionalLabels {
+		available[m.normalize(l)] = struct{}{}
+	}
+
+	ignored := make(map[string]struct{}, len(m.policy.IgnoredLabels))
+
+	for _, l := range m.policy.IgnoredLabels {
+		ignored[m.normalize(l)] = struct{}{}
+	}
+
+	for _, l := range jobLabels {
+		l = m.normalize(l)
+
+		if _, ok := ignored[l]; ok {
+			continue
+		}
+
+		if _, ok := available[l]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// registeredLabels returns the labels to be registered to GitHub along with the runner.
+// In addition to the explicit labels, it includes the implicit labels other than the ones GitHub assigns to
+// every self-hosted runner, so that GitHub routes the jobs that ARC expects the runners to run.
+func (m *runnerLabelMatcher) registeredLabels() []string {
+	var labels []string
+
+	labels = append(labels, m.explicit...)
+
+	seen := map[string]struct{}{}
+
+	// GitHub matches labels case-insensitively
+	for _, l := range githubDefaultRunnerLabels {
+		seen[strings.ToLower(l)] = struct{}{}
+	}
+
+	for _, l := range m.explicit {
+		seen[strings.ToLower(l)] = struct{}{}
+	}
+
+	for _, l := range m.implicit {
+		k := strings.ToLower(l)
+
+		if _, ok := seen[k]; ok {
+			continue
+		}
+
+		seen[k] = struct{}{}
+
+		labels = append(labels, l)
+	}
+
+	return labels
+}
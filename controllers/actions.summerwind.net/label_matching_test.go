@@ -0,0 +1,117 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRunnerLabelMatcher(t *testing.T) {
+	testcases := []struct {
+		name         string
+		config       v1alpha1.RunnerConfig
+		nodeSelector map[string]string
+		jobLabels    []string
+		additional   []string
+
+		want           bool
+		wantRegistered []string
+	}{
+		{
+			name:      "self-hosted is implicit",
+			config:    v1alpha1.RunnerConfig{Labels: []string{"custom"}},
+			jobLabels: []string{"self-hosted", "custom"},
+			want:      true,
+		},
+		{
+			name:      "os and arch labels need to be declared by default",
+			config:    v1alpha1.RunnerConfig{Labels: []string{"gpu-less"}},
+			jobLabels: []string{"self-hosted", "linux", "x64", "gpu-less"},
+			want:      false,
+		},
+		{
+			name: "os and arch labels from node selector",
+			config: v1alpha1.RunnerConfig{
+				Labels:        []string{"gpu-less"},
+				LabelMatching: &v1alpha1.LabelMatchingPolicy{ImplicitLabelsFromNodeSelector: true},
+			},
+			nodeSelector: map[string]string{"kubernetes.io/os": "linux", "kubernetes.io/arch": "amd64"},
+			jobLabels:    []string{"self-hosted", "linux", "x64", "gpu-less"},
+			want:         true,
+			// GitHub assigns the os and arch labels by itself
+			wantRegistered: []string{"gpu-less"},
+		},
+		{
+			name: "wrong arch",
+			config: v1alpha1.RunnerConfig{
+				Labels:        []string{"gpu-less"},
+				LabelMatching: &v1alpha1.LabelMatchingPolicy{ImplicitLabelsFromNodeSelector: true},
+			},
+			nodeSelector: map[string]string{"kubernetes.io/os": "linux", "kubernetes.io/arch": "arm64"},
+			jobLabels:    []string{"self-hosted", "linux", "x64", "gpu-less"},
+			want:         false,
+			// GitHub assigns the os and arch labels by itself
+			wantRegistered: []string{"gpu-less"},
+		},
+		{
+			name:      "case-sensitive by default",
+			config:    v1alpha1.RunnerConfig{Labels: []string{"linux", "x64"}},
+			jobLabels: []string{"self-hosted", "Linux", "X64"},
+			want:      false,
+			// Explicit labels are registered as-is
+			wantRegistered: []string{"linux", "x64"},
+		},
+		{
+			name: "case-insensitive",
+			config: v1alpha1.RunnerConfig{
+				Labels:        []string{"linux", "x64"},
+				LabelMatching: &v1alpha1.LabelMatchingPolicy{CaseInsensitive: true},
+			},
+			jobLabels:      []string{"Self-Hosted", "Linux", "X64"},
+			want:           true,
+			wantRegistered: []string{"linux", "x64"},
+		},
+		{
+			name: "implicit and ignored labels",
+			config: v1alpha1.RunnerConfig{
+				Labels: []string{"custom"},
+				LabelMatching: &v1alpha1.LabelMatchingPolicy{
+					ImplicitLabels: []string{"linux", "ubuntu-22.04", "custom"},
+					IgnoredLabels:  []string{"large"},
+				},
+			},
+			jobLabels:      []string{"self-hosted", "linux", "ubuntu-22.04", "large", "custom"},
+			want:           true,
+			wantRegistered: []string{"custom", "ubuntu-22.04"},
+		},
+		{
+			name:       "additional labels",
+			config:     v1alpha1.RunnerConfig{Labels: []string{"custom"}},
+			jobLabels:  []string{"self-hosted", "linux", "custom"},
+			additional: []string{"linux"},
+			want:       true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			m := newRunnerLabelMatcher(tc.config, tc.nodeSelector)
+
+			if got := m.matches(tc.jobLabels, tc.additional...); got != tc.want {
+				t.Errorf("unexpected match result for %v: want %v, got %v", tc.jobLabels, tc.want, got)
+			}
+
+			wantRegistered := tc.wantRegistered
+			if wantRegistered == nil {
+				wantRegistered = tc.config.Labels
+			}
+
+			if d := cmp.Diff(wantRegistered, m.registeredLabels()); d != "" {
+				t.Errorf("unexpected registered labels: (-want, +got)\n%s", d)
+			}
+		})
+	}
+}
@@ -31,6 +31,9 @@ var (
 		horizontalRunnerAutoscalerWorkflowRunsInProgress,
 		horizontalRunnerAutoscalerWorkflowRunsQueued,
 		horizontalRunnerAutoscalerWorkflowRunsUnknown,
+		horizontalRunnerAutoscalerWorkflowJobsInProgress,
+		horizontalRunnerAutoscalerWorkflowJobsQueued,
+		horizontalRunnerAutoscalerIdleRunners,
 	}
 )
 
@@ -128,6 +131,29 @@ var (
 		},
 		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
 	)
+	// QueuedAndInProgressWorkflowJobs
+	horizontalRunnerAutoscalerWorkflowJobsInProgress = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_workflow_jobs_in_progress",
+			Help: "workflow_jobs_in_progress of QueuedAndInProgressWorkflowJobs",
+		},
+		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
+	)
+	horizontalRunnerAutoscalerWorkflowJobsQueued = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_workflow_jobs_queued",
+			Help: "workflow_jobs_queued of QueuedAndInProgressWorkflowJobs",
+		},
+		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
+	)
+	// IdleRunnerBuffer
+	horizontalRunnerAutoscalerIdleRunners = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_idle_runners",
+			Help: "idle_runners of IdleRunnerBuffer",
+		},
+		[]string{hraName, hraNamespace, stEnterprise, stOrganization, stRepository, stKind, stName},
+	)
 )
 
 func SetHorizontalRunnerAutoscalerSpec(o metav1.ObjectMeta, spec v1alpha1.HorizontalRunnerAutoscalerSpec) {
@@ -210,3 +236,59 @@ func SetHorizontalRunnerAutoscalerQueuedAndInProgressWorkflowRuns(
 	horizontalRunnerAutoscalerWorkflowRunsQueued.With(labels).Set(float64(workflowRunsQueued))
 	horizontalRunnerAutoscalerWorkflowRunsUnknown.With(labels).Set(float64(workflowRunsUnknown))
 }
+
+func SetHorizontalRunnerAutoscalerQueuedAndInProgressWorkflowJobs(
+	o metav1.ObjectMeta,
+	enterprise string,
+	organization string,
+	repository string,
+	kind string,
+	name string,
+	necessaryReplicas int,
+	workflowJobsInProgress int,
+	workflowJobsQueued int,
+) {
+	labels := prometheus.Labels{
+		hraName:        o.Name,
+		hraNamespace:   o.Namespace,
+		stEnterprise:   enterprise,
+		stOrganization: organization,
+		stRepository:   repository,
+		stKind:         kind,
+		stName:         name,
+	}
+	horizontalRunnerAutoscalerNecessaryReplicas.With(labels).Set(float64(necessaryReplicas))
+	horizontalRunnerAutoscalerWorkflowJobsInProgress.With(labels).Set(float64(workflowJobsInProgress))
+	horizontalRunnerAutoscalerWorkflowJobsQueued.With(labels).Set(float64(workflowJobsQueued))
+}
+
+func SetHorizontalRunnerAutoscalerIdleRunnerBuffer(
+	o metav1.ObjectMeta,
+	enterprise string,
+	organization string,
+	repository string,
+	kind string,
+	name string,
+	desiredReplicas int,
+	numRunners int,
+	numRunnersRegistered int,
+	numRunnersBusy int,
+	numTerminatingBusy int,
+	idleRunners int,
+) {
+	labels := prometheus.Labels{
+		hraName:        o.Name,
+		hraNamespace:   o.Namespace,
+		stEnterprise:   enterprise,
+		stOrganization: organization,
+		stRepository:   repository,
+		stKind:         kind,
+		stName:         name,
+	}
+	horizontalRunnerAutoscalerReplicasDesired.With(labels).Set(float64(desiredReplicas))
+	horizontalRunnerAutoscalerRunners.With(labels).Set(float64(numRunners))
+	horizontalRunnerAutoscalerRunnersRegistered.With(labels).Set(float64(numRunnersRegistered))
+	horizontalRunnerAutoscalerRunnersBusy.With(labels).Set(float64(numRunnersBusy))
+	horizontalRunnerAutoscalerTerminatingBusy.With(labels).Set(float64(numTerminatingBusy))
+	horizontalRunnerAutoscalerIdleRunners.With(labels).Set(float64(idleRunners))
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	wrpEndpoint = "endpoint"
+	wrpResult   = "result"
+)
+
+const (
+	// WorkflowRunPollerEndpointWorkflowRuns and WorkflowRunPollerEndpointWorkflowJobs are the values of the endpoint label
+	// of the API request metric.
+	WorkflowRunPollerEndpointWorkflowRuns = "list_workflow_runs"
+	WorkflowRunPollerEndpointWorkflowJobs = "list_workflow_jobs"
+
+	// WorkflowRunPollerResult* are the values of the result label of the API request metric.
+	WorkflowRunPollerResultModified    = "modified"
+	WorkflowRunPollerResultNotModified = "not_modified"
+	WorkflowRunPollerResultError       = "error"
+)
+
+var (
+	workflowRunPollerMetrics = []prometheus.Collector{
+		workflowRunPollerPollDuration,
+		workflowRunPollerAPIRequests,
+		workflowRunPollerSnapshotRequests,
+		workflowRunPollerRepositories,
+	}
+)
+
+var (
+	workflowRunPollerPollDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "workflowrunpoller_poll_duration_seconds",
+			Help:    "Time taken to poll the workflow runs and jobs of a repository",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 10),
+		},
+		[]string{wrpResult},
+	)
+	workflowRunPollerAPIRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "workflowrunpoller_github_api_requests_total",
+			Help: "Number of GitHub API requests made by the workflow run poller. Requests with the not_modified result don't count against the rate limit",
+		},
+		[]string{wrpEndpoint, wrpResult},
+	)
+	workflowRunPollerSnapshotRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "workflowrunpoller_snapshot_requests_total",
+			Help: "Number of repository snapshots requested by HorizontalRunnerAutoscalers, by whether it was served from the cache",
+		},
+		[]string{"cache"},
+	)
+	workflowRunPollerRepositories = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "workflowrunpoller_repositories",
+			Help: "Number of repositories tracked by the workflow run poller",
+		},
+	)
+)
+
+func ObserveWorkflowRunPollerPoll(d time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+
+	workflowRunPollerPollDuration.With(prometheus.Labels{wrpResult: result}).Observe(d.Seconds())
+}
+
+func IncWorkflowRunPollerAPIRequests(endpoint, result string) {
+	workflowRunPollerAPIRequests.With(prometheus.Labels{wrpEndpoint: endpoint, wrpResult: result}).Inc()
+}
+
+func IncWorkflowRunPollerSnapshotRequests(cacheHit bool) {
+	cache := "miss"
+	if cacheHit {
+		cache = "hit"
+	}
+
+	workflowRunPollerSnapshotRequests.With(prometheus.Labels{"cache": cache}).Inc()
+}
+
+func SetWorkflowRunPollerRepositories(n int) {
+	workflowRunPollerRepositories.Set(float64(n))
+}
@@ -451,7 +451,7 @@ func (r *RunnerReconciler) updateRegistrationToken(ctx context.Context, runner v
 	updated.Status.Registration = v1alpha1.RunnerStatusRegistration{
 		Organization: runner.Spec.Organization,
 		Repository:   runner.Spec.Repository,
-		Labels:       runner.Spec.Labels,
+		Labels:       newRunnerLabelMatcher(runner.Spec.RunnerConfig, runner.Spec.NodeSelector).registeredLabels(),
 		Token:        rt.GetToken(),
 		ExpiresAt:    metav1.NewTime(rt.GetExpiresAt().Time),
 	}
@@ -582,6 +582,9 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 		}
 	}
 
+	// The node selector is needed to derive the implicit runner labels to be registered
+	template.Spec.NodeSelector = runner.Spec.NodeSelector
+
 	pod, err := newRunnerPodWithContainerMode(runner.Spec.ContainerMode, template, runner.Spec.RunnerConfig, r.RunnerImage, r.RunnerImagePullSecrets, r.DockerImage, r.DockerRegistryMirror, ghc.GithubBaseURL, r.UseRunnerStatusUpdateHook)
 	if err != nil {
 		return pod, err
@@ -806,7 +809,7 @@ func newRunnerPodWithContainerMode(containerMode string, template corev1.Pod, ru
 		},
 		{
 			Name:  EnvVarLabels,
-			Value: strings.Join(runnerSpec.Labels, ","),
+			Value: strings.Join(newRunnerLabelMatcher(runnerSpec, template.Spec.NodeSelector).registeredLabels(), ","),
 		},
 		{
 			Name:  EnvVarGroup,
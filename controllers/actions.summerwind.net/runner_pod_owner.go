@@ -249,7 +249,7 @@ type result struct {
 // The second call fails due to the first call mutated the client.Object to have .Revision.
 // Passing a factory function of client.Object and creating a brand-new client.Object per a client.Create call resolves this issue,
 // allowing us to create two or more replicas in one reconcilation loop without being rejected by K8s.
-func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger, effectiveTime *metav1.Time, newDesiredReplicas int, create func() client.Object, ephemeral bool, owners []client.Object) (*result, error) {
+func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger, effectiveTime *metav1.Time, newDesiredReplicas int, create func() client.Object, ephemeral bool, scaleDownPolicy string, owners []client.Object) (*result, error) {
 	state, err := collectPodsForOwners(ctx, c, log, owners)
 	if err != nil || state == nil {
 		return nil, err
@@ -402,9 +402,12 @@ func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger,
 
 		var retained int
 
+		// The owners at the end of the candidates are retained, and the rest are deleted
+		candidates := orderScaleDownCandidates(scaleDownPolicy, currentObjects)
+
 		var delete []*podsForOwner
-		for i := len(currentObjects) - 1; i >= 0; i-- {
-			ss := currentObjects[i]
+		for i := len(candidates) - 1; i >= 0; i-- {
+			ss := candidates[i]
 
 			if ss.running == 0 || retained >= newDesiredReplicas {
 				// In case the desired replicas is satisfied until i-1, or this owner has no running pods,
@@ -482,6 +485,114 @@ func syncRunnerPodsOwners(ctx context.Context, c client.Client, log logr.Logger,
 	}, nil
 }
 
+// orderScaleDownCandidates returns the owners ordered by the scale down policy, from the one to be deleted first to the one to be deleted last.
+// The owners must be sorted by their creation timestamps, which is the order of the OldestFirst policy.
+//
+// The owners that are already being unregistered always come first regardless of the policy,
+// so that a change in the order between reconciliations doesn't result in unregistering more runners than needed.
+func orderScaleDownCandidates(policy string, owners []*podsForOwner) []*podsForOwner {
+	candidates := append([]*podsForOwner{}, owners...)
+
+	switch policy {
+	case v1alpha1.ScaleDownPolicyNewestFirst:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[j].owner.GetCreationTimestamp().Time.Before(candidates[i].owner.GetCreationTimestamp().Time)
+		})
+	case v1alpha1.ScaleDownPolicyLongestIdle:
+		idleSince := make(map[*podsForOwner]time.Time, len(candidates))
+		for _, c := range candidates {
+			idleSince[c] = ownerIdleSince(c)
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			ti, tj := idleSince[candidates[i]], idleSince[candidates[j]]
+
+			// Busy owners have zero idleSince and are deleted last
+			if ti.IsZero() || tj.IsZero() {
+				return !ti.IsZero() && tj.IsZero()
+			}
+
+			return ti.Before(tj)
+		})
+	case v1alpha1.ScaleDownPolicyPackNodes:
+		podsPerNode := map[string]int{}
+		for _, c := range candidates {
+			for _, po := range c.pods {
+				if po.Spec.NodeName != "" {
+					podsPerNode[po.Spec.NodeName]++
+				}
+			}
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			ni, nj := ownerNodeName(candidates[i]), ownerNodeName(candidates[j])
+
+			// Unscheduled owners have no node to drain, and are deleted first as they are not running jobs either
+			if ci, cj := podsPerNode[ni], podsPerNode[nj]; ci != cj {
+				return ci < cj
+			}
+
+			// Drain one node at a time
+			return ni < nj
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		_, mi := getAnnotation(candidates[i].owner, AnnotationKeyUnregistrationRequestTimestamp)
+		_, mj := getAnnotation(candidates[j].owner, AnnotationKeyUnregistrationRequestTimestamp)
+
+		return mi && !mj
+	})
+
+	return candidates
+}
+
+// ownerIdleSince returns the time the runner of the owner became idle, or the zero time when it's running a workflow job.
+// The runner becomes idle when it completes a workflow job, or when the runner pod becomes ready after the registration.
+func ownerIdleSince(ss *podsForOwner) time.Time {
+	var idleSince time.Time
+
+	for _, po := range ss.pods {
+		if _, ok := po.Annotations[AnnotationKeyWorkflowJobID]; ok {
+			return time.Time{}
+		}
+
+		t := po.CreationTimestamp.Time
+
+		if ready := podConditionTransitionTime(&po, corev1.PodReady, corev1.ConditionTrue); ready != nil {
+			t = ready.Time
+		}
+
+		if v, ok := po.Annotations[AnnotationKeyWorkflowJobCompletionTimestamp]; ok {
+			if completed, err := time.Parse(time.RFC3339, v); err == nil && completed.After(t) {
+				t = completed
+			}
+		}
+
+		if t.After(idleSince) {
+			idleSince = t
+		}
+	}
+
+	if idleSince.IsZero() {
+		// The owner has no pod yet
+		idleSince = ss.owner.GetCreationTimestamp().Time
+	}
+
+	return idleSince
+}
+
+// ownerNodeName returns the name of the node the pod of the owner is scheduled to, or an empty string when it's not scheduled.
+func ownerNodeName(ss *podsForOwner) string {
+	for _, po := range ss.pods {
+		if po.Spec.NodeName != "" {
+			return po.Spec.NodeName
+		}
+	}
+
+	return ""
+}
+
 func collectPodsForOwners(ctx context.Context, c client.Client, log logr.Logger, owners []client.Object) (*state, error) {
 	podsForOwnerPerTemplateHash := map[string][]*podsForOwner{}
 
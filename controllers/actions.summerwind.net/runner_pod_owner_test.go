@@ -0,0 +1,155 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions/actions-runner-controller/apis/actions.summerwind.net/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOrderScaleDownCandidates(t *testing.T) {
+	now := time.Now()
+
+	type runner struct {
+		name string
+		// created is how long ago the runner was created
+		created time.Duration
+		// ready is how long ago the runner pod became ready
+		ready time.Duration
+		// completed is how long ago the runner completed its last workflow job, if any
+		completed time.Duration
+		busy      bool
+		node      string
+		marked    bool
+	}
+
+	newPodsForOwner := func(r runner) *podsForOwner {
+		rn := &v1alpha1.Runner{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "default",
+				Name:              r.name,
+				CreationTimestamp: metav1.Time{Time: now.Add(-r.created)},
+			},
+		}
+
+		if r.marked {
+			rn.Annotations = map[string]string{AnnotationKeyUnregistrationRequestTimestamp: now.Format(time.RFC3339)}
+		}
+
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "default",
+				Name:              r.name,
+				CreationTimestamp: rn.CreationTimestamp,
+				Annotations:       map[string]string{},
+			},
+			Spec: corev1.PodSpec{NodeName: r.node},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.Time{Time: now.Add(-r.ready)}},
+				},
+			},
+		}
+
+		if r.completed > 0 {
+			pod.Annotations[AnnotationKeyWorkflowJobCompletionTimestamp] = now.Add(-r.completed).Format(time.RFC3339)
+		}
+
+		if r.busy {
+			pod.Annotations[AnnotationKeyWorkflowJobID] = "1"
+		}
+
+		return &podsForOwner{
+			running: 1,
+			owner:   &ownerRunner{Object: rn, Runner: rn},
+			object:  rn,
+			pods:    []corev1.Pod{pod},
+		}
+	}
+
+	// Sorted by the creation timestamps as syncRunnerPodsOwners does
+	runners := []runner{
+		// Idle since the registration an hour ago
+		{name: "a", created: 70 * time.Minute, ready: 60 * time.Minute, node: "node-1"},
+		// Idle since the job completion 5 minutes ago
+		{name: "b", created: 50 * time.Minute, ready: 45 * time.Minute, completed: 5 * time.Minute, node: "node-2"},
+		// Running a job
+		{name: "c", created: 40 * time.Minute, ready: 35 * time.Minute, busy: true, node: "node-1"},
+		// Idle since the job completion 10 minutes ago
+		{name: "d", created: 20 * time.Minute, ready: 19 * time.Minute, completed: 10 * time.Minute, node: "node-2"},
+		// Just registered
+		{name: "e", created: 2 * time.Minute, ready: time.Minute, node: "node-3"},
+	}
+
+	testcases := []struct {
+		policy string
+		marked string
+		want   []string
+	}{
+		{
+			policy: "",
+			want:   []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			policy: v1alpha1.ScaleDownPolicyOldestFirst,
+			want:   []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			policy: v1alpha1.ScaleDownPolicyNewestFirst,
+			want:   []string{"e", "d", "c", "b", "a"},
+		},
+		{
+			policy: v1alpha1.ScaleDownPolicyLongestIdle,
+			// d has been idle longer than b, although d was created later
+			want: []string{"a", "d", "b", "e", "c"},
+		},
+		{
+			policy: v1alpha1.ScaleDownPolicyPackNodes,
+			want:   []string{"e", "a", "c", "b", "d"},
+		},
+		{
+			policy: v1alpha1.ScaleDownPolicyLongestIdle,
+			marked: "e",
+			want:   []string{"e", "a", "d", "b", "c"},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		name := tc.policy
+		if name == "" {
+			name = "default"
+		}
+		if tc.marked != "" {
+			name += " with marked " + tc.marked
+		}
+
+		t.Run(name, func(t *testing.T) {
+			var owners []*podsForOwner
+
+			for _, r := range runners {
+				r.marked = r.name == tc.marked
+				owners = append(owners, newPodsForOwner(r))
+			}
+
+			var got []string
+
+			for _, c := range orderScaleDownCandidates(tc.policy, owners) {
+				got = append(got, c.owner.GetName())
+			}
+
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Errorf("unexpected order: (-want, +got)\n%s", d)
+			}
+
+			// The owners passed in are left unchanged
+			if n := owners[0].owner.GetName(); n != "a" {
+				t.Errorf("unexpected first owner: want a, got %s", n)
+			}
+		})
+	}
+}
@@ -191,9 +191,10 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	if rd.Spec.EffectiveTime != nil {
 		et2 = rd.Spec.EffectiveTime.Time
 	}
-	if currentDesiredReplicas != newDesiredReplicas || et1 != et2 {
+	if currentDesiredReplicas != newDesiredReplicas || et1 != et2 || newestSet.Spec.ScaleDownPolicy != rd.Spec.ScaleDownPolicy {
 		newestSet.Spec.Replicas = &newDesiredReplicas
 		newestSet.Spec.EffectiveTime = rd.Spec.EffectiveTime
+		newestSet.Spec.ScaleDownPolicy = rd.Spec.ScaleDownPolicy
 
 		if err := r.Client.Update(ctx, newestSet); err != nil {
 			log.Error(err, "Failed to update runnerreplicaset resource")
@@ -314,6 +315,13 @@ func (r *RunnerDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	status.Replicas = &totalCurrentReplicas
 	status.UpdatedReplicas = &updatedReplicas
 
+	selector, err := metav1.LabelSelectorAsSelector(getSelector(&rd))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status.Selector = selector.String()
+
 	if !reflect.DeepEqual(rd.Status, status) {
 		updated := rd.DeepCopy()
 		updated.Status = status
@@ -462,10 +470,11 @@ func newRunnerReplicaSet(rd *v1alpha1.RunnerDeployment, commonRunnerLabels []str
 			Labels:       newRSTemplate.ObjectMeta.Labels,
 		},
 		Spec: v1alpha1.RunnerReplicaSetSpec{
-			Replicas:      rd.Spec.Replicas,
-			Selector:      newRSSelector,
-			Template:      newRSTemplate,
-			EffectiveTime: rd.Spec.EffectiveTime,
+			Replicas:        rd.Spec.Replicas,
+			Selector:        newRSSelector,
+			Template:        newRSTemplate,
+			EffectiveTime:   rd.Spec.EffectiveTime,
+			ScaleDownPolicy: rd.Spec.ScaleDownPolicy,
 		},
 	}
 
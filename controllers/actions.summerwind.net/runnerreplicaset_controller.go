@@ -81,6 +81,7 @@ func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		template := rs.Spec.DeepCopy()
 		template.Replicas = nil
 		template.EffectiveTime = nil
+		template.ScaleDownPolicy = ""
 		templateHash := ComputeHash(template)
 
 		log.Info("Using auto-generated template hash", "value", templateHash)
@@ -128,7 +129,7 @@ func (r *RunnerReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		live = append(live, &r)
 	}
 
-	res, err := syncRunnerPodsOwners(ctx, r.Client, log, effectiveTime, replicas, func() client.Object { return desired.DeepCopy() }, ephemeral, live)
+	res, err := syncRunnerPodsOwners(ctx, r.Client, log, effectiveTime, replicas, func() client.Object { return desired.DeepCopy() }, ephemeral, rs.Spec.ScaleDownPolicy, live)
 	if err != nil || res == nil {
 		return ctrl.Result{}, err
 	}
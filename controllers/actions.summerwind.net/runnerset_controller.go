@@ -139,7 +139,7 @@ func (r *RunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return *res, nil
 	}
 
-	res, err := syncRunnerPodsOwners(ctx, r.Client, log, effectiveTime, newDesiredReplicas, func() client.Object { return create.DeepCopy() }, ephemeral, owners)
+	res, err := syncRunnerPodsOwners(ctx, r.Client, log, effectiveTime, newDesiredReplicas, func() client.Object { return create.DeepCopy() }, ephemeral, runnerSet.Spec.ScaleDownPolicy, owners)
 	if err != nil || res == nil {
 		return ctrl.Result{}, err
 	}
@@ -161,6 +161,13 @@ func (r *RunnerSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	status.Replicas = &statusReplicas
 	status.UpdatedReplicas = &updatedReplicas
 
+	selector, err := metav1.LabelSelectorAsSelector(getRunnerSetSelector(runnerSet))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status.Selector = selector.String()
+
 	if !reflect.DeepEqual(runnerSet.Status, status) {
 		updated := runnerSet.DeepCopy()
 		updated.Status = *status
@@ -0,0 +1,281 @@
+package actionssummerwindnet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed standard cron expression with five fields:
+// minute, hour, day of month, month, and day of week.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar are true when the field starts with "*" or "?".
+	// Like the standard cron, day of month and day of week are OR-ed when neither of them is a wildcard.
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{min: 0, max: 59}
+	cronHour   = cronField{min: 0, max: 23}
+	cronDom    = cronField{min: 1, max: 31}
+	cronMonth  = cronField{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is also accepted as Sunday
+	cronDow = cronField{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+func parseCronSchedule(spec string) (*cronSchedule, error) {
+	expr := strings.TrimSpace(spec)
+
+	if strings.HasPrefix(expr, "@") {
+		e, ok := cronDescriptors[expr]
+		if !ok {
+			return nil, fmt.Errorf("invalid cron %q: unsupported descriptor", spec)
+		}
+		expr = e
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	var (
+		s   cronSchedule
+		err error
+	)
+
+	if s.minute, err = parseCronField(fields[0], cronMinute); err != nil {
+		return nil, fmt.Errorf("invalid cron %q: minute: %w", spec, err)
+	}
+
+	if s.hour, err = parseCronField(fields[1], cronHour); err != nil {
+		return nil, fmt.Errorf("invalid cron %q: hour: %w", spec, err)
+	}
+
+	if s.dom, err = parseCronField(fields[2], cronDom); err != nil {
+		return nil, fmt.Errorf("invalid cron %q: day of month: %w", spec, err)
+	}
+
+	if s.month, err = parseCronField(fields[3], cronMonth); err != nil {
+		return nil, fmt.Errorf("invalid cron %q: month: %w", spec, err)
+	}
+
+	if s.dow, err = parseCronField(fields[4], cronDow); err != nil {
+		return nil, fmt.Errorf("invalid cron %q: day of week: %w", spec, err)
+	}
+
+	// Sunday can be written as either 0 or 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	s.domStar = fields[2][0] == '*' || fields[2][0] == '?'
+	s.dowStar = fields[4][0] == '*' || fields[4][0] == '?'
+
+	return &s, nil
+}
+
+// parseCronField parses a comma-separated list of `*`, `N`, `N-M`, optionally followed by `/STEP`, into a bit set.
+func parseCronField(field string, f cronField) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangeAndStep := strings.SplitN(part, "/", 2)
+
+		var start, end int
+
+		switch r := rangeAndStep[0]; {
+		case r == "*" || r == "?":
+			start, end = f.min, f.max
+		case strings.Contains(r, "-"):
+			startAndEnd := strings.SplitN(r, "-", 2)
+
+			var err error
+
+			if start, err = f.parseValue(startAndEnd[0]); err != nil {
+				return 0, err
+			}
+
+			if end, err = f.parseValue(startAndEnd[1]); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := f.parseValue(r)
+			if err != nil {
+				return 0, err
+			}
+
+			start, end = v, v
+
+			// `N/STEP` means from N to the max
+			if len(rangeAndStep) == 2 {
+				end = f.max
+			}
+		}
+
+		step := 1
+
+		if len(rangeAndStep) == 2 {
+			s, err := strconv.Atoi(rangeAndStep[1])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step %q", rangeAndStep[1])
+			}
+
+			step = s
+		}
+
+		if start > end {
+			return 0, fmt.Errorf("invalid range %q: the start is greater than the end", rangeAndStep[0])
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+func (f cronField) parseValue(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, f.min, f.max)
+	}
+
+	return v, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+// next returns the earliest time matching the schedule that is strictly after t, in the location of t.
+// It returns the zero time when there's no such time within 5 years, like "0 0 30 2 *".
+func (s *cronSchedule) next(t time.Time) time.Time {
+	loc := t.Location()
+
+	// Cron has the minute precision
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t
+}
+
+// MatchCronSchedule returns the active and the upcoming periods of the override that starts at every time
+// matching the cron expression in the location and lasts for the duration.
+// Overrides that start after untilTime are ignored unless untilTime is zero.
+func MatchCronSchedule(now time.Time, cron string, duration time.Duration, loc *time.Location, untilTime time.Time) (*Period, *Period, error) {
+	if duration <= 0 {
+		return nil, nil, fmt.Errorf("invalid duration %s: it must be positive when cron is specified", duration)
+	}
+
+	schedule, err := parseCronSchedule(cron)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var active, upcoming *Period
+
+	// The active override is the latest one that started within the duration.
+	t := schedule.next(now.Add(-duration).In(loc))
+	for !t.IsZero() && !t.After(now) {
+		active = &Period{StartTime: t, EndTime: t.Add(duration)}
+		t = schedule.next(t)
+	}
+
+	if !t.IsZero() {
+		upcoming = &Period{StartTime: t, EndTime: t.Add(duration)}
+	}
+
+	if !untilTime.IsZero() {
+		if active != nil && active.StartTime.After(untilTime) {
+			active = nil
+		}
+
+		if upcoming != nil && upcoming.StartTime.After(untilTime) {
+			upcoming = nil
+		}
+	}
+
+	return active, upcoming, nil
+}
@@ -0,0 +1,166 @@
+package actionssummerwindnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchCronSchedule(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name     string
+		now      string
+		cron     string
+		duration time.Duration
+		loc      *time.Location
+		until    string
+
+		wantActive   string
+		wantUpcoming string
+		err          string
+	}{
+		{
+			name:         "weekday business hours before start",
+			now:          "2022-10-28T06:00:00Z",
+			cron:         "0 9 * * 1-5",
+			duration:     9 * time.Hour,
+			loc:          berlin,
+			wantUpcoming: "2022-10-28T09:00:00+02:00-2022-10-28T18:00:00+02:00",
+		},
+		{
+			name:         "weekday business hours active",
+			now:          "2022-10-28T10:00:00Z",
+			cron:         "0 9 * * MON-FRI",
+			duration:     9 * time.Hour,
+			loc:          berlin,
+			wantActive:   "2022-10-28T09:00:00+02:00-2022-10-28T18:00:00+02:00",
+			wantUpcoming: "2022-10-31T09:00:00+01:00-2022-10-31T18:00:00+01:00",
+		},
+		{
+			name:         "weekend",
+			now:          "2022-10-29T10:00:00Z",
+			cron:         "0 9 * * 1-5",
+			duration:     9 * time.Hour,
+			loc:          berlin,
+			wantUpcoming: "2022-10-31T09:00:00+01:00-2022-10-31T18:00:00+01:00",
+		},
+		{
+			name:         "defaults to utc",
+			now:          "2022-10-28T10:00:00Z",
+			cron:         "30 */6 * * *",
+			duration:     4 * time.Hour,
+			wantActive:   "2022-10-28T06:30:00Z-2022-10-28T10:30:00Z",
+			wantUpcoming: "2022-10-28T12:30:00Z-2022-10-28T16:30:00Z",
+		},
+		{
+			name:         "overlapping overrides",
+			now:          "2022-10-28T10:00:00Z",
+			cron:         "@hourly",
+			duration:     2 * time.Hour,
+			wantActive:   "2022-10-28T10:00:00Z-2022-10-28T12:00:00Z",
+			wantUpcoming: "2022-10-28T11:00:00Z-2022-10-28T13:00:00Z",
+		},
+		{
+			name:         "day of month or day of week",
+			now:          "2022-10-28T10:00:00Z",
+			cron:         "0 0 1 * 6",
+			duration:     time.Hour,
+			wantUpcoming: "2022-10-29T00:00:00Z-2022-10-29T01:00:00Z",
+		},
+		{
+			name:         "sunday as 7",
+			now:          "2022-10-28T10:00:00Z",
+			cron:         "0 0 * * 7",
+			duration:     time.Hour,
+			wantUpcoming: "2022-10-30T00:00:00Z-2022-10-30T01:00:00Z",
+		},
+		{
+			name:       "until",
+			now:        "2022-10-28T10:00:00Z",
+			cron:       "@daily",
+			duration:   12 * time.Hour,
+			until:      "2022-10-28T12:00:00Z",
+			wantActive: "2022-10-28T00:00:00Z-2022-10-28T12:00:00Z",
+		},
+		{
+			name:     "never",
+			now:      "2022-10-28T10:00:00Z",
+			cron:     "0 0 30 2 *",
+			duration: time.Hour,
+		},
+		{
+			name:     "missing duration",
+			now:      "2022-10-28T10:00:00Z",
+			cron:     "@daily",
+			err:      "invalid duration 0s: it must be positive when cron is specified",
+			duration: 0,
+		},
+		{
+			name:     "too few fields",
+			now:      "2022-10-28T10:00:00Z",
+			cron:     "0 9 * *",
+			duration: time.Hour,
+			err:      `invalid cron "0 9 * *": expected 5 fields, got 4`,
+		},
+		{
+			name:     "out of range",
+			now:      "2022-10-28T10:00:00Z",
+			cron:     "0 24 * * *",
+			duration: time.Hour,
+			err:      `invalid cron "0 24 * * *": hour: value 24 out of range [0, 23]`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tc.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var until time.Time
+
+			if tc.until != "" {
+				until, err = time.Parse(time.RFC3339, tc.until)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			active, upcoming, err := MatchCronSchedule(now, tc.cron, tc.duration, tc.loc, until)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("unexpected error: want %q, got %v", tc.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if active.String() != tc.wantActive {
+				t.Errorf("unexpected active: want %q, got %q", tc.wantActive, active)
+			}
+
+			if upcoming.String() != tc.wantUpcoming {
+				t.Errorf("unexpected upcoming: want %q, got %q", tc.wantUpcoming, upcoming)
+			}
+		})
+	}
+}
+
+func FuzzParseCronSchedule(f *testing.F) {
+	f.Add("0 9 * * 1-5")
+	f.Fuzz(func(t *testing.T, cron string) {
+		// Verify that it never panics
+		_, _ = parseCronSchedule(cron)
+	})
+}
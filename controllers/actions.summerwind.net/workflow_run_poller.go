@@ -0,0 +1,393 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	prometheus_metrics "github.com/actions/actions-runner-controller/controllers/actions.summerwind.net/metrics"
+	arcgithub "github.com/actions/actions-runner-controller/github"
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v47/github"
+)
+
+const (
+	DefaultWorkflowRunPollInterval    = 30 * time.Second
+	DefaultWorkflowRunPollConcurrency = 10
+
+	// A repository that hasn't been requested by any HRA for this many poll intervals is forgotten.
+	workflowRunPollerIdleIntervals = 10
+)
+
+// WorkflowRunPoller lists the queued and in-progress workflow runs of repositories along with their jobs,
+// and serves the result to every HRA that watches the same repository.
+//
+// Each repository is polled at most once per Interval, no matter how many HRAs watch it.
+// The number of concurrent GitHub API requests is bounded by Concurrency,
+// and every request is conditional on the ETag of the previous response when it's known,
+// so that unchanged lists don't count against the API rate limit.
+type WorkflowRunPoller struct {
+	// Interval is how long a snapshot of a repository is served before the repository is polled again.
+	Interval time.Duration
+	// Concurrency is the maximum number of concurrent GitHub API requests made by the poller.
+	Concurrency int
+
+	Log logr.Logger
+
+	initOnce sync.Once
+	sem      chan struct{}
+
+	mu    sync.Mutex
+	repos map[workflowRunPollerKey]*workflowRunPollerRepo
+}
+
+type workflowRunPollerKey struct {
+	// client is a part of the key because a repository visible to a GitHub client
+	// may not be visible to another one that has different credentials.
+	client      *arcgithub.Client
+	owner, repo string
+}
+
+type workflowRunPollerRepo struct {
+	// mu is held while the repository is polled, so that the HRAs requesting the same repository
+	// at the same time wait for the single poll instead of making their own requests.
+	mu sync.Mutex
+
+	snapshot *workflowRunsSnapshot
+
+	// The last responses and their ETags, used to make conditional requests
+	runs map[string]*workflowRunList
+	jobs map[int64]*workflowJobList
+
+	// lastAccessTime is guarded by WorkflowRunPoller.mu
+	lastAccessTime time.Time
+}
+
+type workflowRunList struct {
+	etag string
+	runs []*github.WorkflowRun
+}
+
+type workflowJobList struct {
+	etag string
+	jobs []*github.WorkflowJob
+}
+
+// workflowRunsSnapshot is the queued and in-progress workflow runs of a repository at a point in time.
+// It must not be modified as it's shared among HRAs.
+type workflowRunsSnapshot struct {
+	owner, repo string
+	fetchTime   time.Time
+
+	runs []*github.WorkflowRun
+	// jobs is the jobs of each workflow run keyed by the run ID.
+	// A run is missing when its jobs could not be listed.
+	jobs map[int64][]*github.WorkflowJob
+}
+
+func (p *WorkflowRunPoller) init() {
+	p.initOnce.Do(func() {
+		concurrency := p.Concurrency
+		if concurrency <= 0 {
+			concurrency = DefaultWorkflowRunPollConcurrency
+		}
+
+		p.sem = make(chan struct{}, concurrency)
+		p.repos = map[workflowRunPollerKey]*workflowRunPollerRepo{}
+	})
+}
+
+func (p *WorkflowRunPoller) interval() time.Duration {
+	if p.Interval <= 0 {
+		return DefaultWorkflowRunPollInterval
+	}
+
+	return p.Interval
+}
+
+// Snapshots returns the snapshots of the repositories, each of which is a pair of the owner and the repository name.
+// The repositories whose snapshots are older than the interval are polled concurrently.
+func (p *WorkflowRunPoller) Snapshots(ctx context.Context, ghc *arcgithub.Client, repos [][]string) ([]*workflowRunsSnapshot, error) {
+	snapshots := make([]*workflowRunsSnapshot, len(repos))
+	errs := make([]error, len(repos))
+
+	var wg sync.WaitGroup
+
+	for i := range repos {
+		i := i
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			snapshots[i], errs[i] = p.snapshot(ctx, ghc, repos[i][0], repos[i][1])
+		}()
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return snapshots, nil
+}
+
+func (p *WorkflowRunPoller) snapshot(ctx context.Context, ghc *arcgithub.Client, owner, repo string) (*workflowRunsSnapshot, error) {
+	p.init()
+
+	now := time.Now()
+	interval := p.interval()
+	key := workflowRunPollerKey{client: ghc, owner: owner, repo: repo}
+
+	p.mu.Lock()
+	for k, e := range p.repos {
+		if now.Sub(e.lastAccessTime) > workflowRunPollerIdleIntervals*interval {
+			delete(p.repos, k)
+		}
+	}
+	e, ok := p.repos[key]
+	if !ok {
+		e = &workflowRunPollerRepo{}
+		p.repos[key] = e
+	}
+	e.lastAccessTime = now
+	prometheus_metrics.SetWorkflowRunPollerRepositories(len(p.repos))
+	p.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.snapshot != nil && time.Since(e.snapshot.fetchTime) < interval {
+		prometheus_metrics.IncWorkflowRunPollerSnapshotRequests(true)
+
+		return e.snapshot, nil
+	}
+
+	prometheus_metrics.IncWorkflowRunPollerSnapshotRequests(false)
+
+	start := time.Now()
+	snapshot, err := p.poll(ctx, ghc, owner, repo, e)
+	prometheus_metrics.ObserveWorkflowRunPollerPoll(time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+
+	e.snapshot = snapshot
+
+	return snapshot, nil
+}
+
+func (p *WorkflowRunPoller) poll(ctx context.Context, ghc *arcgithub.Client, owner, repo string, e *workflowRunPollerRepo) (*workflowRunsSnapshot, error) {
+	fetchTime := time.Now()
+
+	runLists := map[string]*workflowRunList{}
+
+	var runs []*github.WorkflowRun
+
+	for _, status := range []string{"queued", "in_progress"} {
+		l, err := p.listWorkflowRuns(ctx, ghc, owner, repo, status, e.runs[status])
+		if err != nil {
+			return nil, fmt.Errorf("listing %s workflow runs: %w", status, err)
+		}
+
+		runLists[status] = l
+		runs = append(runs, l.runs...)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		jobLists = map[int64]*workflowJobList{}
+	)
+
+	for _, run := range runs {
+		runID := run.GetID()
+		if runID == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			l, err := p.listWorkflowJobs(ctx, ghc, owner, repo, runID, e.jobs[runID])
+			if err != nil {
+				p.Log.Error(err, "Error listing workflow jobs", "owner", owner, "repo", repo, "run_id", runID)
+				return
+			}
+
+			mu.Lock()
+			jobLists[runID] = l
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	// Runs that are no longer queued or in progress are dropped here
+	e.runs = runLists
+	e.jobs = jobLists
+
+	jobs := make(map[int64][]*github.WorkflowJob, len(jobLists))
+	for runID, l := range jobLists {
+		jobs[runID] = l.jobs
+	}
+
+	return &workflowRunsSnapshot{
+		owner:     owner,
+		repo:      repo,
+		fetchTime: fetchTime,
+		runs:      runs,
+		jobs:      jobs,
+	}, nil
+}
+
+func (p *WorkflowRunPoller) listWorkflowRuns(ctx context.Context, ghc *arcgithub.Client, owner, repo, status string, prev *workflowRunList) (*workflowRunList, error) {
+	var l workflowRunList
+
+	for page := 1; ; {
+		q := url.Values{}
+		q.Set("status", status)
+		q.Set("per_page", "100")
+		q.Set("page", strconv.Itoa(page))
+
+		u := fmt.Sprintf("repos/%v/%v/actions/runs?%s", owner, repo, q.Encode())
+
+		// Only the lists that fit in a page are requested conditionally,
+		// as the ETag of the first page doesn't tell if the other pages have changed.
+		var etag string
+		if page == 1 && prev != nil {
+			etag = prev.etag
+		}
+
+		var runs github.WorkflowRuns
+
+		res, notModified, err := p.get(ctx, ghc, prometheus_metrics.WorkflowRunPollerEndpointWorkflowRuns, u, etag, &runs)
+		if err != nil {
+			return nil, err
+		}
+
+		if notModified {
+			return prev, nil
+		}
+
+		l.runs = append(l.runs, runs.WorkflowRuns...)
+
+		if res.NextPage == 0 {
+			if page == 1 {
+				l.etag = res.Header.Get("ETag")
+			}
+			break
+		}
+
+		page = res.NextPage
+	}
+
+	return &l, nil
+}
+
+func (p *WorkflowRunPoller) listWorkflowJobs(ctx context.Context, ghc *arcgithub.Client, owner, repo string, runID int64, prev *workflowJobList) (*workflowJobList, error) {
+	var l workflowJobList
+
+	for page := 1; ; {
+		q := url.Values{}
+		q.Set("per_page", "50")
+		q.Set("page", strconv.Itoa(page))
+
+		u := fmt.Sprintf("repos/%v/%v/actions/runs/%v/jobs?%s", owner, repo, runID, q.Encode())
+
+		var etag string
+		if page == 1 && prev != nil {
+			etag = prev.etag
+		}
+
+		var jobs github.Jobs
+
+		res, notModified, err := p.get(ctx, ghc, prometheus_metrics.WorkflowRunPollerEndpointWorkflowJobs, u, etag, &jobs)
+		if err != nil {
+			return nil, err
+		}
+
+		if notModified {
+			return prev, nil
+		}
+
+		l.jobs = append(l.jobs, jobs.Jobs...)
+
+		if res.NextPage == 0 {
+			if page == 1 {
+				l.etag = res.Header.Get("ETag")
+			}
+			break
+		}
+
+		page = res.NextPage
+	}
+
+	return &l, nil
+}
+
+// get sends a GET request to the GitHub API and decodes the response body into v.
+// When etag is not empty, the request is made conditional on it and notModified is true when the resource hasn't changed,
+// in which case v is left untouched or filled with the same content.
+func (p *WorkflowRunPoller) get(ctx context.Context, ghc *arcgithub.Client, endpoint, u, etag string, v interface{}) (*github.Response, bool, error) {
+	req, err := ghc.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+
+	res, err := ghc.Do(ctx, req, v)
+
+	<-p.sem
+
+	if res != nil && res.StatusCode == http.StatusNotModified && etag != "" {
+		prometheus_metrics.IncWorkflowRunPollerAPIRequests(endpoint, prometheus_metrics.WorkflowRunPollerResultNotModified)
+
+		return res, true, nil
+	}
+
+	if err != nil {
+		prometheus_metrics.IncWorkflowRunPollerAPIRequests(endpoint, prometheus_metrics.WorkflowRunPollerResultError)
+
+		return res, false, err
+	}
+
+	// The HTTP cache of the client may have revalidated the response on our behalf,
+	// in which case the response is 200 but has the same ETag.
+	if etag != "" && res.Header.Get("ETag") == etag {
+		prometheus_metrics.IncWorkflowRunPollerAPIRequests(endpoint, prometheus_metrics.WorkflowRunPollerResultNotModified)
+
+		return res, true, nil
+	}
+
+	prometheus_metrics.IncWorkflowRunPollerAPIRequests(endpoint, prometheus_metrics.WorkflowRunPollerResultModified)
+
+	return res, false, nil
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) workflowRunPoller() *WorkflowRunPoller {
+	r.workflowRunPollerOnce.Do(func() {
+		if r.WorkflowRunPoller == nil {
+			r.WorkflowRunPoller = &WorkflowRunPoller{Log: r.Log.WithName("workflowrunpoller")}
+		}
+	})
+
+	return r.WorkflowRunPoller
+}
@@ -0,0 +1,149 @@
+package actionssummerwindnet
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+type fakeWorkflowRunServer struct {
+	mu sync.Mutex
+
+	requests    int
+	notModified int
+	inFlight    int
+	maxInFlight int
+}
+
+func (s *fakeWorkflowRunServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	s.inFlight++
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	var body string
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/jobs"):
+		body = `{"total_count": 1, "jobs": [{"id": 1, "run_id": 1, "status": "queued", "labels": ["self-hosted"]}]}`
+	case r.URL.Query().Get("status") == "queued":
+		body = `{"total_count": 1, "workflow_runs": [{"id": 1, "status": "queued"}]}`
+	default:
+		body = `{"total_count": 0, "workflow_runs": []}`
+	}
+
+	etag := fmt.Sprintf(`"%x"`, len(body))
+
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		s.mu.Lock()
+		s.notModified++
+		s.mu.Unlock()
+
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	fmt.Fprint(w, body)
+}
+
+func TestWorkflowRunPoller(t *testing.T) {
+	fake := &fakeWorkflowRunServer{}
+
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ghc := newGithubClient(server)
+
+	p := &WorkflowRunPoller{
+		Interval:    time.Hour,
+		Concurrency: 2,
+		Log: zap.New(func(o *zap.Options) {
+			o.Development = true
+		}),
+	}
+
+	var repos [][]string
+	for i := 0; i < 5; i++ {
+		repos = append(repos, []string{"test", fmt.Sprintf("repo%d", i)})
+	}
+
+	// Two HRAs watching the same repositories at the same time
+	var wg sync.WaitGroup
+
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			snapshots, err := p.Snapshots(context.Background(), ghc, repos)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			for i, s := range snapshots {
+				if s.repo != repos[i][1] || len(s.runs) != 1 || len(s.jobs[1]) != 1 {
+					t.Errorf("unexpected snapshot of %s: %+v", repos[i][1], s)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// queued runs, in_progress runs, and jobs of the queued run, per repository
+	if fake.requests != 15 {
+		t.Errorf("unexpected number of requests: want 15, got %d", fake.requests)
+	}
+
+	if fake.maxInFlight > 2 {
+		t.Errorf("the number of concurrent requests exceeded the limit: %d", fake.maxInFlight)
+	}
+
+	// The snapshots are served from the cache until the interval passes
+	if _, err := p.Snapshots(context.Background(), ghc, repos); err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.requests != 15 {
+		t.Errorf("unexpected number of requests: want 15, got %d", fake.requests)
+	}
+
+	p.Interval = time.Nanosecond
+
+	snapshots, err := p.Snapshots(context.Background(), ghc, repos[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := snapshots[0]; len(s.runs) != 1 || len(s.jobs[1]) != 1 {
+		t.Errorf("unexpected snapshot: %+v", s)
+	}
+
+	// Unchanged lists are not fetched again.
+	// The HTTP cache of the client may revalidate the response on behalf of the poller,
+	// in which case the server still sees the conditional request.
+	if fake.requests != 18 || fake.notModified != 3 {
+		t.Errorf("unexpected number of requests: want 18 requests with 3 not modified, got %d requests with %d not modified", fake.requests, fake.notModified)
+	}
+}
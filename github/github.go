@@ -250,8 +250,6 @@ func (c *Client) ListRunners(ctx context.Context, enterprise, org, repo string)
 // inherited to the organization from an enterprise.
 // We can remove this when google/go-github library is updated to support this.
 func (c *Client) ListOrganizationRunnerGroupsForRepository(ctx context.Context, org, repo string) ([]*github.RunnerGroup, error) {
-	var runnerGroups []*github.RunnerGroup
-
 	var opts github.ListOrgRunnerGroupOptions
 
 	opts.PerPage = 100
@@ -265,6 +263,44 @@ func (c *Client) ListOrganizationRunnerGroupsForRepository(ctx context.Context,
 	// passed to visible_to_repository must be "myrepo".
 	opts.VisibleToRepository = repoName
 
+	return c.listOrganizationRunnerGroups(ctx, org, opts)
+}
+
+func (c *Client) ListRunnerGroupRepositoryAccesses(ctx context.Context, org string, runnerGroupId int64) ([]*github.Repository, error) {
+	var repos []*github.Repository
+
+	opts := github.ListOptions{PerPage: 100}
+	for {
+		list, res, err := c.Client.Actions.ListRepositoryAccessRunnerGroup(ctx, org, runnerGroupId, &opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repository access for runner group: %w", err)
+		}
+
+		repos = append(repos, list.Repositories...)
+		if res.NextPage == 0 {
+			break
+		}
+
+		opts.Page = res.NextPage
+	}
+
+	return repos, nil
+}
+
+// ListOrganizationRunnerGroups returns all the runner groups in the organization,
+// including the ones inherited from an enterprise.
+func (c *Client) ListOrganizationRunnerGroups(ctx context.Context, org string) ([]*github.RunnerGroup, error) {
+	var opts github.ListOrgRunnerGroupOptions
+
+	opts.PerPage = 100
+
+	return c.listOrganizationRunnerGroups(ctx, org, opts)
+}
+
+// listOrganizationRunnerGroups pages through the organization runner groups matching opts.
+func (c *Client) listOrganizationRunnerGroups(ctx context.Context, org string, opts github.ListOrgRunnerGroupOptions) ([]*github.RunnerGroup, error) {
+	var runnerGroups []*github.RunnerGroup
+
 	for {
 		list, res, err := c.Actions.ListOrganizationRunnerGroups(ctx, org, &opts)
 		if err != nil {
@@ -281,14 +317,120 @@ func (c *Client) ListOrganizationRunnerGroupsForRepository(ctx context.Context,
 	return runnerGroups, nil
 }
 
-func (c *Client) ListRunnerGroupRepositoryAccesses(ctx context.Context, org string, runnerGroupId int64) ([]*github.Repository, error) {
+// ListRunnerGroupRunners returns all the runners in the runner group of the organization,
+// or of the enterprise when the organization is empty.
+func (c *Client) ListRunnerGroupRunners(ctx context.Context, enterprise, org, group string) ([]*github.Runner, error) {
+	var (
+		groups []*github.RunnerGroup
+		err    error
+	)
+
+	if len(org) > 0 {
+		groups, err = c.ListOrganizationRunnerGroups(ctx, org)
+	} else if len(enterprise) > 0 {
+		groups, err = c.listEnterpriseRunnerGroups(ctx, enterprise)
+	} else {
+		return nil, fmt.Errorf("runner group %q requires either enterprise or organization", group)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var groupID int64
+
+	for _, g := range groups {
+		if g.GetName() == group {
+			groupID = g.GetID()
+			break
+		}
+	}
+
+	if groupID == 0 {
+		return nil, fmt.Errorf("runner group %q not found", group)
+	}
+
+	var runners []*github.Runner
+
+	opts := github.ListOptions{PerPage: 100}
+	for {
+		list, res, err := c.listRunnerGroupRunners(ctx, enterprise, org, groupID, &opts)
+		if err != nil {
+			return runners, fmt.Errorf("failed to list runners of runner group %q: %w", group, err)
+		}
+
+		runners = append(runners, list.Runners...)
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return runners, nil
+}
+
+// listEnterpriseRunnerGroups returns all the runner groups in the enterprise.
+// We can remove this when google/go-github library is updated to support enterprise runner groups.
+func (c *Client) listEnterpriseRunnerGroups(ctx context.Context, enterprise string) ([]*github.RunnerGroup, error) {
+	var runnerGroups []*github.RunnerGroup
+
+	opts := github.ListOptions{PerPage: 100}
+	for {
+		u := fmt.Sprintf("enterprises/%v/actions/runner-groups?per_page=%d&page=%d", enterprise, opts.PerPage, opts.Page)
+
+		req, err := c.Client.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		list := &github.RunnerGroups{}
+
+		res, err := c.Client.Do(ctx, req, list)
+		if err != nil {
+			return runnerGroups, fmt.Errorf("failed to list enterprise runner groups: %w", err)
+		}
+
+		runnerGroups = append(runnerGroups, list.RunnerGroups...)
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return runnerGroups, nil
+}
+
+// ListOrganizationRepositories returns all the repositories in the organization.
+func (c *Client) ListOrganizationRepositories(ctx context.Context, org string) ([]*github.Repository, error) {
+	var repos []*github.Repository
+
+	opts := github.RepositoryListByOrgOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		list, res, err := c.Client.Repositories.ListByOrg(ctx, org, &opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization repositories: %w", err)
+		}
+
+		repos = append(repos, list...)
+		if res.NextPage == 0 {
+			break
+		}
+
+		opts.Page = res.NextPage
+	}
+
+	return repos, nil
+}
+
+// ListInstallationRepositories returns all the repositories accessible to the GitHub App installation
+// that the client is authenticated as.
+func (c *Client) ListInstallationRepositories(ctx context.Context) ([]*github.Repository, error) {
 	var repos []*github.Repository
 
 	opts := github.ListOptions{PerPage: 100}
 	for {
-		list, res, err := c.Client.Actions.ListRepositoryAccessRunnerGroup(ctx, org, runnerGroupId, &opts)
+		list, res, err := c.Client.Apps.ListRepos(ctx, &opts)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list repository access for runner group: %w", err)
+			return nil, fmt.Errorf("failed to list installation repositories: %w", err)
 		}
 
 		repos = append(repos, list.Repositories...)
@@ -347,6 +489,28 @@ func (c *Client) listRunners(ctx context.Context, enterprise, org, repo string,
 	return c.Client.Enterprise.ListRunners(ctx, enterprise, opts)
 }
 
+func (c *Client) listRunnerGroupRunners(ctx context.Context, enterprise, org string, groupID int64, opts *github.ListOptions) (*github.Runners, *github.Response, error) {
+	if len(org) > 0 {
+		return c.Client.Actions.ListRunnerGroupRunners(ctx, org, groupID, opts)
+	}
+
+	u := fmt.Sprintf("enterprises/%v/actions/runner-groups/%v/runners?per_page=%d&page=%d", enterprise, groupID, opts.PerPage, opts.Page)
+
+	req, err := c.Client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	runners := &github.Runners{}
+
+	res, err := c.Client.Do(ctx, req, runners)
+	if err != nil {
+		return nil, res, err
+	}
+
+	return runners, res, nil
+}
+
 func (c *Client) ListRepositoryWorkflowRuns(ctx context.Context, user string, repoName string) ([]*github.WorkflowRun, error) {
 	queued, err := c.listRepositoryWorkflowRuns(ctx, user, repoName, "queued")
 	if err != nil {
@@ -380,7 +544,7 @@ func (c *Client) listRepositoryWorkflowRuns(ctx context.Context, user string, re
 		list, res, err := c.Client.Actions.ListRepositoryWorkflowRuns(ctx, user, repoName, &opts)
 
 		if err != nil {
-			return workflowRuns, fmt.Errorf("failed to list workflow runs: %v", err)
+			return workflowRuns, fmt.Errorf("failed to list workflow runs: %w", err)
 		}
 
 		workflowRuns = append(workflowRuns, list.WorkflowRuns...)
@@ -30,6 +30,9 @@ import (
 	"github.com/actions/actions-runner-controller/logging"
 	"github.com/kelseyhightower/envconfig"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/scale"
 
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
@@ -77,6 +80,14 @@ func main() {
 
 		defaultScaleDownDelay time.Duration
 
+		workflowRunPollInterval    time.Duration
+		workflowRunPollConcurrency int
+
+		capacityReservationSyncPeriod  time.Duration
+		capacityReservationSyncLookups int
+
+		kedaExternalScalerAddr string
+
 		runnerImage            string
 		runnerImagePullSecrets stringSlice
 
@@ -115,6 +126,11 @@ func main() {
 	flag.StringVar(&c.RunnerGitHubURL, "runner-github-url", c.RunnerGitHubURL, "GitHub URL to be used by runners during registration")
 	flag.BoolVar(&runnerStatusUpdateHook, "runner-status-update-hook", false, "Use custom RBAC for runners (role, role binding and service account).")
 	flag.DurationVar(&defaultScaleDownDelay, "default-scale-down-delay", actionssummerwindnet.DefaultScaleDownDelay, "The approximate delay for a scale down followed by a scale up, used to prevent flapping (down->up->down->... loop)")
+	flag.DurationVar(&workflowRunPollInterval, "workflow-run-poll-interval", actionssummerwindnet.DefaultWorkflowRunPollInterval, "The minimum interval between polls of the workflow runs of a repository for the TotalNumberOfQueuedAndInProgressWorkflowRuns metric. The result is shared among all the HorizontalRunnerAutoscalers watching the repository")
+	flag.IntVar(&workflowRunPollConcurrency, "workflow-run-poll-concurrency", actionssummerwindnet.DefaultWorkflowRunPollConcurrency, "The maximum number of concurrent GitHub API requests made to poll workflow runs and jobs")
+	flag.StringVar(&kedaExternalScalerAddr, "keda-external-scaler-addr", "", "The address the KEDA external scaler gRPC service binds to. It serves the desired replicas of the HorizontalRunnerAutoscalers annotated with "+actionssummerwindnet.AnnotationKeyExternalScaler+"="+actionssummerwindnet.AnnotationValueExternalScalerKEDA+" to KEDA ScaledObjects. Set to empty to disable it")
+	flag.DurationVar(&capacityReservationSyncPeriod, "capacity-reservation-sync-period", actionssummerwindnet.DefaultCapacityReservationSyncPeriod, "The minimum interval between syncs of the capacity reservations made for workflow jobs with the status of the jobs on GitHub. A reservation is expired when its job has completed, and extended while its job is queued")
+	flag.IntVar(&capacityReservationSyncLookups, "capacity-reservation-sync-lookups", actionssummerwindnet.DefaultCapacityReservationSyncLookups, "The maximum number of workflow jobs looked up on GitHub per sync of the capacity reservations of a HorizontalRunnerAutoscaler. The jobs of the remaining reservations are looked up in the following syncs")
 	flag.IntVar(&port, "port", 9443, "The port to which the admission webhook endpoint should bind")
 	flag.DurationVar(&syncPeriod, "sync-period", 1*time.Minute, "Determines the minimum frequency at which K8s resources managed by this controller are reconciled.")
 	flag.Var(&commonRunnerLabels, "common-runner-labels", "Runner labels in the K1=V1,K2=V2,... format that are inherited all the runners created by the controller. See https://github.com/actions/actions-runner-controller/issues/321 for more information")
@@ -222,6 +238,11 @@ func main() {
 		"Initializing actions-runner-controller",
 		"version", build.Version,
 		"default-scale-down-delay", defaultScaleDownDelay,
+		"workflow-run-poll-interval", workflowRunPollInterval,
+		"workflow-run-poll-concurrency", workflowRunPollConcurrency,
+		"capacity-reservation-sync-period", capacityReservationSyncPeriod,
+		"capacity-reservation-sync-lookups", capacityReservationSyncLookups,
+		"keda-external-scaler-addr", kedaExternalScalerAddr,
 		"sync-period", syncPeriod,
 		"default-runner-image", runnerImage,
 		"default-docker-image", dockerImage,
@@ -231,12 +252,33 @@ func main() {
 		"watch-namespace", namespace,
 	)
 
+	// The scale client is used by HRAs to scale any resource that exposes the scale subresource
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		log.Error(err, "unable to create discovery client")
+		os.Exit(1)
+	}
+
+	scaleClient, err := scale.NewForConfig(mgr.GetConfig(), mgr.GetRESTMapper(), dynamic.LegacyAPIPathResolverFunc, scale.NewDiscoveryScaleKindResolver(discoveryClient))
+	if err != nil {
+		log.Error(err, "unable to create scale client")
+		os.Exit(1)
+	}
+
 	horizontalRunnerAutoscaler := &actionssummerwindnet.HorizontalRunnerAutoscalerReconciler{
 		Client:                mgr.GetClient(),
 		Log:                   log.WithName("horizontalrunnerautoscaler"),
 		Scheme:                mgr.GetScheme(),
 		GitHubClient:          multiClient,
 		DefaultScaleDownDelay: defaultScaleDownDelay,
+		WorkflowRunPoller: &actionssummerwindnet.WorkflowRunPoller{
+			Interval:    workflowRunPollInterval,
+			Concurrency: workflowRunPollConcurrency,
+			Log:         log.WithName("workflowrunpoller"),
+		},
+		CapacityReservationSyncPeriod:  capacityReservationSyncPeriod,
+		CapacityReservationSyncLookups: capacityReservationSyncLookups,
+		ScaleClient:                    scaleClient,
 	}
 
 	runnerPodReconciler := &actionssummerwindnet.RunnerPodReconciler{
@@ -268,6 +310,19 @@ func main() {
 		os.Exit(1)
 	}
 
+	if kedaExternalScalerAddr != "" {
+		kedaExternalScaler := &actionssummerwindnet.KEDAExternalScaler{
+			Autoscaler:  horizontalRunnerAutoscaler,
+			Log:         log.WithName("kedaexternalscaler"),
+			BindAddress: kedaExternalScalerAddr,
+		}
+
+		if err = mgr.Add(kedaExternalScaler); err != nil {
+			log.Error(err, "unable to add KEDA external scaler")
+			os.Exit(1)
+		}
+	}
+
 	if err = runnerPersistentVolumeReconciler.SetupWithManager(mgr); err != nil {
 		log.Error(err, "unable to create controller", "controller", "RunnerPersistentVolume")
 		os.Exit(1)
@@ -47,8 +47,9 @@ func Match(pat string, s string) bool {
 
 		subs := strings.SplitN(s, p, 2)
 
-		if len(subs) == 0 {
-			break
+		// The literal part of the pattern isn't found in the rest of the string
+		if len(subs) < 2 {
+			return inverse
 		}
 
 		if subs[0] != "" {
@@ -211,4 +211,36 @@ func TestMatch(t *testing.T) {
 			Want:    false,
 		})
 	})
+
+	t.Run("*-release == main", func(t *testing.T) {
+		run(t, testcase{
+			Pattern: "*-release",
+			Target:  "main",
+			Want:    false,
+		})
+	})
+
+	t.Run("!*-release == main", func(t *testing.T) {
+		run(t, testcase{
+			Pattern: "!*-release",
+			Target:  "main",
+			Want:    true,
+		})
+	})
+
+	t.Run("foo*bar == foo", func(t *testing.T) {
+		run(t, testcase{
+			Pattern: "foo*bar",
+			Target:  "foo",
+			Want:    false,
+		})
+	})
+
+	t.Run("!foo*bar == foo", func(t *testing.T) {
+		run(t, testcase{
+			Pattern: "!foo*bar",
+			Target:  "foo",
+			Want:    true,
+		})
+	})
 }